@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/VanDung-dev/HieraChain-Engine/hierachain-engine/api"
 )
@@ -28,6 +29,8 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
-	server.Stop()
+	if err := server.GracefulStop(30 * time.Second); err != nil {
+		log.Printf("Graceful shutdown incomplete: %v", err)
+	}
 	log.Println("Server stopped.")
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
 	"github.com/apache/arrow-go/v18/arrow/ipc"
@@ -56,6 +57,57 @@ func (h *ArrowHandler) ProcessBatch(data []byte) ([]byte, error) {
 	return h.createSuccessResponse()
 }
 
+// BatchSummary is the JSON response to a batch on a connection that
+// negotiated FormatJSON.
+type BatchSummary struct {
+	Status  string   `json:"status"`
+	Batches int      `json:"batches"`
+	Rows    int64    `json:"rows"`
+	Columns []string `json:"columns"`
+}
+
+// ProcessBatchAs processes data like ProcessBatch and encodes the response
+// in the given format.
+func (h *ArrowHandler) ProcessBatchAs(data []byte, format ResponseFormat) ([]byte, error) {
+	if format != FormatJSON {
+		return h.ProcessBatch(data)
+	}
+
+	summary, err := h.summarize(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(summary)
+}
+
+// summarize reads every record batch in the IPC stream and summarises them.
+func (h *ArrowHandler) summarize(data []byte) (*BatchSummary, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("received empty data")
+	}
+
+	reader, err := ipc.NewReader(bytes.NewReader(data), ipc.WithAllocator(h.mem))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IPC reader: %w", err)
+	}
+	defer reader.Release()
+
+	summary := &BatchSummary{Status: "ok", Columns: []string{}}
+	for _, field := range reader.Schema().Fields() {
+		summary.Columns = append(summary.Columns, field.Name)
+	}
+
+	for reader.Next() {
+		summary.Batches++
+		summary.Rows += reader.Record().NumRows()
+	}
+	if reader.Err() != nil {
+		return nil, fmt.Errorf("error reading Arrow stream: %w", reader.Err())
+	}
+
+	return summary, nil
+}
+
 func (h *ArrowHandler) createSuccessResponse() ([]byte, error) {
 	return []byte("OK"), nil // Temporary simplification for Phase 1 verification
 }
@@ -2,16 +2,84 @@ package api
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"sync"
 )
 
 // MaxMessageSize is the maximum allowed message size (50MB).
 // This prevents DoS attacks via oversized messages.
 const MaxMessageSize = 50 * 1024 * 1024 // 50MB
 
+// DefaultPooledBufferSize is the largest message body kept in a BufferPool (1MB).
+// Larger bodies are allocated per request and left to the garbage collector.
+const DefaultPooledBufferSize = 1 * 1024 * 1024 // 1MB
+
+// Control frames share the length-prefixed framing with Arrow payloads but
+// carry a small JSON object instead of an IPC stream, e.g. {"type":"ping"}.
+// An Arrow IPC stream never starts with '{', so a payload is only treated
+// as a control frame if it is a JSON object with a known "type".
+//
+// Response format negotiation: a client sends
+// {"type":"format","format":"json"} (or "arrow") and the server replies
+// with the same frame naming the format now in effect for that connection.
+// An unsupported format leaves the current one unchanged. In the default
+// arrow format a batch is answered with the handler's raw response; in
+// json format it is answered with a BatchSummary object.
+const (
+	// PingFrame asks the server to reply with PongFrame without processing a batch.
+	PingFrame = `{"type":"ping"}`
+	// PongFrame is the server's reply to PingFrame.
+	PongFrame = `{"type":"pong"}`
+	// ReconnectFrame is sent by a draining server before it closes a
+	// connection, asking the client to reconnect to another instance.
+	ReconnectFrame = `{"type":"reconnect"}`
+)
+
+// ResponseFormat selects how a connection's batch responses are encoded.
+type ResponseFormat string
+
+const (
+	// FormatArrow returns the handler's response unchanged (the default).
+	FormatArrow ResponseFormat = "arrow"
+	// FormatJSON returns a JSON-encoded BatchSummary.
+	FormatJSON ResponseFormat = "json"
+)
+
+// controlFrame is the decoded form of a control frame.
+type controlFrame struct {
+	Type   string         `json:"type"`
+	Format ResponseFormat `json:"format,omitempty"`
+}
+
+// parseControlFrame decodes data as a control frame. It returns false if
+// data is not a JSON object with a known "type".
+func parseControlFrame(data []byte) (controlFrame, bool) {
+	var frame controlFrame
+	if len(data) == 0 || data[0] != '{' {
+		return frame, false
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return frame, false
+	}
+
+	switch frame.Type {
+	case "ping", "format":
+		return frame, true
+	default:
+		return frame, false
+	}
+}
+
+// FormatFrame returns the control frame requesting or acknowledging format.
+func FormatFrame(format ResponseFormat) []byte {
+	frame, _ := json.Marshal(controlFrame{Type: "format", Format: format})
+	return frame
+}
+
 // ErrMessageTooLarge is returned when a message exceeds MaxMessageSize.
 var ErrMessageTooLarge = errors.New("message size exceeds maximum allowed size")
 
@@ -36,6 +104,77 @@ func ReadMessage(r io.Reader) ([]byte, error) {
 	return buf, nil
 }
 
+// ControlResponse returns the reply to a stateless control frame. It
+// returns false if data is not such a frame and should be handled as Arrow
+// data. Format frames change connection state and are handled by the server.
+func ControlResponse(data []byte) ([]byte, bool) {
+	frame, ok := parseControlFrame(data)
+	if !ok || frame.Type != "ping" {
+		return nil, false
+	}
+	return []byte(PongFrame), true
+}
+
+// BufferPool recycles message body buffers to reduce per-request allocations.
+// A nil *BufferPool is valid and allocates a fresh buffer for every message.
+type BufferPool struct {
+	pool    sync.Pool
+	maxSize int
+}
+
+// NewBufferPool creates a BufferPool that recycles buffers up to maxSize bytes.
+func NewBufferPool(maxSize int) *BufferPool {
+	if maxSize <= 0 {
+		maxSize = DefaultPooledBufferSize
+	}
+	return &BufferPool{maxSize: maxSize}
+}
+
+// Get returns a buffer of length size, reusing a pooled one when possible.
+func (p *BufferPool) Get(size int) []byte {
+	if p == nil || size > p.maxSize {
+		return make([]byte, size)
+	}
+
+	if bp, ok := p.pool.Get().(*[]byte); ok && cap(*bp) >= size {
+		return (*bp)[:size]
+	}
+	return make([]byte, size)
+}
+
+// Put returns a buffer to the pool. The caller must not use buf afterwards.
+func (p *BufferPool) Put(buf []byte) {
+	if p == nil || buf == nil || cap(buf) > p.maxSize {
+		return
+	}
+	buf = buf[:0]
+	p.pool.Put(&buf)
+}
+
+// ReadMessagePooled reads a length-prefixed message into a buffer borrowed from pool.
+// The caller must return the buffer with pool.Put once it is no longer referenced.
+func ReadMessagePooled(r io.Reader, pool *BufferPool) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+
+	// Prevent DoS by limiting message size
+	if length > MaxMessageSize {
+		return nil, fmt.Errorf("%w: %d bytes (max: %d)", ErrMessageTooLarge, length, MaxMessageSize)
+	}
+
+	buf := pool.Get(int(length))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		pool.Put(buf)
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+
+	return buf, nil
+}
+
 // WriteMessage writes a length-prefixed message to the writer.
 // Format: [4 bytes length (BigEndian)] [N bytes payload]
 func WriteMessage(w io.Writer, data []byte) error {
@@ -49,8 +188,9 @@ func WriteMessage(w io.Writer, data []byte) error {
 		return fmt.Errorf("%w: %d bytes (max: %d)", ErrMessageTooLarge, len(data), MaxMessageSize)
 	}
 
-	length := uint32(len(data)) // #nosec G115 - bounds checked above
-	if err := binary.Write(w, binary.BigEndian, length); err != nil {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(data))) // #nosec G115 - bounds checked above
+	if _, err := w.Write(header[:]); err != nil {
 		return fmt.Errorf("failed to write message length: %w", err)
 	}
 
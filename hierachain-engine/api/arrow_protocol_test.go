@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadMessagePooledRoundTrip(t *testing.T) {
+	pool := NewBufferPool(1024)
+
+	var buf bytes.Buffer
+	for _, msg := range []string{"first message", "second", "a third, longer message"} {
+		if err := WriteMessage(&buf, []byte(msg)); err != nil {
+			t.Fatalf("WriteMessage failed: %v", err)
+		}
+	}
+
+	for _, expected := range []string{"first message", "second", "a third, longer message"} {
+		data, err := ReadMessagePooled(&buf, pool)
+		if err != nil {
+			t.Fatalf("ReadMessagePooled failed: %v", err)
+		}
+		if string(data) != expected {
+			t.Errorf("Expected %q, got %q", expected, string(data))
+		}
+		pool.Put(data)
+	}
+}
+
+func TestBufferPoolSizeCap(t *testing.T) {
+	pool := NewBufferPool(16)
+
+	small := pool.Get(8)
+	if len(small) != 8 {
+		t.Errorf("Expected length 8, got %d", len(small))
+	}
+	pool.Put(small)
+
+	large := pool.Get(64)
+	if len(large) != 64 {
+		t.Errorf("Expected length 64, got %d", len(large))
+	}
+	// Oversized buffers are not pooled
+	pool.Put(large)
+	if reused := pool.Get(16); cap(reused) > 16 {
+		t.Errorf("Oversized buffer was pooled: cap %d", cap(reused))
+	}
+}
+
+func TestBufferPoolNil(t *testing.T) {
+	var pool *BufferPool
+
+	buf := pool.Get(32)
+	if len(buf) != 32 {
+		t.Errorf("Expected length 32, got %d", len(buf))
+	}
+	pool.Put(buf) // must not panic
+}
+
+func benchmarkPayload() []byte {
+	var buf bytes.Buffer
+	_ = WriteMessage(&buf, make([]byte, 64*1024))
+	return buf.Bytes()
+}
+
+// BenchmarkReadMessage measures allocations of the unpooled read path.
+// Run with: go test -bench=ReadMessage -benchmem ./hierachain-engine/api/
+func BenchmarkReadMessage(b *testing.B) {
+	payload := benchmarkPayload()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadMessage(bytes.NewReader(payload)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkReadMessagePooled measures allocations of the pooled read path.
+func BenchmarkReadMessagePooled(b *testing.B) {
+	payload := benchmarkPayload()
+	pool := NewBufferPool(DefaultPooledBufferSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := ReadMessagePooled(bytes.NewReader(payload), pool)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pool.Put(data)
+	}
+}
+
+func TestControlResponse(t *testing.T) {
+	if resp, ok := ControlResponse([]byte(PingFrame)); !ok || string(resp) != PongFrame {
+		t.Errorf("Expected pong for ping, got %q (ok=%v)", resp, ok)
+	}
+
+	for _, data := range []string{"", `{"type":"unknown"}`, `{not json`, "\xff\xff\xff\xff"} {
+		if _, ok := ControlResponse([]byte(data)); ok {
+			t.Errorf("Expected %q not to be a control frame", data)
+		}
+	}
+}
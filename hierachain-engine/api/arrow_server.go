@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -23,9 +24,19 @@ type ArrowServer struct {
 	listener      net.Listener
 	handler       *ArrowHandler
 	authenticator *Authenticator
+	bufferPool    *BufferPool
+	listenOpts    ListenerOptions
+	metrics       *Metrics
 	running       bool
+	health        HealthState
 	mu            sync.Mutex
 	quit          chan struct{}
+
+	// Open client connections, tracked so Drain and GracefulStop can
+	// close them. The value is true while a request is being received
+	// or processed.
+	conns  map[net.Conn]bool
+	connWg sync.WaitGroup
 }
 
 // NewArrowServer creates a new ArrowServer instance.
@@ -36,7 +47,11 @@ func NewArrowServer() *ArrowServer {
 	return &ArrowServer{
 		handler:       NewArrowHandler(),
 		authenticator: NewAuthenticatorFromEnv(),
+		bufferPool:    NewBufferPool(DefaultPooledBufferSize),
+		listenOpts:    DefaultListenerOptions(),
+		health:        HealthStarting,
 		quit:          make(chan struct{}),
+		conns:         make(map[net.Conn]bool),
 	}
 }
 
@@ -45,7 +60,11 @@ func NewArrowServerWithAuth(authConfig AuthConfig) *ArrowServer {
 	return &ArrowServer{
 		handler:       NewArrowHandler(),
 		authenticator: NewAuthenticator(authConfig),
+		bufferPool:    NewBufferPool(DefaultPooledBufferSize),
+		listenOpts:    DefaultListenerOptions(),
+		health:        HealthStarting,
 		quit:          make(chan struct{}),
+		conns:         make(map[net.Conn]bool),
 	}
 }
 
@@ -59,6 +78,49 @@ func (s *ArrowServer) GetAuthToken() string {
 	return s.authenticator.GetToken()
 }
 
+// Addr returns the listener's concrete address, or nil if the server has not started.
+// Use it to discover the port chosen when binding to port 0.
+func (s *ArrowServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Health returns the server's current lifecycle state.
+func (s *ArrowServer) Health() HealthState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.health
+}
+
+// SetBufferPool sets the pool used for request message bodies.
+// Passing nil disables pooling. Must be called before Start.
+func (s *ArrowServer) SetBufferPool(pool *BufferPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bufferPool = pool
+}
+
+// SetListenerOptions sets the TCP listener options. Must be called before Start.
+func (s *ArrowServer) SetListenerOptions(opts ListenerOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listenOpts = opts
+}
+
+// SetMetrics sets where the server records each request's kind, outcome
+// and processing time. Passing nil disables recording. Must be called
+// before Start.
+func (s *ArrowServer) SetMetrics(m *Metrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = m
+}
+
 // Start starts the Arrow server on the specified address.
 // This method blocks until the server is stopped or fails.
 func (s *ArrowServer) Start(address string) error {
@@ -68,13 +130,14 @@ func (s *ArrowServer) Start(address string) error {
 		return fmt.Errorf("server is already running")
 	}
 
-	lis, err := net.Listen("tcp", address)
+	lis, err := listen(address, s.listenOpts)
 	if err != nil {
 		s.mu.Unlock()
 		return fmt.Errorf("failed to listen on %s: %w", address, err)
 	}
 	s.listener = lis
 	s.running = true
+	s.health = HealthServing
 	s.mu.Unlock()
 
 	defer s.Stop()
@@ -103,13 +166,14 @@ func (s *ArrowServer) StartAsync(address string) error {
 		return fmt.Errorf("server is already running")
 	}
 
-	lis, err := net.Listen("tcp", address)
+	lis, err := listen(address, s.listenOpts)
 	if err != nil {
 		s.mu.Unlock()
 		return fmt.Errorf("failed to listen on %s: %w", address, err)
 	}
 	s.listener = lis
 	s.running = true
+	s.health = HealthServing
 	s.mu.Unlock()
 
 	go func() {
@@ -130,7 +194,7 @@ func (s *ArrowServer) StartAsync(address string) error {
 	return nil
 }
 
-// Stop stops the server.
+// Stop stops the server immediately without waiting for open connections.
 func (s *ArrowServer) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -139,6 +203,71 @@ func (s *ArrowServer) Stop() {
 		return
 	}
 
+	s.closeListener()
+	s.health = HealthStopped
+}
+
+// Drain stops accepting connections and reports DRAINING. Each open
+// connection finishes its in-flight request, then receives ReconnectFrame
+// and is closed. Idle connections are signalled immediately.
+func (s *ArrowServer) Drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.closeListener()
+	s.health = HealthDraining
+
+	for conn, busy := range s.conns {
+		if !busy {
+			// Wake the handler blocked waiting for the next request
+			_ = conn.SetReadDeadline(time.Now())
+		}
+	}
+}
+
+// GracefulStop drains the server and waits for open connections to close.
+// Connections still open when the timeout elapses are closed and an error
+// is returned. The state is STOPPED once GracefulStop returns.
+func (s *ArrowServer) GracefulStop(timeout time.Duration) error {
+	s.Drain()
+
+	s.mu.Lock()
+	if s.health != HealthDraining {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.connWg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close() // Best effort: the handler exits on the next read
+		}
+		s.mu.Unlock()
+		err = errors.New("graceful stop timeout")
+	}
+
+	s.mu.Lock()
+	s.health = HealthStopped
+	s.mu.Unlock()
+	return err
+}
+
+// closeListener marks the server stopped and closes the listener.
+// The caller must hold s.mu.
+func (s *ArrowServer) closeListener() {
 	s.running = false
 	close(s.quit)
 	if s.listener != nil {
@@ -151,10 +280,62 @@ func (s *ArrowServer) Stop() {
 	}
 }
 
+// trackConn registers conn as open. It returns false if the server is
+// no longer serving, in which case the connection should be dropped.
+func (s *ArrowServer) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return false
+	}
+	s.conns[conn] = false
+	s.connWg.Add(1)
+	return true
+}
+
+// untrackConn removes conn from the open connection set.
+func (s *ArrowServer) untrackConn(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.connWg.Done()
+}
+
+// setBusy records whether conn has a request in flight.
+func (s *ArrowServer) setBusy(conn net.Conn, busy bool) {
+	s.mu.Lock()
+	if _, ok := s.conns[conn]; ok {
+		s.conns[conn] = busy
+	}
+	s.mu.Unlock()
+}
+
+// sendReconnect tells a client of a draining server to reconnect elsewhere.
+// Errors are ignored because the connection is closed right after.
+func (s *ArrowServer) sendReconnect(conn net.Conn) {
+	if err := conn.SetWriteDeadline(time.Now().Add(ConnectionWriteTimeout)); err != nil {
+		return
+	}
+	_ = WriteMessage(conn, []byte(ReconnectFrame))
+}
+
+// draining returns true once Drain or GracefulStop has begun.
+func (s *ArrowServer) draining() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.health == HealthDraining
+}
+
 // handleConnection handles a single client connection.
 func (s *ArrowServer) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
+	if !s.trackConn(conn) {
+		return
+	}
+	defer s.untrackConn(conn)
+
 	// Panic recovery to prevent one connection from crashing the entire server
 	defer func() {
 		if r := recover(); r != nil {
@@ -169,24 +350,64 @@ func (s *ArrowServer) handleConnection(conn net.Conn) {
 		}
 	}
 
+	s.mu.Lock()
+	pool := s.bufferPool
+	metrics := s.metrics
+	s.mu.Unlock()
+
+	reader := &requestReader{Conn: conn, server: s}
+	format := FormatArrow
+
 	for {
 		// Set read deadline to prevent Slowloris-style attacks
 		if err := conn.SetReadDeadline(time.Now().Add(ConnectionReadTimeout)); err != nil {
 			return
 		}
 
-		// 1. Read request message
-		data, err := ReadMessage(conn)
+		// Checked after arming the deadline, so a concurrent Drain is
+		// either seen here or interrupts the idle read below
+		if s.draining() {
+			s.sendReconnect(conn)
+			return
+		}
+
+		// 1. Read request message into a pooled buffer
+		reader.started = false
+		data, err := ReadMessagePooled(reader, pool)
 		if err != nil {
-			if err != io.EOF {
+			if s.draining() {
+				s.sendReconnect(conn)
+			} else if err != io.EOF {
 				// Timeout or other error - close connection
 				// fmt.Printf("Error reading message: %v\n", err)
 			}
 			return
 		}
 
-		// 2. Process message (Arrow RecordBatch)
-		response, err := s.handler.ProcessBatch(data)
+		// 2. Process message: control frames are answered directly,
+		// anything else is an Arrow RecordBatch.
+		// The request buffer is returned to the pool as soon as processing
+		// finishes; the response must not reference it.
+		var response []byte
+		start := time.Now()
+		frame, isControl := parseControlFrame(data)
+		kind := frame.Type
+		switch {
+		case !isControl:
+			kind = "batch"
+			response, err = s.handler.ProcessBatchAs(data, format)
+		case frame.Type == "format":
+			if frame.Format == FormatArrow || frame.Format == FormatJSON {
+				format = frame.Format
+			}
+			response = FormatFrame(format)
+		default:
+			response, _ = ControlResponse(data)
+		}
+		pool.Put(data)
+		if metrics != nil {
+			metrics.RecordServerRequest(kind, time.Since(start), err == nil)
+		}
 		if err != nil {
 			// Send error response? For now, we might just close connection or log
 			// Or send a specific error packet
@@ -204,7 +425,26 @@ func (s *ArrowServer) handleConnection(conn net.Conn) {
 			// fmt.Printf("Error writing response: %v\n", err)
 			return
 		}
+		s.setBusy(conn, false)
+	}
+}
+
+// requestReader marks its connection busy once the first byte of a
+// request arrives, so Drain lets that request complete.
+type requestReader struct {
+	net.Conn
+	server  *ArrowServer
+	started bool
+}
+
+// Read implements io.Reader.
+func (r *requestReader) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+	if n > 0 && !r.started {
+		r.started = true
+		r.server.setBusy(r.Conn, true)
 	}
+	return n, err
 }
 
 // performAuthHandshake performs token-based authentication handshake.
@@ -2,7 +2,10 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/ipc"
 	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestArrowServer_BasicConnection(t *testing.T) {
@@ -77,3 +81,442 @@ func TestArrowServer_BasicConnection(t *testing.T) {
 		t.Errorf("Expected response 'OK', got '%s'", string(respData))
 	}
 }
+
+// int32Payload serializes a single-column Int32 record as an IPC stream.
+func int32Payload(t *testing.T, values []int32) []byte {
+	t.Helper()
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "int32_col", Type: arrow.PrimitiveTypes.Int32}}, nil)
+	b := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer b.Release()
+	b.Field(0).(*array.Int32Builder).AppendValues(values, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	if err := writer.Write(rec); err != nil {
+		t.Fatalf("Failed to write record: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArrowServer_ResponseFormat(t *testing.T) {
+	server := NewArrowServerWithAuth(AuthConfig{Enabled: false})
+	if err := server.StartAsync("127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	roundTrip := func(msg []byte) string {
+		t.Helper()
+		if err := WriteMessage(conn, msg); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		resp, err := ReadMessage(conn)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		return string(resp)
+	}
+	payload := int32Payload(t, []int32{1, 2, 3})
+
+	// Arrow is the default
+	if resp := roundTrip(payload); resp != "OK" {
+		t.Errorf("Expected 'OK' in arrow format, got %q", resp)
+	}
+
+	// Switching to JSON is acknowledged and applies to later batches
+	if resp := roundTrip(FormatFrame(FormatJSON)); resp != string(FormatFrame(FormatJSON)) {
+		t.Fatalf("Expected json format ack, got %q", resp)
+	}
+	var summary BatchSummary
+	if err := json.Unmarshal([]byte(roundTrip(payload)), &summary); err != nil {
+		t.Fatalf("Expected JSON summary: %v", err)
+	}
+	if summary.Status != "ok" || summary.Batches != 1 || summary.Rows != 3 ||
+		len(summary.Columns) != 1 || summary.Columns[0] != "int32_col" {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+
+	// An unsupported format is answered with the format still in effect
+	if resp := roundTrip([]byte(`{"type":"format","format":"xml"}`)); resp != string(FormatFrame(FormatJSON)) {
+		t.Errorf("Expected json format to remain, got %q", resp)
+	}
+
+	// Switching back restores raw responses
+	roundTrip(FormatFrame(FormatArrow))
+	if resp := roundTrip(payload); resp != "OK" {
+		t.Errorf("Expected 'OK' after switching back, got %q", resp)
+	}
+}
+
+func TestArrowServer_RestartSamePort(t *testing.T) {
+	first := NewArrowServerWithAuth(AuthConfig{Enabled: false})
+	first.SetListenerOptions(ListenerOptions{ReuseAddr: true, Backlog: 256})
+	if err := first.StartAsync("127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	addr := first.Addr().String()
+
+	// A served connection closed by the server leaves it in TIME_WAIT
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if err := WriteMessage(conn, []byte(PingFrame)); err != nil {
+		t.Fatalf("Failed to write ping: %v", err)
+	}
+	if _, err := ReadMessage(conn); err != nil {
+		t.Fatalf("Failed to read pong: %v", err)
+	}
+	if err := first.GracefulStop(5 * time.Second); err != nil {
+		t.Fatalf("GracefulStop failed: %v", err)
+	}
+
+	second := NewArrowServerWithAuth(AuthConfig{Enabled: false})
+	if err := second.StartAsync(addr); err != nil {
+		t.Fatalf("Restart on %s failed: %v", addr, err)
+	}
+	defer second.Stop()
+
+	conn2, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to connect after restart: %v", err)
+	}
+	defer conn2.Close()
+	if err := WriteMessage(conn2, []byte(PingFrame)); err != nil {
+		t.Fatalf("Failed to write ping after restart: %v", err)
+	}
+	if resp, err := ReadMessage(conn2); err != nil || string(resp) != PongFrame {
+		t.Errorf("Expected %s after restart, got %q (%v)", PongFrame, resp, err)
+	}
+}
+
+func TestArrowServer_AddrEphemeralPort(t *testing.T) {
+	server := NewArrowServerWithAuth(AuthConfig{Enabled: false})
+	if server.Addr() != nil {
+		t.Error("Addr should be nil before start")
+	}
+
+	if err := server.StartAsync("127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	addr := server.Addr()
+	if addr == nil {
+		t.Fatal("Addr returned nil after start")
+	}
+	if tcpAddr, ok := addr.(*net.TCPAddr); !ok || tcpAddr.Port == 0 {
+		t.Fatalf("Expected concrete TCP port, got %v", addr)
+	}
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Failed to connect to %s: %v", addr, err)
+	}
+	defer conn.Close()
+
+	mem := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	if err := writer.Write(rec); err != nil {
+		t.Fatalf("Failed to write record: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	if err := WriteMessage(conn, buf.Bytes()); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	respData, err := ReadMessage(conn)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if string(respData) != "OK" {
+		t.Errorf("Expected response 'OK', got '%s'", string(respData))
+	}
+}
+
+func TestArrowServer_HealthTransitions(t *testing.T) {
+	server := NewArrowServerWithAuth(AuthConfig{Enabled: false})
+	if state := server.Health(); state != HealthStarting {
+		t.Fatalf("Expected STARTING before start, got %s", state)
+	}
+
+	if err := server.StartAsync("127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	if state := server.Health(); state != HealthServing {
+		t.Fatalf("Expected SERVING after start, got %s", state)
+	}
+
+	// A connection with a partially received request holds the server in DRAINING
+	conn := dialBusy(t, server)
+	defer conn.Close()
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.GracefulStop(5 * time.Second)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for server.Health() != HealthDraining && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if state := server.Health(); state != HealthDraining {
+		t.Fatalf("Expected DRAINING during graceful stop, got %s", state)
+	}
+
+	// Closing the client lets the drain complete
+	conn.Close()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("GracefulStop failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GracefulStop did not return")
+	}
+
+	if state := server.Health(); state != HealthStopped {
+		t.Errorf("Expected STOPPED after graceful stop, got %s", state)
+	}
+}
+
+// dialBusy connects to server and sends the first half of a PingFrame,
+// returning once the server considers the request in flight.
+func dialBusy(t *testing.T, server *ArrowServer) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	header := []byte{0, 0, 0, byte(len(PingFrame))}
+	if _, err := conn.Write(append(header, PingFrame[:5]...)); err != nil {
+		t.Fatalf("Failed to write partial request: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		server.mu.Lock()
+		busy := 0
+		for _, inFlight := range server.conns {
+			if inFlight {
+				busy++
+			}
+		}
+		server.mu.Unlock()
+		if busy == 1 {
+			return conn
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Server did not mark the request in flight")
+	return nil
+}
+
+func TestArrowServer_DrainSignalsReconnect(t *testing.T) {
+	server := NewArrowServerWithAuth(AuthConfig{Enabled: false})
+	if err := server.StartAsync("127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	busy := dialBusy(t, server)
+	defer busy.Close()
+
+	idle, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer idle.Close()
+	// Round trip so the idle connection is being served before draining
+	if err := WriteMessage(idle, []byte(PingFrame)); err != nil {
+		t.Fatalf("Failed to write ping: %v", err)
+	}
+	if _, err := ReadMessage(idle); err != nil {
+		t.Fatalf("Failed to read pong: %v", err)
+	}
+
+	server.Drain()
+	if state := server.Health(); state != HealthDraining {
+		t.Fatalf("Expected DRAINING, got %s", state)
+	}
+
+	// New connections are refused
+	if conn, err := net.DialTimeout("tcp", server.Addr().String(), time.Second); err == nil {
+		conn.Close()
+		t.Error("Expected dial to fail while draining")
+	}
+
+	// The idle client is signalled straight away
+	_ = idle.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err := ReadMessage(idle)
+	if err != nil || string(resp) != ReconnectFrame {
+		t.Fatalf("Expected %s on idle connection, got %q (%v)", ReconnectFrame, resp, err)
+	}
+
+	// The in-flight request completes before the reconnect signal
+	if _, err := busy.Write([]byte(PingFrame[5:])); err != nil {
+		t.Fatalf("Failed to finish request: %v", err)
+	}
+	_ = busy.SetReadDeadline(time.Now().Add(5 * time.Second))
+	resp, err = ReadMessage(busy)
+	if err != nil || string(resp) != PongFrame {
+		t.Fatalf("Expected %s for in-flight request, got %q (%v)", PongFrame, resp, err)
+	}
+	resp, err = ReadMessage(busy)
+	if err != nil || string(resp) != ReconnectFrame {
+		t.Fatalf("Expected %s after in-flight request, got %q (%v)", ReconnectFrame, resp, err)
+	}
+	if _, err := ReadMessage(busy); err == nil {
+		t.Error("Expected connection to be closed after reconnect signal")
+	}
+
+	if err := server.GracefulStop(5 * time.Second); err != nil {
+		t.Errorf("GracefulStop failed: %v", err)
+	}
+	if state := server.Health(); state != HealthStopped {
+		t.Errorf("Expected STOPPED, got %s", state)
+	}
+}
+
+func TestMetricsServer_HealthEndpoint(t *testing.T) {
+	ms := NewMetricsServer("127.0.0.1:0")
+
+	rec := httptest.NewRecorder()
+	ms.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "OK" {
+		t.Errorf("Expected 200 OK without health source, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	state := HealthServing
+	ms.SetHealthSource(func() HealthState { return state })
+
+	rec = httptest.NewRecorder()
+	ms.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "SERVING" {
+		t.Errorf("Expected 200 SERVING, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	state = HealthDraining
+	rec = httptest.NewRecorder()
+	ms.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "DRAINING" {
+		t.Errorf("Expected 503 DRAINING, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestArrowServer_PingAfterAuth(t *testing.T) {
+	server := NewArrowServerWithAuth(AuthConfig{Enabled: true, Token: "secret-token"})
+	if err := server.StartAsync("127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if err := WriteMessage(conn, []byte(`{"type":"auth","token":"secret-token"}`)); err != nil {
+		t.Fatalf("Failed to write auth message: %v", err)
+	}
+	authResp, err := ReadMessage(conn)
+	if err != nil {
+		t.Fatalf("Failed to read auth response: %v", err)
+	}
+	if string(authResp) != `{"success":true}` {
+		t.Fatalf("Auth failed: %s", authResp)
+	}
+
+	// Ping is answered without invoking ProcessBatch, repeatedly on one connection
+	for i := 0; i < 2; i++ {
+		if err := WriteMessage(conn, []byte(PingFrame)); err != nil {
+			t.Fatalf("Failed to write ping: %v", err)
+		}
+		resp, err := ReadMessage(conn)
+		if err != nil {
+			t.Fatalf("Failed to read pong: %v", err)
+		}
+		if string(resp) != PongFrame {
+			t.Errorf("Expected %s, got %s", PongFrame, resp)
+		}
+	}
+}
+
+func TestArrowServer_RecordsRequestMetrics(t *testing.T) {
+	metrics := NewMetrics("arrow_server_test")
+
+	server := NewArrowServerWithAuth(AuthConfig{Enabled: false})
+	server.SetMetrics(metrics)
+	if err := server.StartAsync("127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	for _, msg := range [][]byte{int32Payload(t, []int32{1, 2}), []byte(PingFrame), []byte(PingFrame)} {
+		if err := WriteMessage(conn, msg); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		if _, err := ReadMessage(conn); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+	}
+
+	// An invalid batch is recorded as a failure before the connection closes
+	if err := WriteMessage(conn, []byte("not arrow")); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	if _, err := ReadMessage(conn); err == nil {
+		t.Fatal("Expected connection to close after invalid batch")
+	}
+
+	tests := []struct {
+		kind, status string
+		want         float64
+	}{
+		{"batch", "success", 1},
+		{"batch", "failure", 1},
+		{"ping", "success", 2},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(metrics.ServerRequests.WithLabelValues(tt.kind, tt.status))
+		if got != tt.want {
+			t.Errorf("Expected %v %s/%s requests, got %v", tt.want, tt.kind, tt.status, got)
+		}
+	}
+
+	if n := testutil.CollectAndCount(metrics.ServerRequestDuration); n != 2 {
+		t.Errorf("Expected latency series for 2 kinds, got %d", n)
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+// HealthState describes where a server is in its lifecycle.
+type HealthState int32
+
+const (
+	// HealthStarting means the server has been created but is not yet accepting connections.
+	HealthStarting HealthState = iota
+	// HealthServing means the server is accepting and processing requests.
+	HealthServing
+	// HealthDraining means the server is finishing in-flight requests and accepts no new connections.
+	HealthDraining
+	// HealthStopped means the server has shut down.
+	HealthStopped
+)
+
+// String returns the upper-case name of the state.
+func (h HealthState) String() string {
+	switch h {
+	case HealthStarting:
+		return "STARTING"
+	case HealthServing:
+		return "SERVING"
+	case HealthDraining:
+		return "DRAINING"
+	case HealthStopped:
+		return "STOPPED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// IsServing returns true if load balancers should route traffic to the server.
+func (h HealthState) IsServing() bool {
+	return h == HealthServing
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"net"
+)
+
+// ListenerOptions tunes the Arrow server's TCP listener.
+//
+// Platform differences:
+//   - ReuseAddr: Go already sets SO_REUSEADDR on Unix listeners, so this
+//     only makes the default explicit there. It is ignored elsewhere; on
+//     Windows SO_REUSEADDR would let another process steal the port.
+//   - ReusePort: SO_REUSEPORT is applied on Linux, macOS and the BSDs. On
+//     Linux it lets several servers share a port with kernel load balancing.
+//   - Backlog: applied on Linux only. Elsewhere, and when zero, the accept
+//     queue is sized by the OS (net.core.somaxconn on Linux).
+type ListenerOptions struct {
+	ReuseAddr bool `json:"reuse_addr"`
+	ReusePort bool `json:"reuse_port"`
+	Backlog   int  `json:"backlog"`
+}
+
+// DefaultListenerOptions returns options that allow restarting on the same
+// port while old connections are in TIME_WAIT.
+func DefaultListenerOptions() ListenerOptions {
+	return ListenerOptions{
+		ReuseAddr: true,
+	}
+}
+
+// listen opens a TCP listener on address configured by opts.
+func listen(address string, opts ListenerOptions) (net.Listener, error) {
+	lc := net.ListenConfig{Control: opts.control}
+	lis, err := lc.Listen(context.Background(), "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Backlog > 0 {
+		if err := setBacklog(lis, opts.Backlog); err != nil {
+			_ = lis.Close() // Best effort: the listen error is what matters
+			return nil, err
+		}
+	}
+	return lis, nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package api
+
+import (
+	"net"
+	"syscall"
+)
+
+// control ignores opts: socket reuse options are only applied on Linux,
+// macOS and the BSDs.
+func (opts ListenerOptions) control(network, address string, c syscall.RawConn) error {
+	return nil
+}
+
+// setBacklog is a no-op: the backlog is only tunable on Linux.
+func setBacklog(lis net.Listener, backlog int) error {
+	return nil
+}
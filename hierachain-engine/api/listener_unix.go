@@ -0,0 +1,63 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// control sets the socket options in opts before the listener binds.
+func (opts ListenerOptions) control(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		if opts.ReuseAddr {
+			if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); sockErr != nil {
+				sockErr = fmt.Errorf("failed to set SO_REUSEADDR: %w", sockErr)
+				return
+			}
+		}
+		if opts.ReusePort {
+			if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); sockErr != nil {
+				sockErr = fmt.Errorf("failed to set SO_REUSEPORT: %w", sockErr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// setBacklog resizes the accept queue of a listening socket. Linux applies
+// a repeated listen() call to an already listening socket; other systems
+// keep the OS default.
+func setBacklog(lis net.Listener, backlog int) error {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+
+	tcp, ok := lis.(*net.TCPListener)
+	if !ok {
+		return nil
+	}
+	raw, err := tcp.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var listenErr error
+	if err := raw.Control(func(fd uintptr) {
+		listenErr = unix.Listen(int(fd), backlog)
+	}); err != nil {
+		return err
+	}
+	if listenErr != nil {
+		return fmt.Errorf("failed to set listen backlog: %w", listenErr)
+	}
+	return nil
+}
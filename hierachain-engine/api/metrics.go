@@ -3,6 +3,7 @@ package api
 
 import (
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -24,9 +25,25 @@ type Metrics struct {
 	BatchLatency prometheus.Histogram
 
 	// System metrics
-	MempoolSize       prometheus.Gauge
+	MempoolSize prometheus.Gauge
+
+	// Worker pool metrics labelled by pool name. Completed and failed
+	// task counts are the per-status counts of PoolTaskDuration.
+	PoolTaskDuration *prometheus.HistogramVec
+	PoolActive       *prometheus.GaugeVec
+	PoolPending      *prometheus.GaugeVec
+	PoolDropped      *prometheus.CounterVec
+	PoolRejected     *prometheus.CounterVec
+
+	// Unlabelled worker pool gauges, set by UpdateWorkerPool.
+	//
+	// Deprecated: use PoolActive and PoolPending.
 	WorkerPoolActive  prometheus.Gauge
 	WorkerPoolPending prometheus.Gauge
+
+	// Arrow server requests labelled by kind (batch, ping, format) and status
+	ServerRequests        *prometheus.CounterVec
+	ServerRequestDuration *prometheus.HistogramVec
 }
 
 // DefaultMetrics creates metrics with default settings.
@@ -80,6 +97,32 @@ func NewMetrics(namespace string) *Metrics {
 			Name:      "mempool_size",
 			Help:      "Current number of pending transactions in mempool",
 		}),
+		PoolTaskDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "pool_task_duration_seconds",
+			Help:      "Worker pool task processing time in seconds, by pool and outcome",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"pool", "status"}),
+		PoolActive: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pool_active_tasks",
+			Help:      "Number of tasks running in each worker pool",
+		}, []string{"pool"}),
+		PoolPending: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pool_pending_tasks",
+			Help:      "Number of tasks queued in each worker pool",
+		}, []string{"pool"}),
+		PoolDropped: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pool_dropped_results_total",
+			Help:      "Total number of worker pool results dropped because the result channel was full",
+		}, []string{"pool"}),
+		PoolRejected: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "pool_rejected_total",
+			Help:      "Total number of worker pool submissions rejected because the queue was full",
+		}, []string{"pool"}),
 		WorkerPoolActive: promauto.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "worker_pool_active",
@@ -90,6 +133,18 @@ func NewMetrics(namespace string) *Metrics {
 			Name:      "worker_pool_pending",
 			Help:      "Number of pending tasks in worker pool",
 		}),
+
+		ServerRequests: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "server_requests_total",
+			Help:      "Total number of Arrow server requests, by kind and status",
+		}, []string{"kind", "status"}),
+		ServerRequestDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "server_request_duration_seconds",
+			Help:      "Arrow server request processing time in seconds, by kind",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"kind"}),
 	}
 }
 
@@ -116,39 +171,112 @@ func (m *Metrics) UpdateMempoolSize(size int) {
 	m.MempoolSize.Set(float64(size))
 }
 
+// ObserveTaskDuration records how long a task in the named pool took.
+func (m *Metrics) ObserveTaskDuration(pool string, d time.Duration, success bool) {
+	status := "success"
+	if !success {
+		status = "failure"
+	}
+	m.PoolTaskDuration.WithLabelValues(pool, status).Observe(d.Seconds())
+}
+
+// SetActive sets the number of running tasks in the named pool.
+func (m *Metrics) SetActive(pool string, n int) {
+	m.PoolActive.WithLabelValues(pool).Set(float64(n))
+}
+
+// SetPending sets the number of queued tasks in the named pool.
+func (m *Metrics) SetPending(pool string, n int) {
+	m.PoolPending.WithLabelValues(pool).Set(float64(n))
+}
+
+// RecordDroppedResult records a result of the named pool dropped because
+// the result channel was full.
+func (m *Metrics) RecordDroppedResult(pool string) {
+	m.PoolDropped.WithLabelValues(pool).Inc()
+}
+
+// RecordQueueFull records a submission to the named pool rejected because
+// the queue was full.
+func (m *Metrics) RecordQueueFull(pool string) {
+	m.PoolRejected.WithLabelValues(pool).Inc()
+}
+
 // UpdateWorkerPool updates worker pool gauges.
+//
+// Deprecated: use SetActive and SetPending, which are labelled by pool.
 func (m *Metrics) UpdateWorkerPool(active, pending int) {
 	m.WorkerPoolActive.Set(float64(active))
 	m.WorkerPoolPending.Set(float64(pending))
 }
 
+// RecordServerRequest records an Arrow server request of the given kind
+// that took d to process.
+func (m *Metrics) RecordServerRequest(kind string, d time.Duration, success bool) {
+	status := "success"
+	if !success {
+		status = "failure"
+	}
+	m.ServerRequests.WithLabelValues(kind, status).Inc()
+	m.ServerRequestDuration.WithLabelValues(kind).Observe(d.Seconds())
+}
+
 // MetricsServer runs an HTTP server exposing /metrics endpoint.
 type MetricsServer struct {
 	server *http.Server
+
+	health func() HealthState
+	mu     sync.RWMutex
 }
 
 // NewMetricsServer creates a new metrics server on the given address.
 func NewMetricsServer(addr string) *MetricsServer {
+	s := &MetricsServer{}
+
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write([]byte("OK")); err != nil {
-			// Log error but don't fail the health check
-			// The response header is already written
-			_ = err
+	mux.HandleFunc("/health", s.handleHealth)
+
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second, // Prevents Slowloris attack (G112)
+		IdleTimeout:       120 * time.Second,
+	}
+	return s
+}
+
+// SetHealthSource sets the function reporting the state served on /health,
+// typically ArrowServer.Health. Without a source /health always reports OK.
+func (s *MetricsServer) SetHealthSource(health func() HealthState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health = health
+}
+
+// handleHealth writes the current health state. Any state other than
+// SERVING is reported as 503 so load balancers stop routing traffic.
+func (s *MetricsServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	health := s.health
+	s.mu.RUnlock()
+
+	status, body := http.StatusOK, "OK"
+	if health != nil {
+		state := health()
+		body = state.String()
+		if !state.IsServing() {
+			status = http.StatusServiceUnavailable
 		}
-	})
-
-	return &MetricsServer{
-		server: &http.Server{
-			Addr:              addr,
-			Handler:           mux,
-			ReadTimeout:       30 * time.Second,
-			WriteTimeout:      30 * time.Second,
-			ReadHeaderTimeout: 10 * time.Second, // Prevents Slowloris attack (G112)
-			IdleTimeout:       120 * time.Second,
-		},
+	}
+
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(body)); err != nil {
+		// Log error but don't fail the health check
+		// The response header is already written
+		_ = err
 	}
 }
 
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when an entity exceeds its submission quota.
+var ErrQuotaExceeded = errors.New("entity quota exceeded")
+
+// QuotaConfig holds per-entity submission quota configuration.
+type QuotaConfig struct {
+	// DefaultLimit is the number of submissions allowed per entity per window.
+	// Zero or negative disables the quota.
+	DefaultLimit int
+	// Window is the sliding window length
+	Window time.Duration
+	// Limits overrides DefaultLimit for specific entities
+	Limits map[string]int
+	// PruneInterval is how often idle entities are dropped from the tracker
+	PruneInterval time.Duration
+}
+
+// DefaultQuotaConfig returns a configuration allowing 1000 submissions per entity per minute.
+func DefaultQuotaConfig() QuotaConfig {
+	return QuotaConfig{
+		DefaultLimit:  1000,
+		Window:        time.Minute,
+		Limits:        make(map[string]int),
+		PruneInterval: time.Minute,
+	}
+}
+
+// QuotaTracker enforces per-entity submission quotas over a sliding window.
+// It is safe for concurrent use.
+type QuotaTracker struct {
+	config QuotaConfig
+	events map[string][]time.Time // entity -> submission times, oldest first
+	now    func() time.Time
+	mu     sync.Mutex
+
+	// Control
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+}
+
+// NewQuotaTracker creates a new QuotaTracker with the given config.
+func NewQuotaTracker(config QuotaConfig) *QuotaTracker {
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.PruneInterval <= 0 {
+		config.PruneInterval = config.Window
+	}
+
+	limits := make(map[string]int, len(config.Limits))
+	for entity, limit := range config.Limits {
+		limits[entity] = limit
+	}
+	config.Limits = limits
+
+	return &QuotaTracker{
+		config: config,
+		events: make(map[string][]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Allow records a submission for the entity if it is within quota.
+// Returns an error wrapping ErrQuotaExceeded, naming the entity, otherwise.
+func (q *QuotaTracker) Allow(entityID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	limit := q.limitFor(entityID)
+	if limit <= 0 {
+		return nil
+	}
+
+	now := q.now()
+	events := q.trim(q.events[entityID], now)
+
+	if len(events) >= limit {
+		q.events[entityID] = events
+		return fmt.Errorf("%w: entity %q submitted %d transactions in %s (limit %d)",
+			ErrQuotaExceeded, entityID, len(events), q.config.Window, limit)
+	}
+
+	q.events[entityID] = append(events, now)
+	return nil
+}
+
+// SetLimit sets the quota for a specific entity, overriding the default.
+func (q *QuotaTracker) SetLimit(entityID string, limit int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.config.Limits[entityID] = limit
+}
+
+// Usage returns the number of submissions recorded for the entity in the current window.
+func (q *QuotaTracker) Usage(entityID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events, ok := q.events[entityID]
+	if !ok {
+		return 0
+	}
+
+	// Write back only for tracked entities, so queries don't add entries
+	events = q.trim(events, q.now())
+	if len(events) == 0 {
+		delete(q.events, entityID)
+	} else {
+		q.events[entityID] = events
+	}
+	return len(events)
+}
+
+// Prune drops expired submissions and forgets idle entities.
+// Returns the number of entities removed.
+func (q *QuotaTracker) Prune() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	removed := 0
+	for entityID, events := range q.events {
+		events = q.trim(events, now)
+		if len(events) == 0 {
+			delete(q.events, entityID)
+			removed++
+			continue
+		}
+		q.events[entityID] = events
+	}
+	return removed
+}
+
+// Start begins periodic pruning. A stopped tracker can be started again.
+func (q *QuotaTracker) Start() {
+	q.mu.Lock()
+	if q.running {
+		q.mu.Unlock()
+		return
+	}
+	q.running = true
+	q.stopChan = make(chan struct{})
+	stop := q.stopChan
+	q.mu.Unlock()
+
+	q.wg.Add(1)
+	go q.pruneLoop(stop)
+}
+
+// Stop stops periodic pruning.
+func (q *QuotaTracker) Stop() {
+	q.mu.Lock()
+	if !q.running {
+		q.mu.Unlock()
+		return
+	}
+	q.running = false
+	stop := q.stopChan
+	q.mu.Unlock()
+
+	close(stop)
+	q.wg.Wait()
+}
+
+// pruneLoop periodically prunes the tracker.
+func (q *QuotaTracker) pruneLoop(stop chan struct{}) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.config.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			q.Prune()
+		}
+	}
+}
+
+// limitFor returns the quota for an entity (called with lock held).
+func (q *QuotaTracker) limitFor(entityID string) int {
+	if limit, ok := q.config.Limits[entityID]; ok {
+		return limit
+	}
+	return q.config.DefaultLimit
+}
+
+// trim drops submissions older than the window (called with lock held).
+func (q *QuotaTracker) trim(events []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-q.config.Window)
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	if i == 0 {
+		return events
+	}
+	return append(events[:0], events[i:]...)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestQuotaTracker(limit int) (*QuotaTracker, *time.Time) {
+	config := DefaultQuotaConfig()
+	config.DefaultLimit = limit
+
+	clock := time.Unix(1700000000, 0)
+	q := NewQuotaTracker(config)
+	q.now = func() time.Time { return clock }
+	return q, &clock
+}
+
+func TestQuotaTrackerExceedAndRecover(t *testing.T) {
+	q, clock := newTestQuotaTracker(3)
+
+	for i := 0; i < 3; i++ {
+		if err := q.Allow("entity-1"); err != nil {
+			t.Fatalf("Submission %d should be allowed: %v", i, err)
+		}
+		*clock = clock.Add(10 * time.Second)
+	}
+
+	err := q.Allow("entity-1")
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "entity-1") {
+		t.Errorf("Error should name the entity: %v", err)
+	}
+
+	// Other entities are unaffected
+	if err := q.Allow("entity-2"); err != nil {
+		t.Errorf("entity-2 should be allowed: %v", err)
+	}
+
+	// Once the oldest submission leaves the window, one slot frees up
+	*clock = clock.Add(31 * time.Second)
+	if err := q.Allow("entity-1"); err != nil {
+		t.Errorf("Expected recovery after window slides: %v", err)
+	}
+	if err := q.Allow("entity-1"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected quota exceeded again, got %v", err)
+	}
+}
+
+func TestQuotaTrackerPerEntityLimit(t *testing.T) {
+	q, _ := newTestQuotaTracker(1)
+	q.SetLimit("vip", 5)
+	q.SetLimit("unlimited", 0)
+
+	for i := 0; i < 5; i++ {
+		if err := q.Allow("vip"); err != nil {
+			t.Fatalf("vip submission %d should be allowed: %v", i, err)
+		}
+	}
+	if err := q.Allow("vip"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected vip to hit its quota, got %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if err := q.Allow("unlimited"); err != nil {
+			t.Fatalf("Unlimited entity rejected: %v", err)
+		}
+	}
+}
+
+func TestQuotaTrackerPrune(t *testing.T) {
+	q, clock := newTestQuotaTracker(10)
+
+	_ = q.Allow("a")
+	_ = q.Allow("b")
+	*clock = clock.Add(2 * time.Minute)
+	_ = q.Allow("c")
+
+	if removed := q.Prune(); removed != 2 {
+		t.Errorf("Expected 2 idle entities pruned, got %d", removed)
+	}
+	if q.Usage("c") != 1 {
+		t.Errorf("Expected usage 1 for c, got %d", q.Usage("c"))
+	}
+}
+
+func TestQuotaTrackerConcurrent(t *testing.T) {
+	q := NewQuotaTracker(QuotaConfig{DefaultLimit: 50, Window: time.Minute})
+	q.Start()
+	defer q.Stop()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if q.Allow("entity") == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 50 {
+		t.Errorf("Expected exactly 50 allowed, got %d", allowed)
+	}
+}
+
+func TestQuotaTrackerRestart(t *testing.T) {
+	q := NewQuotaTracker(QuotaConfig{DefaultLimit: 10, Window: 10 * time.Millisecond, PruneInterval: 5 * time.Millisecond})
+
+	q.Start()
+	q.Stop()
+	q.Start()
+	defer q.Stop()
+
+	// The restarted loop still prunes idle entities
+	_ = q.Allow("entity-1")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		q.mu.Lock()
+		_, tracked := q.events["entity-1"]
+		q.mu.Unlock()
+		if !tracked {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected restarted tracker to prune idle entity")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQuotaTrackerUsageDoesNotTrack(t *testing.T) {
+	q, _ := newTestQuotaTracker(10)
+
+	if usage := q.Usage("never-seen"); usage != 0 {
+		t.Errorf("Expected usage 0, got %d", usage)
+	}
+	if _, tracked := q.events["never-seen"]; tracked {
+		t.Error("Expected Usage not to track an unseen entity")
+	}
+}
@@ -0,0 +1,128 @@
+package consensus
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// commitLog is the set of values one replica committed, keyed by sequence.
+type commitLog struct {
+	Replica string
+	Commits map[int]string
+}
+
+// checkSafety returns every violation of the consensus safety invariants
+// across the given replica logs:
+//   - agreement: no two replicas commit different values at the same sequence
+//   - no gaps: each replica's committed sequences are contiguous from its
+//     first commit to its last
+//
+// Sequences below a replica's first commit are not required, so scenarios
+// that abandon a round before anyone commits remain valid.
+func checkSafety(logs []commitLog) []error {
+	var violations []error
+
+	decided := make(map[int]string)
+	decidedBy := make(map[int]string)
+	for _, replica := range logs {
+		seqs := make([]int, 0, len(replica.Commits))
+		for seq := range replica.Commits {
+			seqs = append(seqs, seq)
+		}
+		sort.Ints(seqs)
+
+		for i, seq := range seqs {
+			value := replica.Commits[seq]
+			if prev, ok := decided[seq]; ok && prev != value {
+				violations = append(violations, fmt.Errorf(
+					"sequence %d: %s committed %q but %s committed %q",
+					seq, decidedBy[seq], prev, replica.Replica, value))
+			} else if !ok {
+				decided[seq] = value
+				decidedBy[seq] = replica.Replica
+			}
+
+			if i > 0 && seq != seqs[i-1]+1 {
+				violations = append(violations, fmt.Errorf(
+					"%s: gap between sequence %d and %d", replica.Replica, seqs[i-1], seq))
+			}
+		}
+	}
+
+	return violations
+}
+
+// assertSafety fails the test if any safety invariant is violated.
+func assertSafety(t *testing.T, logs []commitLog) {
+	t.Helper()
+
+	for _, err := range checkSafety(logs) {
+		t.Errorf("Safety violation: %v", err)
+	}
+}
+
+// commitLogsOf collects the commit logs of a quorum cluster.
+func commitLogsOf(nodes []*quorumNode) []commitLog {
+	logs := make([]commitLog, len(nodes))
+	for i, node := range nodes {
+		logs[i] = commitLog{Replica: node.id, Commits: node.committed}
+	}
+	return logs
+}
+
+func TestCheckSafetyCatchesBrokenProtocol(t *testing.T) {
+	// A quorum of one lets each side of a partition commit on its own
+	net := NewSimNetwork(42)
+	nodes := make([]*quorumNode, 4)
+	for i := range nodes {
+		nodes[i] = newQuorumNode(fmt.Sprintf("node-%d", i), net, 1)
+	}
+
+	net.Partition([]string{"node-2", "node-3"})
+	nodes[0].propose(1, "A")
+	nodes[2].propose(1, "B")
+	net.Run(1000)
+
+	if len(checkSafety(commitLogsOf(nodes))) == 0 {
+		t.Fatal("Expected conflicting commits to be reported")
+	}
+}
+
+func TestCheckSafetyCatchesGaps(t *testing.T) {
+	logs := []commitLog{
+		{Replica: "node-0", Commits: map[int]string{1: "A", 2: "B", 3: "C"}},
+		{Replica: "node-1", Commits: map[int]string{1: "A", 3: "C"}},
+	}
+
+	violations := checkSafety(logs)
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 gap violation, got %v", violations)
+	}
+
+	// Starting later than sequence 1 is not a gap
+	logs[1].Commits = map[int]string{2: "B", 3: "C"}
+	if violations := checkSafety(logs); len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+}
+
+func TestSimNetworkReorderSafety(t *testing.T) {
+	net, nodes := newQuorumCluster(3, 4)
+	net.DelayRange(0, 50*time.Millisecond)
+
+	// Heights proposed by rotating leaders, delivered heavily reordered
+	for height := 1; height <= 5; height++ {
+		leader := nodes[height%len(nodes)]
+		leader.propose(height, fmt.Sprintf("block-%d", height))
+		net.Run(1000)
+	}
+
+	assertSafety(t, commitLogsOf(nodes))
+	for _, node := range nodes {
+		if len(node.committed) != 5 {
+			t.Errorf("%s committed %d heights, expected 5", node.id, len(node.committed))
+		}
+	}
+}
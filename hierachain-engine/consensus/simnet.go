@@ -0,0 +1,254 @@
+package consensus
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Envelope is a message in flight on a SimNetwork.
+type Envelope struct {
+	From    string
+	To      string
+	Payload interface{}
+	SentAt  time.Duration // virtual time the message was sent
+
+	deliverAt time.Duration
+	seq       uint64
+}
+
+// SimHandler receives messages delivered by a SimNetwork.
+type SimHandler func(env Envelope)
+
+// SimStats contains simulated network statistics.
+type SimStats struct {
+	Sent      int64 `json:"sent"`
+	Delivered int64 `json:"delivered"`
+	Dropped   int64 `json:"dropped"`
+	InFlight  int   `json:"in_flight"`
+}
+
+// SimNetwork is a deterministic in-memory network for consensus tests.
+// Delivery order, delays and drops are driven by a seeded RNG and a virtual
+// clock, so the same seed and the same sequence of calls always produce the
+// same execution. Messages are only delivered when Step or Run is called.
+type SimNetwork struct {
+	rng      *rand.Rand
+	handlers map[string]SimHandler
+	order    []string // node IDs in registration order, for deterministic broadcast
+
+	// Fault injection
+	isolated map[string]bool // nodes cut off from the rest of the network
+	minDelay time.Duration
+	maxDelay time.Duration
+	dropRate float64
+
+	now   time.Duration
+	queue []*Envelope
+	seq   uint64
+
+	// Stats
+	sent      int64
+	delivered int64
+	dropped   int64
+
+	mu sync.Mutex
+}
+
+// NewSimNetwork creates a simulated network seeded for reproducibility.
+func NewSimNetwork(seed int64) *SimNetwork {
+	return &SimNetwork{
+		rng:      rand.New(rand.NewSource(seed)), // #nosec G404 - deterministic test RNG
+		handlers: make(map[string]SimHandler),
+		isolated: make(map[string]bool),
+		minDelay: time.Millisecond,
+		maxDelay: time.Millisecond,
+	}
+}
+
+// AddNode registers a node and the handler that receives its messages.
+func (n *SimNetwork) AddNode(nodeID string, handler SimHandler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, exists := n.handlers[nodeID]; !exists {
+		n.order = append(n.order, nodeID)
+	}
+	n.handlers[nodeID] = handler
+}
+
+// Nodes returns the registered node IDs in registration order.
+func (n *SimNetwork) Nodes() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	nodes := make([]string, len(n.order))
+	copy(nodes, n.order)
+	return nodes
+}
+
+// Send queues a message from one node to another.
+// The message may be dropped, delayed or reordered according to the
+// current fault settings.
+func (n *SimNetwork) Send(from, to string, payload interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.send(from, to, payload)
+}
+
+// Broadcast queues a message from one node to every other node.
+func (n *SimNetwork) Broadcast(from string, payload interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, to := range n.order {
+		if to != from {
+			n.send(from, to, payload)
+		}
+	}
+}
+
+// send queues a message (called with lock held).
+func (n *SimNetwork) send(from, to string, payload interface{}) {
+	n.sent++
+
+	if n.dropRate > 0 && n.rng.Float64() < n.dropRate {
+		n.dropped++
+		return
+	}
+
+	delay := n.minDelay
+	if n.maxDelay > n.minDelay {
+		delay += time.Duration(n.rng.Int63n(int64(n.maxDelay - n.minDelay + 1)))
+	}
+
+	n.seq++
+	n.queue = append(n.queue, &Envelope{
+		From:      from,
+		To:        to,
+		Payload:   payload,
+		SentAt:    n.now,
+		deliverAt: n.now + delay,
+		seq:       n.seq,
+	})
+}
+
+// Partition isolates the given nodes from the rest of the network.
+// Nodes on the same side of the partition can still reach each other.
+// Messages crossing the partition are dropped at delivery time.
+func (n *SimNetwork) Partition(nodeIDs []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.isolated = make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		n.isolated[id] = true
+	}
+}
+
+// Heal removes any partition.
+func (n *SimNetwork) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.isolated = make(map[string]bool)
+}
+
+// DelayRange sets the range of per-message delivery delays.
+// Distinct delays reorder messages relative to their send order.
+func (n *SimNetwork) DelayRange(min, max time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if min < 0 {
+		min = 0
+	}
+	if max < min {
+		max = min
+	}
+	n.minDelay = min
+	n.maxDelay = max
+}
+
+// SetDropRate sets the probability in [0, 1] that a sent message is lost.
+func (n *SimNetwork) SetDropRate(rate float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if rate < 0 {
+		rate = 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	n.dropRate = rate
+}
+
+// Step delivers the next due message, advancing the virtual clock.
+// Returns false if no messages are in flight.
+func (n *SimNetwork) Step() bool {
+	n.mu.Lock()
+
+	for len(n.queue) > 0 {
+		sort.Slice(n.queue, func(i, j int) bool {
+			if n.queue[i].deliverAt != n.queue[j].deliverAt {
+				return n.queue[i].deliverAt < n.queue[j].deliverAt
+			}
+			return n.queue[i].seq < n.queue[j].seq
+		})
+
+		env := n.queue[0]
+		n.queue[0] = nil
+		n.queue = n.queue[1:]
+
+		if env.deliverAt > n.now {
+			n.now = env.deliverAt
+		}
+
+		handler, ok := n.handlers[env.To]
+		if !ok || n.isolated[env.From] != n.isolated[env.To] {
+			n.dropped++
+			continue
+		}
+
+		n.delivered++
+		n.mu.Unlock()
+
+		// Handlers run without the lock so they can send replies
+		handler(*env)
+		return true
+	}
+
+	n.mu.Unlock()
+	return false
+}
+
+// Run delivers messages until none are in flight or maxSteps deliveries
+// have been made. Returns the number of messages delivered.
+func (n *SimNetwork) Run(maxSteps int) int {
+	steps := 0
+	for steps < maxSteps && n.Step() {
+		steps++
+	}
+	return steps
+}
+
+// Now returns the current virtual time.
+func (n *SimNetwork) Now() time.Duration {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.now
+}
+
+// GetStats returns simulated network statistics.
+func (n *SimNetwork) GetStats() SimStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return SimStats{
+		Sent:      n.sent,
+		Delivered: n.delivered,
+		Dropped:   n.dropped,
+		InFlight:  len(n.queue),
+	}
+}
@@ -0,0 +1,207 @@
+package consensus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// proposal and vote are the messages of a minimal single-round quorum
+// protocol used to exercise SimNetwork until the BFT engine lands.
+type proposal struct {
+	Height int
+	Value  string
+}
+
+type vote struct {
+	Height int
+	Value  string
+	Voter  string
+}
+
+// quorumNode votes for the first proposal it sees at each height and
+// commits a value once a quorum of votes for it has been received.
+type quorumNode struct {
+	id        string
+	net       *SimNetwork
+	quorum    int
+	voted     map[int]bool
+	votes     map[int]map[string]map[string]bool // height -> value -> voters
+	committed map[int]string
+}
+
+func newQuorumNode(id string, net *SimNetwork, quorum int) *quorumNode {
+	node := &quorumNode{
+		id:        id,
+		net:       net,
+		quorum:    quorum,
+		voted:     make(map[int]bool),
+		votes:     make(map[int]map[string]map[string]bool),
+		committed: make(map[int]string),
+	}
+	net.AddNode(id, node.handle)
+	return node
+}
+
+func (q *quorumNode) propose(height int, value string) {
+	q.net.Broadcast(q.id, proposal{Height: height, Value: value})
+	q.castVote(height, value)
+}
+
+func (q *quorumNode) castVote(height int, value string) {
+	if q.voted[height] {
+		return
+	}
+	q.voted[height] = true
+	v := vote{Height: height, Value: value, Voter: q.id}
+	q.net.Broadcast(q.id, v)
+	q.recordVote(v)
+}
+
+func (q *quorumNode) recordVote(v vote) {
+	if q.votes[v.Height] == nil {
+		q.votes[v.Height] = make(map[string]map[string]bool)
+	}
+	if q.votes[v.Height][v.Value] == nil {
+		q.votes[v.Height][v.Value] = make(map[string]bool)
+	}
+	q.votes[v.Height][v.Value][v.Voter] = true
+
+	if _, done := q.committed[v.Height]; !done && len(q.votes[v.Height][v.Value]) >= q.quorum {
+		q.committed[v.Height] = v.Value
+	}
+}
+
+func (q *quorumNode) handle(env Envelope) {
+	switch msg := env.Payload.(type) {
+	case proposal:
+		q.castVote(msg.Height, msg.Value)
+	case vote:
+		q.recordVote(msg)
+	}
+}
+
+func newQuorumCluster(seed int64, size int) (*SimNetwork, []*quorumNode) {
+	net := NewSimNetwork(seed)
+	quorum := 2*((size-1)/3) + 1
+
+	nodes := make([]*quorumNode, size)
+	for i := range nodes {
+		nodes[i] = newQuorumNode(fmt.Sprintf("node-%d", i), net, quorum)
+	}
+	return net, nodes
+}
+
+func TestSimNetworkPartitionSafety(t *testing.T) {
+	net, nodes := newQuorumCluster(42, 4)
+	net.DelayRange(time.Millisecond, 20*time.Millisecond)
+
+	// Split the network 2/2 and let each side propose a different value
+	net.Partition([]string{"node-2", "node-3"})
+	nodes[0].propose(1, "A")
+	nodes[2].propose(1, "B")
+	net.Run(1000)
+
+	for _, node := range nodes {
+		if value, ok := node.committed[1]; ok {
+			t.Errorf("%s committed %s without a quorum", node.id, value)
+		}
+	}
+	assertSafety(t, commitLogsOf(nodes))
+
+	// Heal and run the next height with a single proposer
+	net.Heal()
+	nodes[1].propose(2, "C")
+	net.Run(1000)
+
+	assertSafety(t, commitLogsOf(nodes))
+	for _, node := range nodes {
+		if node.committed[2] != "C" {
+			t.Errorf("%s expected to commit C at height 2, got %q", node.id, node.committed[2])
+		}
+	}
+}
+
+func TestSimNetworkMinorityPartition(t *testing.T) {
+	net, nodes := newQuorumCluster(7, 4)
+	net.DelayRange(0, 10*time.Millisecond)
+
+	// Isolate one node; the remaining three still form a quorum
+	net.Partition([]string{"node-3"})
+	nodes[0].propose(1, "A")
+	net.Run(1000)
+
+	for _, node := range nodes[:3] {
+		if node.committed[1] != "A" {
+			t.Errorf("%s expected to commit A, got %q", node.id, node.committed[1])
+		}
+	}
+	if _, ok := nodes[3].committed[1]; ok {
+		t.Error("Isolated node should not commit")
+	}
+	assertSafety(t, commitLogsOf(nodes))
+}
+
+func TestSimNetworkDeterministic(t *testing.T) {
+	trace := func(seed int64) []string {
+		net := NewSimNetwork(seed)
+		net.DelayRange(0, 50*time.Millisecond)
+		net.SetDropRate(0.2)
+
+		var order []string
+		for _, id := range []string{"a", "b", "c"} {
+			nodeID := id
+			net.AddNode(nodeID, func(env Envelope) {
+				order = append(order, fmt.Sprintf("%s->%s:%v", env.From, nodeID, env.Payload))
+			})
+		}
+		for i := 0; i < 20; i++ {
+			net.Broadcast("a", i)
+		}
+		net.Run(1000)
+		return order
+	}
+
+	first := trace(99)
+	second := trace(99)
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Error("Same seed produced different executions")
+	}
+	if len(first) == 0 || len(first) == 40 {
+		t.Errorf("Expected some but not all messages delivered, got %d", len(first))
+	}
+}
+
+func TestSimNetworkReordersMessages(t *testing.T) {
+	net := NewSimNetwork(1)
+	net.DelayRange(0, 100*time.Millisecond)
+
+	var received []int
+	net.AddNode("a", func(env Envelope) {})
+	net.AddNode("b", func(env Envelope) {
+		received = append(received, env.Payload.(int))
+	})
+
+	for i := 0; i < 10; i++ {
+		net.Send("a", "b", i)
+	}
+	net.Run(100)
+
+	if len(received) != 10 {
+		t.Fatalf("Expected 10 messages, got %d", len(received))
+	}
+	inOrder := true
+	for i := 1; i < len(received); i++ {
+		if received[i] < received[i-1] {
+			inOrder = false
+		}
+	}
+	if inOrder {
+		t.Error("Expected delays to reorder messages")
+	}
+
+	stats := net.GetStats()
+	if stats.Delivered != 10 || stats.InFlight != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
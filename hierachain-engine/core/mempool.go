@@ -2,7 +2,14 @@ package core
 
 import (
 	"container/heap"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,6 +20,11 @@ var (
 	ErrTxAlreadyExists = errors.New("transaction already exists")
 	ErrTxNotFound      = errors.New("transaction not found")
 	ErrInvalidTx       = errors.New("invalid transaction")
+	ErrInvalidMaxSize  = errors.New("mempool max size must be positive")
+
+	ErrEntityQuotaExceeded = errors.New("entity has too many pending transactions")
+	ErrPriorityNotHigher   = errors.New("replacement priority must be higher than the existing transaction")
+	ErrDuplicateContent    = errors.New("transaction with identical content already exists")
 )
 
 // Transaction represents a pending transaction in the mempool.
@@ -26,6 +38,38 @@ type Transaction struct {
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// NonceMetadataKey is the Metadata key holding a transaction's per-entity
+// sequence number.
+const NonceMetadataKey = "nonce"
+
+// maxExactFloat is the largest integer a float64 holds exactly (2^53).
+const maxExactFloat = 1 << 53
+
+// TxNonce returns the nonce in tx.Metadata[NonceMetadataKey]. Integer,
+// float and numeric string values are accepted, so it works for both JSON
+// and Arrow details. It returns false if the nonce is missing, negative,
+// fractional or too large to be exact.
+func TxNonce(tx *Transaction) (uint64, bool) {
+	switch v := tx.Metadata[NonceMetadataKey].(type) {
+	case int:
+		return uint64(v), v >= 0
+	case int64:
+		return uint64(v), v >= 0
+	case uint64:
+		return v, true
+	case float64:
+		if v < 0 || v >= maxExactFloat || v != math.Trunc(v) {
+			return 0, false
+		}
+		return uint64(v), true
+	case string:
+		if n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
 // Validate checks if the transaction has required fields.
 func (tx *Transaction) Validate() error {
 	if tx.ID == "" {
@@ -70,34 +114,161 @@ func (pq *priorityQueue) Pop() interface{} {
 	return tx
 }
 
+// TxOverheadBytes is the fixed per-transaction cost, on top of len(Data),
+// counted against a mempool's byte limit. It approximates the struct, map
+// and queue entries plus typical IDs and metadata.
+const TxOverheadBytes = 256
+
 // Mempool manages pending transactions with thread-safe operations.
 type Mempool struct {
 	pending map[string]*Transaction
 	queue   priorityQueue
-	maxSize int
+	maxSize int           // 0 means unbounded
+	ttl     time.Duration // 0 means transactions never expire
+	evict   bool          // evict the lowest priority transaction when full
+	bytes   int           // total len(Data) of pending transactions
+	expired int64         // transactions evicted by expiry
 	mu      sync.RWMutex
+
+	// Per-entity limit and the counts it is checked against
+	entities     map[string]int // pending transactions per EntityID
+	maxPerEntity int            // 0 means no per-entity limit
+
+	// Byte budget: Data plus TxOverheadBytes per transaction
+	bytesUsed int64
+	maxBytes  int64 // 0 means no byte limit
+
+	// Content hash -> transaction ID, maintained only when dedup is set
+	contentIDs map[string]string
+	dedup      bool
+
+	// nonceOrdering makes PopBatch serialize each entity by nonce
+	nonceOrdering bool
+
+	// Expiry sweeper lifecycle, guarded separately from mu so StopExpiry
+	// can wait for a sweep that is blocked on mu
+	expiryStop chan struct{}
+	expiryWg   sync.WaitGroup
+	expiryMu   sync.Mutex
+}
+
+// MempoolConfig contains configuration for a Mempool.
+type MempoolConfig struct {
+	// MaxSize caps the number of transactions; zero or less is unbounded
+	MaxSize int
+	// TTL is how long a transaction may stay pending after its Timestamp
+	// before the expiry sweeper evicts it; zero disables expiry
+	TTL time.Duration
+	// EvictOnFull makes a full mempool evict its lowest priority transaction
+	// for a newcomer of strictly higher priority instead of rejecting it
+	EvictOnFull bool
+	// MaxPerEntity caps pending transactions per EntityID so one client
+	// cannot crowd out the rest; zero or less means no limit
+	MaxPerEntity int
+	// MaxBytes caps the memory held, counted as len(Data) plus
+	// TxOverheadBytes per transaction; zero or less means no limit
+	MaxBytes int64
+	// DedupContent rejects a transaction whose EntityID, EventType and Data
+	// match a pending one under a different ID, with ErrDuplicateContent
+	DedupContent bool
+	// NonceOrdering makes PopBatch skip a transaction while one with a
+	// lower nonce (see TxNonce) for the same entity is still pending
+	NonceOrdering bool
 }
 
 // NewMempool creates a new Mempool with the specified maximum size.
+// A maxSize of zero or less creates an unbounded mempool, which is useful
+// for tests and small deployments; use NewBoundedMempool to reject it.
 func NewMempool(maxSize int) *Mempool {
+	return NewMempoolWithConfig(MempoolConfig{MaxSize: maxSize})
+}
+
+// NewMempoolWithConfig creates a new Mempool from config. Expiry only takes
+// effect once StartExpiry is called.
+func NewMempoolWithConfig(config MempoolConfig) *Mempool {
+	if config.MaxSize < 0 {
+		config.MaxSize = 0
+	}
+	if config.TTL < 0 {
+		config.TTL = 0
+	}
+	if config.MaxPerEntity < 0 {
+		config.MaxPerEntity = 0
+	}
+	if config.MaxBytes < 0 {
+		config.MaxBytes = 0
+	}
+
 	m := &Mempool{
 		pending: make(map[string]*Transaction),
 		queue:   make(priorityQueue, 0),
-		maxSize: maxSize,
+		maxSize: config.MaxSize,
+		ttl:     config.TTL,
+		evict:   config.EvictOnFull,
+
+		entities:     make(map[string]int),
+		maxPerEntity: config.MaxPerEntity,
+		maxBytes:     config.MaxBytes,
+
+		contentIDs: make(map[string]string),
+		dedup:      config.DedupContent,
+
+		nonceOrdering: config.NonceOrdering,
 	}
 	heap.Init(&m.queue)
 	return m
 }
 
+// NewBoundedMempool creates a Mempool that holds at most maxSize transactions.
+// It returns ErrInvalidMaxSize if maxSize is not positive.
+func NewBoundedMempool(maxSize int) (*Mempool, error) {
+	if maxSize <= 0 {
+		return nil, ErrInvalidMaxSize
+	}
+	return NewMempool(maxSize), nil
+}
+
+// NewMempoolWithDedup creates a Mempool like NewMempool that, if dedup is
+// set, rejects resubmissions of pending content under a new ID.
+func NewMempoolWithDedup(maxSize int, dedup bool) *Mempool {
+	return NewMempoolWithConfig(MempoolConfig{MaxSize: maxSize, DedupContent: dedup})
+}
+
+// NewMempoolWithNonceOrdering creates an unbounded Mempool whose PopBatch
+// never returns an entity's transactions out of nonce order.
+func NewMempoolWithNonceOrdering() *Mempool {
+	return NewMempoolWithConfig(MempoolConfig{NonceOrdering: true})
+}
+
+// NewMempoolWithByteLimit creates a Mempool bounded by memory rather than
+// transaction count: Add returns ErrMempoolFull when a transaction's Data
+// plus TxOverheadBytes would take the total past maxBytes. A maxBytes of
+// zero or less leaves it unbounded.
+func NewMempoolWithByteLimit(maxBytes int64) *Mempool {
+	return NewMempoolWithConfig(MempoolConfig{MaxBytes: maxBytes})
+}
+
 // Add adds a transaction to the mempool.
 // Returns error if mempool is full or transaction already exists.
+// With EvictOnFull it may evict a lower priority transaction instead of
+// returning ErrMempoolFull; use AddOrEvict to learn which.
 func (m *Mempool) Add(tx *Transaction) error {
+	_, err := m.AddOrEvict(tx)
+	return err
+}
+
+// AddOrEvict adds a transaction to the mempool like Add, returning the
+// transaction evicted to make room, if any. Eviction only happens with
+// EvictOnFull, and only when tx has a strictly higher priority than the
+// lowest in the pool; among the lowest, the most recent is evicted first.
+// An equal-priority newcomer never displaces an existing transaction.
+func (m *Mempool) AddOrEvict(tx *Transaction) (*Transaction, error) {
 	if tx == nil {
-		return ErrInvalidTx
+		return nil, ErrInvalidTx
 	}
 
 	if err := tx.Validate(); err != nil {
-		return err
+		return nil, err
 	}
 
 	m.mu.Lock()
@@ -105,12 +276,41 @@ func (m *Mempool) Add(tx *Transaction) error {
 
 	// Check if already exists
 	if _, exists := m.pending[tx.ID]; exists {
-		return ErrTxAlreadyExists
+		return nil, ErrTxAlreadyExists
 	}
 
-	// Check size limit
-	if len(m.pending) >= m.maxSize {
-		return ErrMempoolFull
+	return m.addLocked(tx)
+}
+
+// addLocked adds a validated transaction whose ID is not pending.
+// The caller must hold m.mu.
+func (m *Mempool) addLocked(tx *Transaction) (*Transaction, error) {
+	// Check the per-entity and byte limits before evicting anything;
+	// eviction only makes room under the count limit
+	if m.maxPerEntity > 0 && m.entities[tx.EntityID] >= m.maxPerEntity {
+		return nil, ErrEntityQuotaExceeded
+	}
+	if !m.fitsBytes(txCost(tx)) {
+		return nil, ErrMempoolFull
+	}
+	if m.dedup {
+		if _, exists := m.contentIDs[contentHash(tx)]; exists {
+			return nil, ErrDuplicateContent
+		}
+	}
+
+	// Check size limit, making room if allowed
+	var evicted *Transaction
+	if m.isFull() {
+		if !m.evict {
+			return nil, ErrMempoolFull
+		}
+		i := m.lowestIndex()
+		if i < 0 || m.queue[i].Priority >= tx.Priority {
+			return nil, ErrMempoolFull
+		}
+		evicted = heap.Remove(&m.queue, i).(*Transaction)
+		m.untrack(evicted)
 	}
 
 	// Set timestamp if not set
@@ -119,12 +319,136 @@ func (m *Mempool) Add(tx *Transaction) error {
 	}
 
 	// Add to map and priority queue
-	m.pending[tx.ID] = tx
+	m.track(tx)
 	heap.Push(&m.queue, tx)
 
+	return evicted, nil
+}
+
+// Replace replaces the pending transaction with tx's ID by tx, but only if
+// tx has a strictly higher priority; otherwise it returns
+// ErrPriorityNotHigher. This lets a stuck transaction be bumped without
+// changing its ID. If tx carries no timestamp it inherits the original's,
+// so it keeps its place among equal priorities and its expiry time.
+// If no transaction with the ID is pending, Replace behaves like Add.
+func (m *Mempool) Replace(tx *Transaction) error {
+	if tx == nil {
+		return ErrInvalidTx
+	}
+
+	if err := tx.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, exists := m.pending[tx.ID]
+	if !exists {
+		_, err := m.addLocked(tx)
+		return err
+	}
+
+	if tx.Priority <= old.Priority {
+		return ErrPriorityNotHigher
+	}
+	if tx.EntityID != old.EntityID && m.maxPerEntity > 0 && m.entities[tx.EntityID] >= m.maxPerEntity {
+		return ErrEntityQuotaExceeded
+	}
+	if !m.fitsBytes(txCost(tx) - txCost(old)) {
+		return ErrMempoolFull
+	}
+	if m.dedup {
+		if id, exists := m.contentIDs[contentHash(tx)]; exists && id != tx.ID {
+			return ErrDuplicateContent
+		}
+	}
+
+	if tx.Timestamp.IsZero() {
+		tx.Timestamp = old.Timestamp
+	}
+
+	// Swap the entry in place and restore the heap order from there
+	for i, queued := range m.queue {
+		if queued == old {
+			m.queue[i] = tx
+			heap.Fix(&m.queue, i)
+			break
+		}
+	}
+	m.untrack(old)
+	m.track(tx)
+
 	return nil
 }
 
+// track adds tx to the pending map and the counters derived from it.
+// The caller must hold m.mu and push tx onto the queue.
+func (m *Mempool) track(tx *Transaction) {
+	m.pending[tx.ID] = tx
+	m.bytes += len(tx.Data)
+	m.bytesUsed += txCost(tx)
+	m.entities[tx.EntityID]++
+	if m.dedup {
+		m.contentIDs[contentHash(tx)] = tx.ID
+	}
+}
+
+// untrack removes tx from the pending map and the counters derived from
+// it. The caller must hold m.mu and remove tx from the queue.
+func (m *Mempool) untrack(tx *Transaction) {
+	delete(m.pending, tx.ID)
+	m.bytes -= len(tx.Data)
+	m.bytesUsed -= txCost(tx)
+	if m.entities[tx.EntityID] <= 1 {
+		delete(m.entities, tx.EntityID)
+	} else {
+		m.entities[tx.EntityID]--
+	}
+	if m.dedup {
+		delete(m.contentIDs, contentHash(tx))
+	}
+}
+
+// contentHash returns the SHA-256 of tx's EntityID, EventType and Data.
+// The strings are length-prefixed so different splits cannot collide.
+func contentHash(tx *Transaction) string {
+	h := sha256.New()
+	var n [8]byte
+	for _, field := range []string{tx.EntityID, tx.EventType} {
+		binary.BigEndian.PutUint64(n[:], uint64(len(field)))
+		h.Write(n[:])
+		h.Write([]byte(field))
+	}
+	h.Write(tx.Data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// txCost returns what tx counts against the byte limit.
+func txCost(tx *Transaction) int64 {
+	return int64(len(tx.Data)) + TxOverheadBytes
+}
+
+// fitsBytes reports whether n more bytes stay within the byte limit.
+// The caller must hold m.mu.
+func (m *Mempool) fitsBytes(n int64) bool {
+	return m.maxBytes <= 0 || m.bytesUsed+n <= m.maxBytes
+}
+
+// lowestIndex returns the queue index of the transaction that would be
+// popped last: the lowest priority, latest timestamp. It scans the queue
+// linearly since the heap only orders the highest priority. Returns -1 if
+// the queue is empty. The caller must hold m.mu.
+func (m *Mempool) lowestIndex() int {
+	lowest := -1
+	for i := range m.queue {
+		if lowest < 0 || m.queue.Less(lowest, i) {
+			lowest = i
+		}
+	}
+	return lowest
+}
+
 // Get retrieves a transaction by ID without removing it.
 func (m *Mempool) Get(txID string) *Transaction {
 	m.mu.RLock()
@@ -138,11 +462,12 @@ func (m *Mempool) Remove(txID string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.pending[txID]; !exists {
+	removed, exists := m.pending[txID]
+	if !exists {
 		return false
 	}
 
-	delete(m.pending, txID)
+	m.untrack(removed)
 
 	// Rebuild the queue without the removed transaction
 	newQueue := make(priorityQueue, 0, len(m.queue)-1)
@@ -158,6 +483,9 @@ func (m *Mempool) Remove(txID string) bool {
 }
 
 // PopBatch removes and returns up to n highest-priority transactions.
+// With NonceOrdering, a transaction is skipped while a lower nonce for its
+// entity is pending, so each entity's transactions come out in nonce
+// order; transactions without a valid nonce are ordered by priority only.
 func (m *Mempool) PopBatch(n int) []*Transaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -166,6 +494,10 @@ func (m *Mempool) PopBatch(n int) []*Transaction {
 		return nil
 	}
 
+	if m.nonceOrdering {
+		return m.popBatchByNonce(n)
+	}
+
 	// Limit to available transactions
 	if n > len(m.queue) {
 		n = len(m.queue)
@@ -174,13 +506,67 @@ func (m *Mempool) PopBatch(n int) []*Transaction {
 	batch := make([]*Transaction, 0, n)
 	for i := 0; i < n; i++ {
 		tx := heap.Pop(&m.queue).(*Transaction)
-		delete(m.pending, tx.ID)
+		m.untrack(tx)
 		batch = append(batch, tx)
 	}
 
 	return batch
 }
 
+// popBatchByNonce is PopBatch in nonce ordering mode. It sorts each
+// entity's pending nonces, so it costs O(n log n) in the mempool size.
+// The caller must hold m.mu.
+func (m *Mempool) popBatchByNonce(n int) []*Transaction {
+	// Pending nonces per entity, ascending; the first is the next due
+	nonces := make(map[string][]uint64)
+	for _, tx := range m.queue {
+		if nonce, ok := TxNonce(tx); ok {
+			nonces[tx.EntityID] = append(nonces[tx.EntityID], nonce)
+		}
+	}
+	for _, list := range nonces {
+		sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	}
+
+	// Transactions waiting on a lower nonce, by entity
+	blocked := make(map[string][]*Transaction)
+	batch := make([]*Transaction, 0, n)
+
+	for len(batch) < n && len(m.queue) > 0 {
+		tx := heap.Pop(&m.queue).(*Transaction)
+
+		nonce, ok := TxNonce(tx)
+		if ok {
+			list := nonces[tx.EntityID]
+			if nonce != list[0] {
+				blocked[tx.EntityID] = append(blocked[tx.EntityID], tx)
+				continue
+			}
+			nonces[tx.EntityID] = list[1:]
+
+			// The entity's next nonce may be among those set aside
+			for _, waiting := range blocked[tx.EntityID] {
+				heap.Push(&m.queue, waiting)
+			}
+			delete(blocked, tx.EntityID)
+		}
+
+		m.untrack(tx)
+		batch = append(batch, tx)
+	}
+
+	for _, waiting := range blocked {
+		for _, tx := range waiting {
+			heap.Push(&m.queue, tx)
+		}
+	}
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return batch
+}
+
 // Peek returns up to n highest-priority transactions without removing them.
 func (m *Mempool) Peek(n int) []*Transaction {
 	m.mu.RLock()
@@ -208,6 +594,84 @@ func (m *Mempool) Peek(n int) []*Transaction {
 	return batch
 }
 
+// ForEach calls fn for each pending transaction, in no particular order,
+// until fn returns false. It holds the read lock throughout and copies
+// nothing, so it is a cheap way to aggregate over a large mempool.
+// fn must not modify the transactions and must not call any Mempool
+// method: writers would deadlock, and readers may deadlock if a writer
+// is waiting.
+func (m *Mempool) ForEach(fn func(tx *Transaction) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, tx := range m.queue {
+		if !fn(tx) {
+			return
+		}
+	}
+}
+
+// PriorityAtPercentile returns the priority at percentile p (0-100) of the
+// pending transactions, using the nearest-rank method over ascending
+// priorities. Returns false if the mempool is empty or p is out of range.
+// Useful for fee estimation: a priority above the 90th percentile outranks
+// 90% of the current mempool.
+func (m *Mempool) PriorityAtPercentile(p float64) (int, bool) {
+	if math.IsNaN(p) || p < 0 || p > 100 {
+		return 0, false
+	}
+
+	m.mu.RLock()
+	priorities := make([]int, len(m.queue))
+	for i, tx := range m.queue {
+		priorities[i] = tx.Priority
+	}
+	m.mu.RUnlock()
+
+	n := len(priorities)
+	if n == 0 {
+		return 0, false
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(n)))
+	if rank < 1 {
+		rank = 1
+	}
+	return selectKth(priorities, rank-1), true
+}
+
+// selectKth returns the k-th smallest value (0-based), reordering values in place.
+// Runs in expected linear time.
+func selectKth(values []int, k int) int {
+	lo, hi := 0, len(values)-1
+	for lo < hi {
+		pivot := values[lo+(hi-lo)/2]
+		i, j := lo, hi
+		for i <= j {
+			for values[i] < pivot {
+				i++
+			}
+			for values[j] > pivot {
+				j--
+			}
+			if i <= j {
+				values[i], values[j] = values[j], values[i]
+				i++
+				j--
+			}
+		}
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			return values[k]
+		}
+	}
+	return values[k]
+}
+
 // Size returns the current number of transactions in the mempool.
 func (m *Mempool) Size() int {
 	m.mu.RLock()
@@ -219,7 +683,12 @@ func (m *Mempool) Size() int {
 func (m *Mempool) IsFull() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return len(m.pending) >= m.maxSize
+	return m.isFull()
+}
+
+// isFull reports whether the size limit is reached. The caller must hold m.mu.
+func (m *Mempool) isFull() bool {
+	return m.maxSize > 0 && len(m.pending) >= m.maxSize
 }
 
 // Clear removes all transactions from the mempool.
@@ -229,24 +698,148 @@ func (m *Mempool) Clear() {
 
 	m.pending = make(map[string]*Transaction)
 	m.queue = make(priorityQueue, 0)
+	m.bytes = 0
+	m.bytesUsed = 0
+	m.entities = make(map[string]int)
+	m.contentIDs = make(map[string]string)
 	heap.Init(&m.queue)
 }
 
+// TotalBytes returns the total size of the Data of pending transactions.
+// It is maintained on every change, so it assumes a transaction's Data is
+// not modified while it is in the mempool.
+func (m *Mempool) TotalBytes() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.bytes
+}
+
+// StartExpiry starts a background sweeper that evicts expired transactions
+// every interval. It is a no-op if the mempool has no TTL, interval is not
+// positive, or the sweeper is already running.
+func (m *Mempool) StartExpiry(interval time.Duration) {
+	if m.ttl <= 0 || interval <= 0 {
+		return
+	}
+
+	m.expiryMu.Lock()
+	defer m.expiryMu.Unlock()
+
+	if m.expiryStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	m.expiryStop = stop
+
+	m.expiryWg.Add(1)
+	go m.expiryLoop(interval, stop)
+}
+
+// StopExpiry stops the expiry sweeper and waits for it to exit. It is safe
+// to call if the sweeper is not running.
+func (m *Mempool) StopExpiry() {
+	m.expiryMu.Lock()
+	defer m.expiryMu.Unlock()
+
+	if m.expiryStop == nil {
+		return
+	}
+	close(m.expiryStop)
+	m.expiryStop = nil
+	m.expiryWg.Wait()
+}
+
+// expiryLoop runs expire every interval until stop is closed.
+func (m *Mempool) expiryLoop(interval time.Duration, stop chan struct{}) {
+	defer m.expiryWg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			m.expire(now)
+		}
+	}
+}
+
+// expire evicts transactions whose Timestamp plus the TTL is before now,
+// returning how many were evicted.
+func (m *Mempool) expire(now time.Time) int {
+	cutoff := now.Add(-m.ttl)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Filter the queue in place and drop the same transactions from the
+	// map, so both always hold the same set
+	kept := m.queue[:0]
+	for _, tx := range m.queue {
+		if tx.Timestamp.Before(cutoff) {
+			m.untrack(tx)
+			continue
+		}
+		kept = append(kept, tx)
+	}
+
+	evicted := len(m.queue) - len(kept)
+	if evicted == 0 {
+		return 0
+	}
+	for i := len(kept); i < len(m.queue); i++ {
+		m.queue[i] = nil // avoid memory leak
+	}
+	m.queue = kept
+	heap.Init(&m.queue)
+	m.expired += int64(evicted)
+
+	return evicted
+}
+
+// EntityCounts returns a copy of the number of pending transactions per
+// EntityID, for debugging.
+func (m *Mempool) EntityCounts() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int, len(m.entities))
+	for entityID, n := range m.entities {
+		counts[entityID] = n
+	}
+	return counts
+}
+
 // Stats returns mempool statistics.
 type MempoolStats struct {
-	Size      int `json:"size"`
-	MaxSize   int `json:"max_size"`
-	Available int `json:"available"`
+	Size      int   `json:"size"`
+	MaxSize   int   `json:"max_size"`
+	Available int   `json:"available"`
+	Bytes     int   `json:"bytes"`
+	Expired   int64 `json:"expired"`
+	BytesUsed int64 `json:"bytes_used"`
+	BytesMax  int64 `json:"bytes_max"`
 }
 
 func (m *Mempool) Stats() MempoolStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	available := m.maxSize - len(m.pending)
+	if m.maxSize == 0 {
+		available = -1 // unbounded
+	}
+
 	return MempoolStats{
 		Size:      len(m.pending),
 		MaxSize:   m.maxSize,
-		Available: m.maxSize - len(m.pending),
+		Available: available,
+		Bytes:     m.bytes,
+		Expired:   m.expired,
+		BytesUsed: m.bytesUsed,
+		BytesMax:  m.maxBytes,
 	}
 }
 
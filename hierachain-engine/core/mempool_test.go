@@ -79,6 +79,49 @@ func TestMempoolFull(t *testing.T) {
 	}
 }
 
+func TestMempoolUnbounded(t *testing.T) {
+	for _, size := range []int{0, -5} {
+		m := NewMempool(size)
+
+		for i := 0; i < 1000; i++ {
+			tx := &Transaction{
+				ID:        fmt.Sprintf("tx-%d", i),
+				EntityID:  "entity",
+				EventType: "test",
+			}
+			if err := m.Add(tx); err != nil {
+				t.Fatalf("maxSize %d: Add %d failed: %v", size, i, err)
+			}
+		}
+
+		if m.IsFull() {
+			t.Errorf("maxSize %d: unbounded mempool should never be full", size)
+		}
+
+		stats := m.Stats()
+		if stats.MaxSize != 0 || stats.Available != -1 {
+			t.Errorf("maxSize %d: expected MaxSize 0 and Available -1, got %+v", size, stats)
+		}
+	}
+}
+
+func TestNewBoundedMempool(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		if m, err := NewBoundedMempool(size); err != ErrInvalidMaxSize || m != nil {
+			t.Errorf("maxSize %d: expected ErrInvalidMaxSize, got %v", size, err)
+		}
+	}
+
+	m, err := NewBoundedMempool(1)
+	if err != nil {
+		t.Fatalf("NewBoundedMempool(1) failed: %v", err)
+	}
+	_ = m.Add(&Transaction{ID: "tx-1", EntityID: "entity", EventType: "test"})
+	if err := m.Add(&Transaction{ID: "tx-2", EntityID: "entity", EventType: "test"}); err != ErrMempoolFull {
+		t.Errorf("Expected ErrMempoolFull, got %v", err)
+	}
+}
+
 func TestMempoolGet(t *testing.T) {
 	m := NewMempool(10)
 
@@ -164,6 +207,532 @@ func TestMempoolPopBatch(t *testing.T) {
 	}
 }
 
+func TestMempoolTotalBytes(t *testing.T) {
+	m := NewMempool(4)
+
+	for i := 0; i < 4; i++ {
+		tx := &Transaction{
+			ID:        fmt.Sprintf("tx-%d", i),
+			EntityID:  "entity",
+			EventType: "test",
+			Data:      make([]byte, 10*(i+1)), // 10, 20, 30, 40 bytes
+			Priority:  i,
+		}
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if m.TotalBytes() != 100 {
+		t.Errorf("Expected 100 bytes, got %d", m.TotalBytes())
+	}
+
+	// Rejected adds do not count
+	_ = m.Add(&Transaction{ID: "tx-0", EntityID: "entity", EventType: "test", Data: make([]byte, 5)})
+	_ = m.Add(&Transaction{ID: "tx-9", EntityID: "entity", EventType: "test", Data: make([]byte, 5)})
+	if m.TotalBytes() != 100 {
+		t.Errorf("Expected 100 bytes after rejected adds, got %d", m.TotalBytes())
+	}
+
+	m.Remove("tx-1")
+	if m.TotalBytes() != 80 {
+		t.Errorf("Expected 80 bytes after remove, got %d", m.TotalBytes())
+	}
+
+	// Pops the two highest priorities: tx-3 (40) and tx-2 (30)
+	m.PopBatch(2)
+	if stats := m.Stats(); stats.Bytes != 10 {
+		t.Errorf("Expected 10 bytes in stats after pop, got %d", stats.Bytes)
+	}
+
+	m.Clear()
+	if m.TotalBytes() != 0 {
+		t.Errorf("Expected 0 bytes after clear, got %d", m.TotalBytes())
+	}
+}
+
+func TestMempoolAddOrEvict(t *testing.T) {
+	m := NewMempoolWithConfig(MempoolConfig{MaxSize: 3, EvictOnFull: true})
+	base := time.Now()
+
+	add := func(id string, priority int, age time.Duration) (*Transaction, error) {
+		return m.AddOrEvict(&Transaction{
+			ID:        id,
+			EntityID:  "entity",
+			EventType: "test",
+			Data:      make([]byte, priority),
+			Priority:  priority,
+			Timestamp: base.Add(-age),
+		})
+	}
+
+	for _, tx := range []struct {
+		id       string
+		priority int
+		age      time.Duration
+	}{{"low-old", 1, 2 * time.Second}, {"low-new", 1, time.Second}, {"high", 5, 0}} {
+		if _, err := add(tx.id, tx.priority, tx.age); err != nil {
+			t.Fatalf("Add %s failed: %v", tx.id, err)
+		}
+	}
+
+	// An equal-priority newcomer is rejected rather than displacing anything
+	if evicted, err := add("equal", 1, 3*time.Second); err != ErrMempoolFull || evicted != nil {
+		t.Errorf("Expected ErrMempoolFull for equal priority, got %v, %v", evicted, err)
+	}
+
+	// A higher priority evicts the most recent of the lowest priority
+	evicted, err := add("mid", 3, 0)
+	if err != nil {
+		t.Fatalf("AddOrEvict failed: %v", err)
+	}
+	if evicted == nil || evicted.ID != "low-new" {
+		t.Fatalf("Expected low-new to be evicted, got %v", evicted)
+	}
+	if m.Contains("low-new") || !m.Contains("mid") || m.Size() != 3 {
+		t.Errorf("Expected mid to replace low-new at size 3, got size %d", m.Size())
+	}
+	if m.TotalBytes() != 1+5+3 {
+		t.Errorf("Expected %d bytes after eviction, got %d", 1+5+3, m.TotalBytes())
+	}
+
+	// Add evicts too, and the queue stays consistent with the map
+	if err := m.Add(&Transaction{ID: "top", EntityID: "entity", EventType: "test", Priority: 9}); err != nil {
+		t.Fatalf("Add with eviction failed: %v", err)
+	}
+	batch := m.PopBatch(10)
+	if len(batch) != 3 || batch[0].ID != "top" || batch[1].ID != "high" || batch[2].ID != "mid" {
+		t.Errorf("Expected top, high, mid, got %v", batch)
+	}
+
+	// Without EvictOnFull a full mempool rejects any newcomer
+	plain := NewMempool(1)
+	_ = plain.Add(&Transaction{ID: "a", EntityID: "entity", EventType: "test", Priority: 1})
+	if evicted, err := plain.AddOrEvict(&Transaction{ID: "b", EntityID: "entity", EventType: "test", Priority: 9}); err != ErrMempoolFull || evicted != nil {
+		t.Errorf("Expected ErrMempoolFull without EvictOnFull, got %v, %v", evicted, err)
+	}
+}
+
+func TestMempoolReplace(t *testing.T) {
+	m := NewMempool(10)
+
+	for i, priority := range []int{5, 3, 1} {
+		tx := &Transaction{
+			ID:        fmt.Sprintf("tx-%d", i),
+			EntityID:  "entity",
+			EventType: "test",
+			Priority:  priority,
+		}
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	original := m.Get("tx-2")
+
+	// Equal or lower priority is rejected and leaves the original in place
+	err := m.Replace(&Transaction{ID: "tx-2", EntityID: "entity", EventType: "test", Priority: 1})
+	if err != ErrPriorityNotHigher {
+		t.Errorf("Expected ErrPriorityNotHigher, got %v", err)
+	}
+	if m.Get("tx-2") != original {
+		t.Error("Rejected replacement should keep the original transaction")
+	}
+
+	// Bumping the lowest transaction moves it to the front
+	bumped := &Transaction{ID: "tx-2", EntityID: "entity", EventType: "test", Priority: 9, Data: []byte("fee")}
+	if err := m.Replace(bumped); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if m.Size() != 3 || m.TotalBytes() != 3 {
+		t.Errorf("Expected size 3 and 3 bytes, got %d and %d", m.Size(), m.TotalBytes())
+	}
+	if !bumped.Timestamp.Equal(original.Timestamp) {
+		t.Error("Replacement without a timestamp should inherit the original's")
+	}
+
+	batch := m.PopBatch(3)
+	if len(batch) != 3 || batch[0] != bumped || batch[1].ID != "tx-0" || batch[2].ID != "tx-1" {
+		t.Errorf("Expected tx-2, tx-0, tx-1 after replacement, got %v", batch)
+	}
+
+	// An unknown ID is simply added
+	if err := m.Replace(&Transaction{ID: "tx-new", EntityID: "entity", EventType: "test"}); err != nil {
+		t.Errorf("Replace of a new ID failed: %v", err)
+	}
+	if !m.Contains("tx-new") {
+		t.Error("Replace of a new ID should add it")
+	}
+}
+
+func TestMempoolEntityQuota(t *testing.T) {
+	m := NewMempoolWithConfig(MempoolConfig{MaxPerEntity: 2})
+
+	add := func(id, entityID string, priority int) error {
+		return m.Add(&Transaction{ID: id, EntityID: entityID, EventType: "test", Priority: priority})
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := add(fmt.Sprintf("flood-%d", i), "flooder", 10-i); err != nil {
+			t.Fatalf("Add within quota failed: %v", err)
+		}
+	}
+	if err := add("flood-2", "flooder", 1); err != ErrEntityQuotaExceeded {
+		t.Errorf("Expected ErrEntityQuotaExceeded, got %v", err)
+	}
+	if err := add("other-0", "other", 5); err != nil {
+		t.Errorf("Other entities should be unaffected, got %v", err)
+	}
+
+	counts := m.EntityCounts()
+	if len(counts) != 2 || counts["flooder"] != 2 || counts["other"] != 1 {
+		t.Errorf("Unexpected entity counts: %v", counts)
+	}
+
+	counts["flooder"] = 0 // the returned map is a copy
+	if m.EntityCounts()["flooder"] != 2 {
+		t.Error("EntityCounts should return a copy")
+	}
+
+	// Popping frees quota, whether one at a time or in a batch
+	m.PopBatch(1) // flood-0
+	if err := add("flood-2", "flooder", 1); err != nil {
+		t.Errorf("Add after pop should fit the quota, got %v", err)
+	}
+	m.PopBatch(2) // flood-1 and other-0
+	if counts := m.EntityCounts(); counts["flooder"] != 1 || counts["other"] != 0 {
+		t.Errorf("Expected flooder 1 and no other after popping, got %v", counts)
+	}
+
+	m.Remove("flood-2")
+	if counts := m.EntityCounts(); len(counts) != 0 {
+		t.Errorf("Expected no entity counts after remove, got %v", counts)
+	}
+
+	_ = add("a", "flooder", 1)
+	m.Clear()
+	if counts := m.EntityCounts(); len(counts) != 0 {
+		t.Errorf("Expected no entity counts after clear, got %v", counts)
+	}
+}
+
+func TestMempoolExpire(t *testing.T) {
+	m := NewMempoolWithConfig(MempoolConfig{MaxSize: 10, TTL: time.Minute})
+	now := time.Now()
+
+	ages := []time.Duration{2 * time.Minute, 30 * time.Second, 90 * time.Second, 0}
+	for i, age := range ages {
+		tx := &Transaction{
+			ID:        fmt.Sprintf("tx-%d", i),
+			EntityID:  "entity",
+			EventType: "test",
+			Data:      make([]byte, 10),
+			Priority:  i,
+			Timestamp: now.Add(-age),
+		}
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	if evicted := m.expire(now); evicted != 2 {
+		t.Errorf("Expected 2 expired transactions, got %d", evicted)
+	}
+	if m.Contains("tx-0") || m.Contains("tx-2") {
+		t.Error("Transactions older than the TTL should be evicted")
+	}
+
+	stats := m.Stats()
+	if stats.Size != 2 || stats.Expired != 2 || stats.Bytes != 20 {
+		t.Errorf("Expected size 2, 2 expired and 20 bytes, got %+v", stats)
+	}
+
+	// The queue holds exactly the remaining transactions, in priority order
+	batch := m.PopBatch(10)
+	if len(batch) != 2 || batch[0].ID != "tx-3" || batch[1].ID != "tx-1" {
+		t.Errorf("Expected tx-3 then tx-1, got %v", batch)
+	}
+
+	// Without a TTL the sweeper never starts
+	unlimited := NewMempool(10)
+	unlimited.StartExpiry(time.Millisecond)
+	if unlimited.expiryStop != nil {
+		t.Error("Expiry should not start without a TTL")
+	}
+	unlimited.StopExpiry()
+}
+
+func TestMempoolExpirySweeper(t *testing.T) {
+	m := NewMempoolWithConfig(MempoolConfig{TTL: 5 * time.Millisecond})
+	m.StartExpiry(time.Millisecond)
+	m.StartExpiry(time.Millisecond) // already running: no-op
+
+	var wg sync.WaitGroup
+	var popped int64
+	var popMu sync.Mutex
+	const adders, perAdder = 4, 200
+
+	// Add and pop concurrently with the sweeper
+	for i := 0; i < adders; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < perAdder; j++ {
+				tx := &Transaction{
+					ID:        fmt.Sprintf("tx-%d-%d", id, j),
+					EntityID:  "entity",
+					EventType: "test",
+				}
+				if err := m.Add(tx); err != nil {
+					t.Errorf("Add failed: %v", err)
+				}
+				if j%10 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				n := len(m.PopBatch(3))
+				popMu.Lock()
+				popped += int64(n)
+				popMu.Unlock()
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Everything left over expires
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Size() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	m.StopExpiry()
+	m.StopExpiry() // already stopped: no-op
+
+	stats := m.Stats()
+	if stats.Size != 0 {
+		t.Fatalf("Expected all transactions to expire, %d remain", stats.Size)
+	}
+	if total := popped + stats.Expired; total != adders*perAdder {
+		t.Errorf("Expected popped + expired = %d, got %d + %d", adders*perAdder, popped, stats.Expired)
+	}
+	if len(m.queue) != 0 || len(m.pending) != 0 {
+		t.Errorf("Expected empty queue and map, got %d and %d", len(m.queue), len(m.pending))
+	}
+}
+
+func TestMempoolByteLimit(t *testing.T) {
+	// Room for three 100-byte transactions
+	m := NewMempoolWithByteLimit(3 * (100 + TxOverheadBytes))
+
+	newTx := func(id string, size, priority int) *Transaction {
+		return &Transaction{ID: id, EntityID: "entity", EventType: "test", Data: make([]byte, size), Priority: priority}
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := m.Add(newTx(fmt.Sprintf("tx-%d", i), 100, i)); err != nil {
+			t.Fatalf("Add within budget failed: %v", err)
+		}
+	}
+	if err := m.Add(newTx("tx-3", 1, 0)); err != ErrMempoolFull {
+		t.Errorf("Expected ErrMempoolFull past the byte budget, got %v", err)
+	}
+
+	stats := m.Stats()
+	if stats.BytesUsed != stats.BytesMax || stats.BytesMax != 3*(100+TxOverheadBytes) {
+		t.Errorf("Expected a full budget of %d, got %d of %d", 3*(100+TxOverheadBytes), stats.BytesUsed, stats.BytesMax)
+	}
+
+	// Every removal path returns its bytes to the budget
+	m.Remove("tx-0")
+	m.PopBatch(1) // tx-2
+	if used := m.Stats().BytesUsed; used != 100+TxOverheadBytes {
+		t.Errorf("Expected %d bytes used, got %d", 100+TxOverheadBytes, used)
+	}
+
+	// A replacement that grows past the budget is rejected
+	if err := m.Add(newTx("big", 100+100+TxOverheadBytes, 0)); err != nil {
+		t.Fatalf("Add filling the budget failed: %v", err)
+	}
+	if err := m.Replace(newTx("tx-1", 101, 9)); err != ErrMempoolFull {
+		t.Errorf("Expected ErrMempoolFull for a growing replacement, got %v", err)
+	}
+	if err := m.Replace(newTx("tx-1", 50, 9)); err != nil {
+		t.Errorf("Shrinking replacement failed: %v", err)
+	}
+
+	m.Clear()
+	if used := m.Stats().BytesUsed; used != 0 {
+		t.Errorf("Expected 0 bytes used after clear, got %d", used)
+	}
+
+	// Count-bounded mempools report no byte limit
+	if limit := NewMempool(10).Stats().BytesMax; limit != 0 {
+		t.Errorf("Expected no byte limit, got %d", limit)
+	}
+}
+
+func TestMempoolDedupContent(t *testing.T) {
+	m := NewMempoolWithDedup(10, true)
+
+	newTx := func(id, data string, priority int) *Transaction {
+		return &Transaction{ID: id, EntityID: "entity", EventType: "transfer", Data: []byte(data), Priority: priority}
+	}
+
+	if err := m.Add(newTx("tx-1", "payload", 1)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := m.Add(newTx("tx-2", "payload", 1)); err != ErrDuplicateContent {
+		t.Errorf("Expected ErrDuplicateContent for the same content, got %v", err)
+	}
+	if err := m.Add(newTx("tx-3", "other", 1)); err != nil {
+		t.Errorf("Different content should be accepted, got %v", err)
+	}
+
+	// Field boundaries matter: "entity"+"transfer" differs from "entit"+"ytransfer"
+	shifted := &Transaction{ID: "tx-4", EntityID: "entit", EventType: "ytransfer", Data: []byte("payload")}
+	if err := m.Add(shifted); err != nil {
+		t.Errorf("Content with shifted field boundaries should be accepted, got %v", err)
+	}
+
+	// A replacement may keep its own content but not take another's
+	if err := m.Replace(newTx("tx-1", "payload", 5)); err != nil {
+		t.Errorf("Replacing with the same content failed: %v", err)
+	}
+	if err := m.Replace(newTx("tx-1", "other", 9)); err != ErrDuplicateContent {
+		t.Errorf("Expected ErrDuplicateContent replacing with tx-3's content, got %v", err)
+	}
+
+	// Removing or popping a transaction frees its content
+	m.Remove("tx-3")
+	if err := m.Add(newTx("tx-5", "other", 1)); err != nil {
+		t.Errorf("Content should be free after remove, got %v", err)
+	}
+	m.PopBatch(1) // tx-1
+	if err := m.Add(newTx("tx-6", "payload", 1)); err != nil {
+		t.Errorf("Content should be free after pop, got %v", err)
+	}
+	m.Clear()
+	if err := m.Add(newTx("tx-7", "payload", 1)); err != nil {
+		t.Errorf("Content should be free after clear, got %v", err)
+	}
+
+	// Without dedup identical content is allowed
+	plain := NewMempoolWithDedup(10, false)
+	_ = plain.Add(newTx("tx-1", "payload", 1))
+	if err := plain.Add(newTx("tx-2", "payload", 1)); err != nil {
+		t.Errorf("Expected duplicate content to be accepted without dedup, got %v", err)
+	}
+}
+
+func TestMempoolForEach(t *testing.T) {
+	m := NewMempool(10)
+	for i := 0; i < 5; i++ {
+		_ = m.Add(&Transaction{ID: fmt.Sprintf("tx-%d", i), EntityID: "entity", EventType: "test", Priority: i})
+	}
+
+	seen := make(map[string]bool)
+	total := 0
+	m.ForEach(func(tx *Transaction) bool {
+		seen[tx.ID] = true
+		total += tx.Priority
+		return true
+	})
+	if len(seen) != 5 || total != 0+1+2+3+4 {
+		t.Errorf("Expected all 5 transactions with priority sum 10, got %d and %d", len(seen), total)
+	}
+
+	// Returning false stops the iteration
+	calls := 0
+	m.ForEach(func(tx *Transaction) bool {
+		calls++
+		return calls < 2
+	})
+	if calls != 2 {
+		t.Errorf("Expected iteration to stop after 2 calls, got %d", calls)
+	}
+
+	NewMempool(10).ForEach(func(tx *Transaction) bool {
+		t.Error("Callback should not run for an empty mempool")
+		return true
+	})
+}
+
+func TestMempoolNonceOrdering(t *testing.T) {
+	m := NewMempoolWithNonceOrdering()
+
+	add := func(id, entityID string, priority int, nonce interface{}) {
+		tx := &Transaction{ID: id, EntityID: entityID, EventType: "test", Priority: priority}
+		if nonce != nil {
+			tx.Metadata = map[string]interface{}{NonceMetadataKey: nonce}
+		}
+		if err := m.Add(tx); err != nil {
+			t.Fatalf("Add %s failed: %v", id, err)
+		}
+	}
+
+	add("a-1", "a", 1, 1)
+	add("a-2", "a", 9, float64(2)) // JSON numbers decode as float64
+	add("a-3", "a", 5, "3")
+	add("b-5", "b", 7, int64(5))
+	add("legacy", "a", 8, nil)
+	add("bad-nonce", "a", 6, -4) // out of range: priority only
+
+	// a-2 outranks everything but must wait for a-1
+	batch := m.PopBatch(2)
+	if len(batch) != 2 || batch[0].ID != "legacy" || batch[1].ID != "b-5" {
+		t.Fatalf("Expected legacy, b-5, got %v", batch)
+	}
+	if m.Size() != 4 || !m.Contains("a-2") {
+		t.Fatalf("Skipped transactions should stay pending, size %d", m.Size())
+	}
+
+	batch = m.PopBatch(10)
+	expected := []string{"bad-nonce", "a-1", "a-2", "a-3"}
+	if len(batch) != len(expected) {
+		t.Fatalf("Expected %d transactions, got %d", len(expected), len(batch))
+	}
+	for i, id := range expected {
+		if batch[i].ID != id {
+			t.Errorf("Position %d: expected %s, got %s", i, id, batch[i].ID)
+		}
+	}
+	if m.Size() != 0 {
+		t.Errorf("Expected empty mempool, got %d", m.Size())
+	}
+}
+
+func TestTxNonce(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		nonce uint64
+		ok    bool
+	}{
+		{7, 7, true},
+		{int64(8), 8, true},
+		{uint64(9), 9, true},
+		{float64(10), 10, true},
+		{" 11 ", 11, true},
+		{nil, 0, false},
+		{-1, 0, false},
+		{1.5, 0, false},
+		{float64(1 << 60), 0, false},
+		{"x", 0, false},
+	}
+
+	for _, tt := range tests {
+		tx := &Transaction{Metadata: map[string]interface{}{NonceMetadataKey: tt.value}}
+		nonce, ok := TxNonce(tx)
+		if ok != tt.ok || (ok && nonce != tt.nonce) {
+			t.Errorf("TxNonce(%v): expected %d, %v, got %d, %v", tt.value, tt.nonce, tt.ok, nonce, ok)
+		}
+	}
+}
+
 func TestMempoolConcurrency(t *testing.T) {
 	m := NewMempool(1000)
 	var wg sync.WaitGroup
@@ -189,6 +758,58 @@ func TestMempoolConcurrency(t *testing.T) {
 	}
 }
 
+func TestMempoolPriorityAtPercentile(t *testing.T) {
+	m := NewMempool(200)
+
+	if _, ok := m.PriorityAtPercentile(50); ok {
+		t.Error("Expected no percentile for empty mempool")
+	}
+
+	// Priorities 1..100, inserted out of order
+	for i := 0; i < 100; i++ {
+		tx := &Transaction{
+			ID:        fmt.Sprintf("tx-%d", i),
+			EntityID:  "entity",
+			EventType: "test",
+			Priority:  (i*37)%100 + 1,
+		}
+		_ = m.Add(tx)
+	}
+
+	cases := []struct {
+		percentile float64
+		expected   int
+	}{
+		{0, 1},
+		{1, 1},
+		{50, 50},
+		{90, 90},
+		{99.5, 100},
+		{100, 100},
+	}
+	for _, c := range cases {
+		got, ok := m.PriorityAtPercentile(c.percentile)
+		if !ok {
+			t.Errorf("Percentile %v: expected a value", c.percentile)
+			continue
+		}
+		if got != c.expected {
+			t.Errorf("Percentile %v: expected %d, got %d", c.percentile, c.expected, got)
+		}
+	}
+
+	for _, invalid := range []float64{-1, 101} {
+		if _, ok := m.PriorityAtPercentile(invalid); ok {
+			t.Errorf("Percentile %v should be rejected", invalid)
+		}
+	}
+
+	// Query must not mutate the mempool ordering
+	if top := m.Peek(1); len(top) != 1 || top[0].Priority != 100 {
+		t.Error("PriorityAtPercentile mutated the queue")
+	}
+}
+
 func BenchmarkMempoolAdd(b *testing.B) {
 	m := NewMempool(b.N + 1)
 	b.ResetTimer()
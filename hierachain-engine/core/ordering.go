@@ -2,10 +2,16 @@ package core
 
 import (
 	"errors"
+	"log"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
 
+// ErrServicePaused is returned by SubmitEvent while the service is paused.
+var ErrServicePaused = errors.New("ordering service is paused")
+
 // OrderingStatus represents the status of the ordering service.
 type OrderingStatus int
 
@@ -43,6 +49,7 @@ const (
 	EventOrdered
 	EventCertified
 	EventRejected
+	EventCancelled
 )
 
 func (s EventStatus) String() string {
@@ -57,6 +64,8 @@ func (s EventStatus) String() string {
 		return "certified"
 	case EventRejected:
 		return "rejected"
+	case EventCancelled:
+		return "cancelled"
 	default:
 		return "unknown"
 	}
@@ -75,49 +84,100 @@ type PendingEvent struct {
 
 // Certification contains validation result for an event.
 type Certification struct {
-	EventID  string
-	Valid    bool
-	Errors   []string
-	CertAt   time.Time
-	Metadata map[string]interface{}
+	EventID     string
+	Valid       bool
+	Errors      []string
+	FailedRules []string // names of the rules that failed, in order
+	CertAt      time.Time
+	Metadata    map[string]interface{}
 }
 
 // ValidationRule is a function that validates event data.
 type ValidationRule func(data map[string]interface{}) error
 
+// NamedRule is a validation rule with a name that identifies its failures
+// in Certification.FailedRules.
+type NamedRule struct {
+	Name string
+	Fn   ValidationRule
+}
+
+// Rule names used by the certifier itself.
+const (
+	// RequiredFieldsRuleName names the built-in required field check.
+	RequiredFieldsRuleName = "required_fields"
+	// TimestampRuleName names DefaultTimestampRule.
+	TimestampRuleName = "timestamp"
+)
+
 // EventCertifier validates events before ordering.
 type EventCertifier struct {
-	rules []ValidationRule
-	certs map[string]*Certification
-	mu    sync.RWMutex
+	rules   []NamedRule
+	certs   map[string]*Certification
+	autoSeq int // numbers rules registered without a name
+	mu      sync.RWMutex
 }
 
 // NewEventCertifier creates a new event certifier.
 func NewEventCertifier() *EventCertifier {
 	return &EventCertifier{
-		rules: make([]ValidationRule, 0),
+		rules: make([]NamedRule, 0),
 		certs: make(map[string]*Certification),
 	}
 }
 
-// AddRule registers a validation rule.
+// AddRule registers a validation rule under a generated name, rule-1,
+// rule-2 and so on.
 func (c *EventCertifier) AddRule(rule ValidationRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = append(c.rules, c.autoNamed(rule))
+}
+
+// AddNamedRule registers a named validation rule.
+func (c *EventCertifier) AddNamedRule(rule NamedRule) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.rules = append(c.rules, rule)
 }
 
+// autoNamed gives rule the next generated name. The caller holds mu.
+func (c *EventCertifier) autoNamed(rule ValidationRule) NamedRule {
+	c.autoSeq++
+	return NamedRule{Name: "rule-" + strconv.Itoa(c.autoSeq), Fn: rule}
+}
+
+// SetRules replaces the registered validation rules with rules, named as
+// by AddRule. Certification history is kept. A Validate call applies
+// either the old or the new rules, never a mixture.
+func (c *EventCertifier) SetRules(rules []ValidationRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rules = make([]NamedRule, 0, len(rules))
+	for _, rule := range rules {
+		c.rules = append(c.rules, c.autoNamed(rule))
+	}
+}
+
+// ClearRules removes all registered validation rules, leaving only the
+// required field check.
+func (c *EventCertifier) ClearRules() {
+	c.SetRules(nil)
+}
+
 // Validate validates an event and returns certification result.
 func (c *EventCertifier) Validate(event *PendingEvent) *Certification {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	cert := &Certification{
-		EventID:  event.ID,
-		Valid:    true,
-		Errors:   make([]string, 0),
-		CertAt:   time.Now(),
-		Metadata: make(map[string]interface{}),
+		EventID:     event.ID,
+		Valid:       true,
+		Errors:      make([]string, 0),
+		FailedRules: make([]string, 0),
+		CertAt:      time.Now(),
+		Metadata:    make(map[string]interface{}),
 	}
 
 	// Check required fields
@@ -128,12 +188,16 @@ func (c *EventCertifier) Validate(event *PendingEvent) *Certification {
 			cert.Errors = append(cert.Errors, "missing required field: "+field)
 		}
 	}
+	if !cert.Valid {
+		cert.FailedRules = append(cert.FailedRules, RequiredFieldsRuleName)
+	}
 
 	// Apply custom rules
 	for _, rule := range c.rules {
-		if err := rule(event.Data); err != nil {
+		if err := rule.Fn(event.Data); err != nil {
 			cert.Valid = false
 			cert.Errors = append(cert.Errors, err.Error())
+			cert.FailedRules = append(cert.FailedRules, rule.Name)
 		}
 	}
 
@@ -158,29 +222,59 @@ type BlockBuilder struct {
 	currentBatch []*PendingEvent
 	batchIDs     map[string]bool
 	batchStart   time.Time
-	mu           sync.Mutex
+
+	// IDs of the most recently sealed events, oldest overwritten first
+	recentIDs  []string
+	recentNext int
+	recentSet  map[string]bool
+	dedupSize  int
+
+	duplicates int64
+	mu         sync.Mutex
 }
 
 // NewBlockBuilder creates a new block builder.
 func NewBlockBuilder(blockSize int, timeout time.Duration) *BlockBuilder {
+	return NewBlockBuilderWithDedup(blockSize, timeout, 0)
+}
+
+// NewBlockBuilderWithDedup creates a block builder that also rejects
+// events whose ID was among the last window sealed events, so a retried
+// event does not reappear in the next block. Zero or less only rejects
+// duplicates within the current batch.
+func NewBlockBuilderWithDedup(blockSize int, timeout time.Duration, window int) *BlockBuilder {
+	if window < 0 {
+		window = 0
+	}
 	return &BlockBuilder{
 		blockSize:    blockSize,
 		batchTimeout: timeout,
 		currentBatch: make([]*PendingEvent, 0, blockSize),
 		batchIDs:     make(map[string]bool),
 		batchStart:   time.Now(),
+		recentIDs:    make([]string, 0, window),
+		recentSet:    make(map[string]bool, window),
+		dedupSize:    window,
 	}
 }
 
 // AddEvent adds a certified event to the current batch.
 // Returns the batch if ready for block creation, nil otherwise.
 func (b *BlockBuilder) AddEvent(event *PendingEvent) []*PendingEvent {
+	batch, _ := b.add(event)
+	return batch
+}
+
+// add is AddEvent that also reports whether event was added, false for a
+// duplicate.
+func (b *BlockBuilder) add(event *PendingEvent) ([]*PendingEvent, bool) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	// Skip duplicates
-	if b.batchIDs[event.ID] {
-		return nil
+	if b.batchIDs[event.ID] || b.recentSet[event.ID] {
+		b.duplicates++
+		return nil, false
 	}
 
 	// Start timer on first event
@@ -193,10 +287,10 @@ func (b *BlockBuilder) AddEvent(event *PendingEvent) []*PendingEvent {
 
 	// Check if batch is ready
 	if b.isReady() {
-		return b.finalize()
+		return b.finalize(), true
 	}
 
-	return nil
+	return nil, true
 }
 
 // ForceFlush forces block creation from current batch.
@@ -210,6 +304,37 @@ func (b *BlockBuilder) ForceFlush() []*PendingEvent {
 	return b.finalize()
 }
 
+// Remove drops the event with eventID from the current batch and reports
+// whether it was there.
+func (b *BlockBuilder) Remove(eventID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.batchIDs[eventID] {
+		return false
+	}
+	delete(b.batchIDs, eventID)
+	for i, e := range b.currentBatch {
+		if e.ID == eventID {
+			b.currentBatch = append(b.currentBatch[:i], b.currentBatch[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// FlushExpired returns the current batch if it is non-empty and older than
+// the batch timeout, nil otherwise.
+func (b *BlockBuilder) FlushExpired() []*PendingEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.currentBatch) == 0 || time.Since(b.batchStart) < b.batchTimeout {
+		return nil
+	}
+	return b.finalize()
+}
+
 // isReady checks if batch is ready (called with lock held).
 func (b *BlockBuilder) isReady() bool {
 	if len(b.currentBatch) >= b.blockSize {
@@ -223,6 +348,10 @@ func (b *BlockBuilder) isReady() bool {
 
 // finalize returns current batch and resets (called with lock held).
 func (b *BlockBuilder) finalize() []*PendingEvent {
+	for _, e := range b.currentBatch {
+		b.rememberSealed(e.ID)
+	}
+
 	batch := b.currentBatch
 	b.currentBatch = make([]*PendingEvent, 0, b.blockSize)
 	b.batchIDs = make(map[string]bool)
@@ -230,6 +359,30 @@ func (b *BlockBuilder) finalize() []*PendingEvent {
 	return batch
 }
 
+// rememberSealed adds id to the recent-ID window, evicting the oldest
+// once it is full (called with lock held).
+func (b *BlockBuilder) rememberSealed(id string) {
+	if b.dedupSize == 0 {
+		return
+	}
+	if len(b.recentIDs) < b.dedupSize {
+		b.recentIDs = append(b.recentIDs, id)
+	} else {
+		delete(b.recentSet, b.recentIDs[b.recentNext])
+		b.recentIDs[b.recentNext] = id
+		b.recentNext = (b.recentNext + 1) % b.dedupSize
+	}
+	b.recentSet[id] = true
+}
+
+// DuplicatesDropped returns how many events AddEvent has dropped as
+// duplicates.
+func (b *BlockBuilder) DuplicatesDropped() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.duplicates
+}
+
 // BatchSize returns current batch size.
 func (b *BlockBuilder) BatchSize() int {
 	b.mu.Lock()
@@ -243,6 +396,9 @@ type OrderingConfig struct {
 	BatchTimeout time.Duration
 	Workers      int
 	MaxPending   int
+	// DedupWindow is how many recently sealed event IDs each channel
+	// remembers to drop retried duplicates; zero disables the check
+	DedupWindow int
 }
 
 // DefaultOrderingConfig returns default configuration.
@@ -252,29 +408,81 @@ func DefaultOrderingConfig() OrderingConfig {
 		BatchTimeout: 2 * time.Second,
 		Workers:      8,
 		MaxPending:   10000,
+		DedupWindow:  5000,
 	}
 }
 
-// OrderingService coordinates event ordering and block creation.
+// DefaultChannel is the channel of events with an empty ChannelID.
+const DefaultChannel = "default"
+
+// Block is a sealed batch of events as published on a Blocks channel.
+type Block struct {
+	ChannelID string
+	Seq       int64 // height on the channel, 1 for its first block
+	Events    []*PendingEvent
+	SealedAt  time.Time
+}
+
+// FinalizedBlock describes a block sealed by the ordering service.
+type FinalizedBlock struct {
+	ChannelID  string    `json:"channel_id"`
+	Sequence   int64     `json:"sequence"` // same as Block.Seq: 1 for the first block sealed on the channel
+	EventCount int       `json:"event_count"`
+	EventIDs   []string  `json:"event_ids"`
+	MerkleRoot string    `json:"merkle_root,omitempty"` // empty without a MerkleRootFunc
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// MerkleRootFunc computes the Merkle root of a block's events, such as
+// the Rust implementation exposed by the integration package.
+type MerkleRootFunc func(events []*PendingEvent) (string, error)
+
+// orderingChannel batches the events of one channel into its own block
+// stream.
+type orderingChannel struct {
+	id      string
+	builder *BlockBuilder
+	blocks  chan *Block
+
+	// Guarded by the service's mu
+	eventsCertified int64
+	blocksCreated   int64
+
+	// Keeps blocks from the event loop and the timeout checker in
+	// sequence order
+	sealMu sync.Mutex
+}
+
+// OrderingService coordinates event ordering and block creation. Events
+// are batched per ChannelID, each channel producing its own blocks.
 type OrderingService struct {
-	config       OrderingConfig
-	status       OrderingStatus
-	certifier    *EventCertifier
-	blockBuilder *BlockBuilder
-	workerPool   *WorkerPool
+	config     OrderingConfig
+	status     OrderingStatus
+	certifier  *EventCertifier
+	workerPool *WorkerPool
 
 	eventChan chan *PendingEvent
-	blockChan chan []*PendingEvent
+	channels  map[string]*orderingChannel
 
 	pending map[string]*PendingEvent
 	mu      sync.RWMutex
 
+	// Block sealing and finalization hooks
+	onSealed    []func(*Block)
+	onFinalized []func(FinalizedBlock)
+	merkleRoot  MerkleRootFunc
+
 	// Stats
 	eventsReceived  int64
 	eventsCertified int64
 	eventsRejected  int64
+	eventsCancelled int64
 	blocksCreated   int64
 
+	// Submissions dropped because an event with the same ID was pending;
+	// duplicates of sealed events are counted by each channel's builder
+	duplicatesDropped int64
+
 	// Control
 	stopCh  chan struct{}
 	wg      sync.WaitGroup
@@ -284,15 +492,14 @@ type OrderingService struct {
 // NewOrderingService creates a new ordering service.
 func NewOrderingService(config OrderingConfig) *OrderingService {
 	s := &OrderingService{
-		config:       config,
-		status:       StatusMaintenance,
-		certifier:    NewEventCertifier(),
-		blockBuilder: NewBlockBuilder(config.BlockSize, config.BatchTimeout),
-		workerPool:   NewWorkerPool("ordering", config.Workers),
-		eventChan:    make(chan *PendingEvent, config.MaxPending),
-		blockChan:    make(chan []*PendingEvent, 100),
-		pending:      make(map[string]*PendingEvent),
-		stopCh:       make(chan struct{}),
+		config:     config,
+		status:     StatusMaintenance,
+		certifier:  NewEventCertifier(),
+		workerPool: NewWorkerPool("ordering", config.Workers),
+		eventChan:  make(chan *PendingEvent, config.MaxPending),
+		channels:   make(map[string]*orderingChannel),
+		pending:    make(map[string]*PendingEvent),
+		stopCh:     make(chan struct{}),
 	}
 
 	// Add default validation rules
@@ -303,8 +510,15 @@ func NewOrderingService(config OrderingConfig) *OrderingService {
 
 // addDefaultRules adds standard validation rules.
 func (s *OrderingService) addDefaultRules() {
-	// Timestamp validation
-	s.certifier.AddRule(func(data map[string]interface{}) error {
+	s.certifier.AddNamedRule(NamedRule{Name: TimestampRuleName, Fn: DefaultTimestampRule()})
+}
+
+// DefaultTimestampRule returns the rule the ordering service registers by
+// default: a timestamp, if present, must be a number of Unix seconds
+// within 24 hours of now. Callers replacing rules with SetRules can
+// include it to keep the check.
+func DefaultTimestampRule() ValidationRule {
+	return func(data map[string]interface{}) error {
 		ts, ok := data["timestamp"]
 		if !ok {
 			return nil // Will be caught by required field check
@@ -329,7 +543,52 @@ func (s *OrderingService) addDefaultRules() {
 		}
 
 		return nil
-	})
+	}
+}
+
+// Certifier returns the event certifier, e.g. to update its rules at runtime.
+func (s *OrderingService) Certifier() *EventCertifier {
+	return s.certifier
+}
+
+// channel returns the state of channel id, creating it on first use. An
+// empty id is the default channel.
+func (s *OrderingService) channel(id string) *orderingChannel {
+	if id == "" {
+		id = DefaultChannel
+	}
+
+	s.mu.RLock()
+	ch, ok := s.channels[id]
+	s.mu.RUnlock()
+	if ok {
+		return ch
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch, ok := s.channels[id]; ok {
+		return ch
+	}
+	ch = &orderingChannel{
+		id:      id,
+		builder: NewBlockBuilderWithDedup(s.config.BlockSize, s.config.BatchTimeout, s.config.DedupWindow),
+		blocks:  make(chan *Block, 100),
+	}
+	s.channels[id] = ch
+	return ch
+}
+
+// channelList returns the current channels.
+func (s *OrderingService) channelList() []*orderingChannel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channels := make([]*orderingChannel, 0, len(s.channels))
+	for _, ch := range s.channels {
+		channels = append(channels, ch)
+	}
+	return channels
 }
 
 // Start begins the ordering service.
@@ -354,6 +613,27 @@ func (s *OrderingService) Start() error {
 	return nil
 }
 
+// Pause stops the service accepting events, putting it in
+// StatusMaintenance: SubmitEvent returns ErrServicePaused. Events already
+// submitted are still certified and batched, and the timeout flusher keeps
+// sealing partial batches. It has no effect unless the service is active.
+func (s *OrderingService) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running && s.status == StatusActive {
+		s.status = StatusMaintenance
+	}
+}
+
+// Resume makes a paused service active again.
+func (s *OrderingService) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running && s.status == StatusMaintenance {
+		s.status = StatusActive
+	}
+}
+
 // Stop stops the ordering service.
 func (s *OrderingService) Stop() {
 	s.mu.Lock()
@@ -378,8 +658,10 @@ func (s *OrderingService) processEvents() {
 		select {
 		case <-s.stopCh:
 			// Flush remaining events
-			if batch := s.blockBuilder.ForceFlush(); batch != nil {
-				s.blockChan <- batch
+			for _, ch := range s.channelList() {
+				if batch := ch.builder.ForceFlush(); batch != nil {
+					s.seal(ch, batch)
+				}
 			}
 			return
 
@@ -389,7 +671,8 @@ func (s *OrderingService) processEvents() {
 	}
 }
 
-// checkTimeouts periodically flushes batches on timeout.
+// checkTimeouts periodically flushes each channel's batch once it is
+// older than the batch timeout.
 func (s *OrderingService) checkTimeouts() {
 	defer s.wg.Done()
 
@@ -401,11 +684,10 @@ func (s *OrderingService) checkTimeouts() {
 		case <-s.stopCh:
 			return
 		case <-ticker.C:
-			if batch := s.blockBuilder.ForceFlush(); batch != nil {
-				s.mu.Lock()
-				s.blocksCreated++
-				s.mu.Unlock()
-				s.blockChan <- batch
+			for _, ch := range s.channelList() {
+				if batch := ch.builder.FlushExpired(); batch != nil {
+					s.seal(ch, batch)
+				}
 			}
 		}
 	}
@@ -415,63 +697,304 @@ func (s *OrderingService) checkTimeouts() {
 func (s *OrderingService) handleEvent(event *PendingEvent) {
 	s.mu.Lock()
 	s.eventsReceived++
-	s.pending[event.ID] = event
+	if s.pending[event.ID] != event {
+		// Cancelled while queued
+		s.mu.Unlock()
+		return
+	}
+	event.Status = EventProcessing
 	s.mu.Unlock()
 
 	// Certify event
-	event.Status = EventProcessing
 	cert := s.certifier.Validate(event)
 
 	if !cert.Valid {
 		s.mu.Lock()
 		s.eventsRejected++
-		delete(s.pending, event.ID)
+		if s.pending[event.ID] == event {
+			delete(s.pending, event.ID)
+			event.Status = EventRejected
+		}
 		s.mu.Unlock()
-		event.Status = EventRejected
 		return
 	}
 
+	// Add to the channel's block builder, unless cancelled during
+	// certification. Holding mu keeps CancelEvent from missing the event
+	// between the check and the builder.
+	ch := s.channel(event.ChannelID)
 	s.mu.Lock()
-	s.eventsCertified++
+	if s.pending[event.ID] != event {
+		s.mu.Unlock()
+		return
+	}
+	batch, added := ch.builder.add(event)
+	if added {
+		s.eventsCertified++
+		ch.eventsCertified++
+		event.Status = EventCertified
+	} else {
+		// Recently sealed under the same ID
+		delete(s.pending, event.ID)
+		event.Status = EventRejected
+	}
 	s.mu.Unlock()
-	event.Status = EventCertified
 
-	// Add to block builder
-	if batch := s.blockBuilder.AddEvent(event); batch != nil {
-		s.mu.Lock()
-		s.blocksCreated++
-		for _, e := range batch {
-			delete(s.pending, e.ID)
-			e.Status = EventOrdered
+	if batch != nil {
+		s.seal(ch, batch)
+	}
+}
+
+// CancelEvent withdraws a submitted event that has not been sealed yet,
+// whether it is queued, being certified or waiting in a batch, and reports
+// whether it was found. It returns false if the event was already sealed,
+// rejected or never submitted.
+func (s *OrderingService) CancelEvent(eventID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.pending[eventID]
+	if !ok {
+		return false
+	}
+	delete(s.pending, eventID)
+	event.Status = EventCancelled
+	s.eventsCancelled++
+
+	channelID := event.ChannelID
+	if channelID == "" {
+		channelID = DefaultChannel
+	}
+	if ch, ok := s.channels[channelID]; ok {
+		ch.builder.Remove(eventID)
+	}
+	return true
+}
+
+// seal records batch as the next block of ch, notifies OnBlockSealed
+// callbacks, publishes it on the channel's Blocks channel and notifies
+// OnBlockFinalized callbacks.
+func (s *OrderingService) seal(ch *orderingChannel, batch []*PendingEvent) {
+	ch.sealMu.Lock()
+	defer ch.sealMu.Unlock()
+
+	s.mu.Lock()
+	// Drop events cancelled after the batch left the builder
+	kept := batch[:0:0]
+	for _, e := range batch {
+		if s.pending[e.ID] == e {
+			kept = append(kept, e)
 		}
+	}
+	if len(kept) == 0 {
 		s.mu.Unlock()
-		s.blockChan <- batch
+		return
+	}
+	batch = kept
+
+	s.blocksCreated++
+	ch.blocksCreated++
+	sealed := &Block{
+		ChannelID: ch.id,
+		Seq:       ch.blocksCreated,
+		Events:    batch,
+		SealedAt:  time.Now(),
+	}
+	block := FinalizedBlock{
+		ChannelID:  ch.id,
+		Sequence:   sealed.Seq,
+		EventCount: len(batch),
+		EventIDs:   make([]string, len(batch)),
+		Timestamp:  sealed.SealedAt,
+	}
+	for i, e := range batch {
+		delete(s.pending, e.ID)
+		e.Status = EventOrdered
+		block.EventIDs[i] = e.ID
+	}
+	onSealed := s.onSealed
+	callbacks := s.onFinalized
+	merkleRoot := s.merkleRoot
+	s.mu.Unlock()
+
+	if merkleRoot != nil {
+		root, err := merkleRoot(batch)
+		if err != nil {
+			log.Printf("Warning: failed to compute Merkle root for block %d of channel %s: %v", block.Sequence, ch.id, err)
+		}
+		block.MerkleRoot = root
+	}
+
+	for _, fn := range onSealed {
+		fn(sealed)
+	}
+
+	ch.blocks <- sealed
+
+	for _, fn := range callbacks {
+		fn(block)
 	}
 }
 
+// OnBlockSealed registers fn to be called synchronously for each block the
+// service seals, before the block is published on its Blocks channel, e.g.
+// to chain block hashes. Each channel's blocks are passed one at a time in
+// Seq order. fn must not modify the block, and must not block, since it
+// delays ordering of later events.
+func (s *OrderingService) OnBlockSealed(fn func(block *Block)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Copy on write so seal can use its snapshot without the lock
+	callbacks := make([]func(*Block), len(s.onSealed), len(s.onSealed)+1)
+	copy(callbacks, s.onSealed)
+	s.onSealed = append(callbacks, fn)
+}
+
+// OnBlockFinalized registers fn to be called once for each block the
+// service seals, after the block is published on its Blocks channel.
+// Each channel's blocks are reported one at a time in sequence order;
+// blocks of different channels may be reported concurrently. Callbacks
+// must not block, since they delay ordering of later events.
+func (s *OrderingService) OnBlockFinalized(fn func(block FinalizedBlock)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Copy on write so seal can use its snapshot without the lock
+	callbacks := make([]func(FinalizedBlock), len(s.onFinalized), len(s.onFinalized)+1)
+	copy(callbacks, s.onFinalized)
+	s.onFinalized = append(callbacks, fn)
+}
+
+// SetMerkleRootFunc sets the function used to fill FinalizedBlock.MerkleRoot.
+// Passing nil leaves the root empty.
+func (s *OrderingService) SetMerkleRootFunc(fn MerkleRootFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merkleRoot = fn
+}
+
 // SubmitEvent submits an event for ordering.
 func (s *OrderingService) SubmitEvent(event *PendingEvent) error {
-	s.mu.RLock()
+	s.mu.Lock()
 	if !s.running {
-		s.mu.RUnlock()
+		s.mu.Unlock()
 		return errors.New("service not running")
 	}
-	s.mu.RUnlock()
-
+	if s.status == StatusMaintenance {
+		s.mu.Unlock()
+		return ErrServicePaused
+	}
+	if _, ok := s.pending[event.ID]; ok {
+		// Already queued or batched; dropped like a duplicate in the batch
+		s.duplicatesDropped++
+		s.mu.Unlock()
+		return nil
+	}
 	event.Status = EventPending
 	event.ReceivedAt = time.Now()
+	s.pending[event.ID] = event
+	s.mu.Unlock()
 
 	select {
 	case s.eventChan <- event:
 		return nil
 	default:
+		s.mu.Lock()
+		if s.pending[event.ID] == event {
+			delete(s.pending, event.ID)
+		}
+		s.mu.Unlock()
 		return errors.New("event queue full")
 	}
 }
 
-// Blocks returns the channel for receiving completed blocks.
-func (s *OrderingService) Blocks() <-chan []*PendingEvent {
-	return s.blockChan
+// Blocks returns the channel for receiving the completed blocks of
+// channelID; an empty ID is the default channel. Each channel's blocks
+// must be consumed, or sealing on that channel stalls.
+func (s *OrderingService) Blocks(channelID string) <-chan *Block {
+	return s.channel(channelID).blocks
+}
+
+// ListPending returns copies of up to limit events that are submitted but
+// not yet sealed, oldest first; zero or less returns all of them. Changing
+// the copies does not affect the service.
+func (s *OrderingService) ListPending(limit int) []*PendingEvent {
+	s.mu.RLock()
+	events := make([]*PendingEvent, 0, len(s.pending))
+	for _, e := range s.pending {
+		events = append(events, s.snapshotEvent(e))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ReceivedAt.Before(events[j].ReceivedAt)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+// GetEvent returns a copy of the pending event with id, or false if no such
+// event is waiting to be sealed.
+func (s *OrderingService) GetEvent(id string) (*PendingEvent, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.pending[id]
+	if !ok {
+		return nil, false
+	}
+	return s.snapshotEvent(e), true
+}
+
+// snapshotEvent copies e for callers outside the service (called with mu
+// held). e.Cert is written by the certifier under its own lock, so it is
+// never read here; the certification comes from the certifier instead.
+func (s *OrderingService) snapshotEvent(e *PendingEvent) *PendingEvent {
+	snapshot := &PendingEvent{
+		ID:         e.ID,
+		Data:       copyValue(e.Data).(map[string]interface{}),
+		ChannelID:  e.ChannelID,
+		Submitter:  e.Submitter,
+		ReceivedAt: e.ReceivedAt,
+		Status:     e.Status,
+	}
+	if e.Status != EventPending && e.Status != EventProcessing {
+		if cert := s.certifier.GetCertification(e.ID); cert != nil {
+			c := *cert
+			c.Errors = append([]string(nil), cert.Errors...)
+			c.FailedRules = append([]string(nil), cert.FailedRules...)
+			c.Metadata = copyValue(cert.Metadata).(map[string]interface{})
+			snapshot.Cert = &c
+		}
+	}
+	return snapshot
+}
+
+// copyValue deep-copies the maps and slices of a decoded JSON-like value.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = copyValue(val)
+		}
+		return m
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		l := make([]interface{}, len(v))
+		for i, val := range v {
+			l[i] = copyValue(val)
+		}
+		return l
+	default:
+		return v
+	}
 }
 
 // GetStatus returns current service status.
@@ -487,9 +1010,22 @@ type OrderingStats struct {
 	EventsReceived  int64  `json:"events_received"`
 	EventsCertified int64  `json:"events_certified"`
 	EventsRejected  int64  `json:"events_rejected"`
+	EventsCancelled int64  `json:"events_cancelled"`
 	BlocksCreated   int64  `json:"blocks_created"`
 	PendingCount    int    `json:"pending_count"`
-	BatchSize       int    `json:"current_batch_size"`
+	BatchSize       int    `json:"current_batch_size"` // summed over channels
+
+	DuplicatesDropped int64 `json:"duplicates_dropped"`
+
+	Channels map[string]ChannelStats `json:"channels"`
+}
+
+// ChannelStats contains the statistics of one ordering channel.
+type ChannelStats struct {
+	EventsCertified   int64 `json:"events_certified"`
+	BlocksCreated     int64 `json:"blocks_created"`
+	BatchSize         int   `json:"current_batch_size"`
+	DuplicatesDropped int64 `json:"duplicates_dropped"`
 }
 
 // GetStats returns service statistics.
@@ -497,13 +1033,29 @@ func (s *OrderingService) GetStats() OrderingStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return OrderingStats{
+	stats := OrderingStats{
 		Status:          s.status.String(),
 		EventsReceived:  s.eventsReceived,
 		EventsCertified: s.eventsCertified,
 		EventsRejected:  s.eventsRejected,
+		EventsCancelled: s.eventsCancelled,
 		BlocksCreated:   s.blocksCreated,
 		PendingCount:    len(s.pending),
-		BatchSize:       s.blockBuilder.BatchSize(),
+		Channels:        make(map[string]ChannelStats, len(s.channels)),
+
+		DuplicatesDropped: s.duplicatesDropped,
+	}
+	for id, ch := range s.channels {
+		batchSize := ch.builder.BatchSize()
+		duplicates := ch.builder.DuplicatesDropped()
+		stats.BatchSize += batchSize
+		stats.DuplicatesDropped += duplicates
+		stats.Channels[id] = ChannelStats{
+			EventsCertified:   ch.eventsCertified,
+			BlocksCreated:     ch.blocksCreated,
+			BatchSize:         batchSize,
+			DuplicatesDropped: duplicates,
+		}
 	}
+	return stats
 }
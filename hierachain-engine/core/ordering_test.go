@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -51,6 +52,126 @@ func TestEventCertifierValidateMissingFields(t *testing.T) {
 	}
 }
 
+func TestEventCertifierSetRules(t *testing.T) {
+	c := NewEventCertifier()
+	c.AddRule(func(data map[string]interface{}) error {
+		return errors.New("old rule")
+	})
+
+	event := func(id string, timestamp float64) *PendingEvent {
+		return &PendingEvent{
+			ID: id,
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": timestamp,
+			},
+		}
+	}
+	now := float64(time.Now().Unix())
+
+	if cert := c.Validate(event("before", now)); cert.Valid {
+		t.Error("Expected the old rule to reject the event")
+	}
+
+	c.SetRules([]ValidationRule{DefaultTimestampRule()})
+	if cert := c.Validate(event("current", now)); !cert.Valid {
+		t.Errorf("Expected valid with the new rules, got errors: %v", cert.Errors)
+	}
+	if cert := c.Validate(event("stale", now-2*86400)); cert.Valid {
+		t.Error("Expected the timestamp rule to reject a stale event")
+	}
+
+	c.ClearRules()
+	if cert := c.Validate(event("cleared", now-2*86400)); !cert.Valid {
+		t.Errorf("Expected valid with no rules, got errors: %v", cert.Errors)
+	}
+
+	// History survives rule changes
+	if c.GetCertification("before") == nil {
+		t.Error("Expected certification history to be kept")
+	}
+}
+
+func TestEventCertifierNamedRules(t *testing.T) {
+	c := NewEventCertifier()
+	c.AddRule(func(data map[string]interface{}) error {
+		return errors.New("unnamed failure")
+	})
+	c.AddNamedRule(NamedRule{Name: "amount_positive", Fn: func(data map[string]interface{}) error {
+		if amount, _ := data["amount"].(float64); amount <= 0 {
+			return errors.New("amount must be positive")
+		}
+		return nil
+	}})
+	c.AddNamedRule(NamedRule{Name: "always_ok", Fn: func(data map[string]interface{}) error {
+		return nil
+	}})
+
+	cert := c.Validate(&PendingEvent{ID: "event-1", Data: map[string]interface{}{"amount": float64(-1)}})
+	expected := []string{RequiredFieldsRuleName, "rule-1", "amount_positive"}
+	if fmt.Sprint(cert.FailedRules) != fmt.Sprint(expected) {
+		t.Errorf("Expected failed rules %v, got %v", expected, cert.FailedRules)
+	}
+	if len(cert.Errors) != 5 {
+		t.Errorf("Expected 5 errors, got %d: %v", len(cert.Errors), cert.Errors)
+	}
+
+	valid := c.Validate(&PendingEvent{ID: "event-2", Data: map[string]interface{}{
+		"entity_id": "entity-1",
+		"event":     "created",
+		"timestamp": float64(time.Now().Unix()),
+		"amount":    float64(5),
+	}})
+	if fmt.Sprint(valid.FailedRules) != "[rule-1]" {
+		t.Errorf("Expected only rule-1 to fail, got %v", valid.FailedRules)
+	}
+}
+
+func TestOrderingServiceTimestampRuleName(t *testing.T) {
+	svc := NewOrderingService(DefaultOrderingConfig())
+	cert := svc.Certifier().Validate(&PendingEvent{ID: "stale", Data: map[string]interface{}{
+		"entity_id": "entity-1",
+		"event":     "created",
+		"timestamp": float64(time.Now().Unix() - 2*86400),
+	}})
+	if fmt.Sprint(cert.FailedRules) != "["+TimestampRuleName+"]" {
+		t.Errorf("Expected the timestamp rule to fail, got %v", cert.FailedRules)
+	}
+}
+
+func TestEventCertifierSetRulesConcurrent(t *testing.T) {
+	c := NewEventCertifier()
+	failing := func(msg string) ValidationRule {
+		return func(data map[string]interface{}) error { return errors.New(msg) }
+	}
+	oldRules := []ValidationRule{failing("old"), failing("old")}
+	newRules := []ValidationRule{failing("new"), failing("new")}
+	c.SetRules(oldRules)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if i%2 == 0 {
+				c.SetRules(newRules)
+			} else {
+				c.SetRules(oldRules)
+			}
+		}
+	}()
+
+	data := map[string]interface{}{"entity_id": "e", "event": "created", "timestamp": float64(time.Now().Unix())}
+	for i := 0; i < 200; i++ {
+		cert := c.Validate(&PendingEvent{ID: fmt.Sprintf("event-%d", i), Data: data})
+		if len(cert.Errors) != 2 || cert.Errors[0] != cert.Errors[1] {
+			t.Fatalf("Expected errors from one rule set, got %v", cert.Errors)
+		}
+	}
+	wg.Wait()
+}
+
 func TestBlockBuilder(t *testing.T) {
 	bb := NewBlockBuilder(3, time.Second)
 
@@ -129,9 +250,9 @@ func TestOrderingService(t *testing.T) {
 
 	// Wait for block
 	select {
-	case block := <-svc.Blocks():
-		if len(block) != 5 {
-			t.Errorf("Expected block of 5, got %d", len(block))
+	case block := <-svc.Blocks(""):
+		if len(block.Events) != 5 {
+			t.Errorf("Expected block of 5, got %d", len(block.Events))
 		}
 	case <-time.After(time.Second):
 		t.Fatal("Timeout waiting for block")
@@ -203,8 +324,8 @@ func TestOrderingServiceConcurrent(t *testing.T) {
 loop:
 	for {
 		select {
-		case block := <-svc.Blocks():
-			totalEvents += len(block)
+		case block := <-svc.Blocks(""):
+			totalEvents += len(block.Events)
 			if totalEvents >= numEvents {
 				break loop
 			}
@@ -218,6 +339,630 @@ loop:
 	}
 }
 
+func TestOrderingServiceChannels(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    3,
+		BatchTimeout: 100 * time.Millisecond,
+		Workers:      1,
+		MaxPending:   100,
+	}
+
+	svc := NewOrderingService(config)
+	finalized := make(chan FinalizedBlock, 10)
+	svc.OnBlockFinalized(func(block FinalizedBlock) {
+		finalized <- block
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	submit := func(id, channel string) {
+		event := &PendingEvent{
+			ID:        id,
+			ChannelID: channel,
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	// A full block on "a", a partial one on "b" and the default channel
+	for i := 0; i < 3; i++ {
+		submit(fmt.Sprintf("a-%d", i), "a")
+	}
+	submit("b-0", "b")
+	submit("default-0", "")
+
+	receive := func(channel string) []*PendingEvent {
+		select {
+		case block := <-svc.Blocks(channel):
+			return block.Events
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for a block on channel %q", channel)
+			return nil
+		}
+	}
+	if block := receive("a"); len(block) != 3 || block[0].ID != "a-0" {
+		t.Errorf("Expected the 3 events of channel a, got %d", len(block))
+	}
+	if block := receive("b"); len(block) != 1 || block[0].ID != "b-0" {
+		t.Errorf("Expected the event of channel b, got %d", len(block))
+	}
+	if block := receive(""); len(block) != 1 || block[0].ID != "default-0" {
+		t.Errorf("Expected the event of the default channel, got %d", len(block))
+	}
+
+	// Each channel numbers its own blocks
+	for i := 0; i < 3; i++ {
+		select {
+		case block := <-finalized:
+			if block.Sequence != 1 {
+				t.Errorf("Expected sequence 1 on channel %s, got %d", block.ChannelID, block.Sequence)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for finalized blocks")
+		}
+	}
+
+	stats := svc.GetStats()
+	if stats.BlocksCreated != 3 || len(stats.Channels) != 3 {
+		t.Fatalf("Expected 3 blocks over 3 channels, got %d over %d", stats.BlocksCreated, len(stats.Channels))
+	}
+	if got := stats.Channels["a"]; got.EventsCertified != 3 || got.BlocksCreated != 1 {
+		t.Errorf("Expected 3 events in 1 block on channel a, got %+v", got)
+	}
+	if got := stats.Channels[DefaultChannel]; got.EventsCertified != 1 || got.BlocksCreated != 1 {
+		t.Errorf("Expected 1 event in 1 block on the default channel, got %+v", got)
+	}
+}
+
+func TestOrderingServiceOnBlockSealed(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    2,
+		BatchTimeout: time.Second,
+		Workers:      1,
+		MaxPending:   100,
+	}
+
+	svc := NewOrderingService(config)
+	type sealing struct {
+		seq      int64
+		queued   int
+		prevHash string
+	}
+	var mu sync.Mutex
+	var sealings []sealing
+	prevHash := "genesis"
+	svc.OnBlockSealed(func(block *Block) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Called before publishing, so only earlier blocks are queued
+		sealings = append(sealings, sealing{block.Seq, len(svc.Blocks("")), prevHash})
+		prevHash = fmt.Sprintf("%s/%d:%s", prevHash, block.Seq, block.Events[0].ID)
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	for i := 0; i < 6; i++ {
+		event := &PendingEvent{
+			ID: fmt.Sprintf("event-%d", i),
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case block := <-svc.Blocks(""):
+			if block.Seq != int64(i) || block.ChannelID != DefaultChannel || block.SealedAt.IsZero() {
+				t.Errorf("Expected block %d of the default channel, got %d of %q", i, block.Seq, block.ChannelID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for block %d", i)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sealings) != 3 {
+		t.Fatalf("Expected 3 sealed callbacks, got %d", len(sealings))
+	}
+	for i, got := range sealings {
+		if got.seq != int64(i+1) {
+			t.Errorf("Callback %d: expected seq %d, got %d", i, i+1, got.seq)
+		}
+		if got.queued > i {
+			t.Errorf("Callback %d: expected the block not yet published, %d queued", i, got.queued)
+		}
+	}
+	if sealings[2].prevHash != "genesis/1:event-0/2:event-2" {
+		t.Errorf("Expected a deterministic hash chain, got %s", sealings[2].prevHash)
+	}
+}
+
+func TestOrderingServiceCancelEvent(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    3,
+		BatchTimeout: time.Minute,
+		Workers:      1,
+		MaxPending:   100,
+	}
+
+	svc := NewOrderingService(config)
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	events := make([]*PendingEvent, 5)
+	for i := range events {
+		events[i] = &PendingEvent{
+			ID: fmt.Sprintf("event-%d", i),
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+	}
+	for _, event := range events[:2] {
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for svc.GetStats().BatchSize < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// event-1 is waiting in the batch; it is removed and not sealed
+	if !svc.CancelEvent("event-1") {
+		t.Error("Expected CancelEvent to find the batched event")
+	}
+	if svc.CancelEvent("event-1") || svc.CancelEvent("unknown") {
+		t.Error("Expected CancelEvent to report cancelled and unknown events as not found")
+	}
+	if stats := svc.GetStats(); stats.BatchSize != 1 {
+		t.Errorf("Expected 1 event left in the batch, got %d", stats.BatchSize)
+	}
+
+	for _, event := range events[2:] {
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	select {
+	case block := <-svc.Blocks(""):
+		ids := make([]string, len(block.Events))
+		for i, e := range block.Events {
+			ids[i] = e.ID
+		}
+		if fmt.Sprint(ids) != "[event-0 event-2 event-3]" {
+			t.Errorf("Expected the cancelled event left out, got %v", ids)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for block")
+	}
+
+	if svc.CancelEvent("event-0") {
+		t.Error("Expected CancelEvent to report a sealed event as not found")
+	}
+	if !svc.CancelEvent("event-4") {
+		t.Error("Expected CancelEvent to find the pending event")
+	}
+	stats := svc.GetStats()
+	if stats.EventsCancelled != 2 || stats.PendingCount != 0 {
+		t.Errorf("Expected 2 cancelled and nothing pending, got %d and %d", stats.EventsCancelled, stats.PendingCount)
+	}
+	if events[1].Status != EventCancelled {
+		t.Errorf("Expected status cancelled, got %s", events[1].Status)
+	}
+}
+
+func TestOrderingServiceCancelEventRace(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    5,
+		BatchTimeout: 20 * time.Millisecond,
+		Workers:      1,
+		MaxPending:   1000,
+	}
+
+	svc := NewOrderingService(config)
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	var mu sync.Mutex
+	sealed := make(map[string]bool)
+	go func() {
+		for block := range svc.Blocks("") {
+			mu.Lock()
+			for _, e := range block.Events {
+				sealed[e.ID] = true
+			}
+			mu.Unlock()
+		}
+	}()
+
+	const numEvents = 500
+	var cancelled sync.Map
+	var wg sync.WaitGroup
+	for i := 0; i < numEvents; i++ {
+		id := fmt.Sprintf("event-%d", i)
+		event := &PendingEvent{
+			ID: id,
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		if i%3 == 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if svc.CancelEvent(id) {
+					cancelled.Store(id, true)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	// Every event ends up sealed or cancelled, never both
+	deadline := time.Now().Add(2 * time.Second)
+	for svc.GetStats().PendingCount > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var numCancelled int
+	cancelled.Range(func(key, value interface{}) bool {
+		numCancelled++
+		if sealed[key.(string)] {
+			t.Errorf("Event %s was both cancelled and sealed", key)
+		}
+		return true
+	})
+	if len(sealed)+numCancelled != numEvents {
+		t.Errorf("Expected %d events sealed or cancelled, got %d sealed and %d cancelled", numEvents, len(sealed), numCancelled)
+	}
+	if stats := svc.GetStats(); stats.EventsCancelled != int64(numCancelled) {
+		t.Errorf("Expected %d cancelled in stats, got %d", numCancelled, stats.EventsCancelled)
+	}
+}
+
+func TestBlockBuilderDedupWindow(t *testing.T) {
+	bb := NewBlockBuilderWithDedup(2, time.Minute, 3)
+	add := func(id string) []*PendingEvent {
+		return bb.AddEvent(&PendingEvent{ID: id})
+	}
+
+	add("a")
+	if batch := add("b"); len(batch) != 2 {
+		t.Fatalf("Expected a block of 2, got %d", len(batch))
+	}
+
+	// A sealed ID is rejected in the next batch
+	if batch := add("a"); batch != nil || bb.BatchSize() != 0 {
+		t.Error("Expected the recently sealed event to be dropped")
+	}
+	add("c")
+	add("c")
+	if got := bb.DuplicatesDropped(); got != 2 {
+		t.Errorf("Expected 2 duplicates dropped, got %d", got)
+	}
+
+	// Sealing c and d pushes a out of the window of 3
+	add("d")
+	add("a")
+	if bb.BatchSize() != 1 {
+		t.Errorf("Expected a to be accepted once outside the window, got batch size %d", bb.BatchSize())
+	}
+
+	// Without a window only the current batch is checked
+	plain := NewBlockBuilder(1, time.Minute)
+	plain.AddEvent(&PendingEvent{ID: "a"})
+	if batch := plain.AddEvent(&PendingEvent{ID: "a"}); len(batch) != 1 {
+		t.Error("Expected a builder without a window to accept a sealed ID again")
+	}
+}
+
+func TestOrderingServiceDropsDuplicates(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    3,
+		BatchTimeout: time.Minute,
+		Workers:      1,
+		MaxPending:   100,
+		DedupWindow:  100,
+	}
+
+	svc := NewOrderingService(config)
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	submit := func(id string) {
+		event := &PendingEvent{
+			ID: id,
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	// A retry while pending, then one after sealing
+	submit("event-0")
+	submit("event-0")
+	submit("event-1")
+	submit("event-2")
+	select {
+	case block := <-svc.Blocks(""):
+		if len(block.Events) != 3 {
+			t.Errorf("Expected block of 3, got %d", len(block.Events))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for block")
+	}
+	submit("event-1")
+
+	deadline := time.Now().Add(time.Second)
+	for svc.GetStats().DuplicatesDropped < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := svc.GetStats()
+	if stats.DuplicatesDropped != 2 {
+		t.Errorf("Expected 2 duplicates dropped, got %d", stats.DuplicatesDropped)
+	}
+	if stats.PendingCount != 0 || stats.BatchSize != 0 {
+		t.Errorf("Expected nothing pending, got %d pending and batch of %d", stats.PendingCount, stats.BatchSize)
+	}
+	if got := stats.Channels[DefaultChannel].DuplicatesDropped; got != 1 {
+		t.Errorf("Expected 1 duplicate dropped by the channel, got %d", got)
+	}
+}
+
+func TestOrderingServicePauseResume(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    10,
+		BatchTimeout: 50 * time.Millisecond,
+		Workers:      1,
+		MaxPending:   100,
+	}
+
+	svc := NewOrderingService(config)
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	newEvent := func(id string) *PendingEvent {
+		return &PendingEvent{
+			ID: id,
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+	}
+
+	if err := svc.SubmitEvent(newEvent("before")); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	svc.Pause()
+	if status := svc.GetStatus(); status != StatusMaintenance {
+		t.Errorf("Expected maintenance status, got %s", status)
+	}
+	if err := svc.SubmitEvent(newEvent("paused")); !errors.Is(err, ErrServicePaused) {
+		t.Errorf("Expected ErrServicePaused, got %v", err)
+	}
+
+	// The partial batch is still sealed by the timeout while paused
+	select {
+	case block := <-svc.Blocks(""):
+		if len(block.Events) != 1 || block.Events[0].ID != "before" {
+			t.Errorf("Expected the in-flight event sealed, got %d events", len(block.Events))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the partial batch while paused")
+	}
+
+	svc.Resume()
+	if status := svc.GetStatus(); status != StatusActive {
+		t.Errorf("Expected active status, got %s", status)
+	}
+	if err := svc.SubmitEvent(newEvent("after")); err != nil {
+		t.Errorf("Expected events accepted after resuming, got %v", err)
+	}
+	select {
+	case block := <-svc.Blocks(""):
+		if len(block.Events) != 1 || block.Events[0].ID != "after" {
+			t.Errorf("Expected the event submitted after resuming, got %d events", len(block.Events))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for block after resuming")
+	}
+}
+
+func TestOrderingServiceListPending(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    10,
+		BatchTimeout: time.Minute,
+		Workers:      1,
+		MaxPending:   100,
+	}
+
+	svc := NewOrderingService(config)
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	for i := 0; i < 4; i++ {
+		event := &PendingEvent{
+			ID: fmt.Sprintf("event-%d", i),
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+				"details":   map[string]interface{}{"tags": []interface{}{"a"}},
+			},
+		}
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	all := svc.ListPending(0)
+	if len(all) != 4 {
+		t.Fatalf("Expected 4 pending events, got %d", len(all))
+	}
+	for i, e := range all {
+		if e.ID != fmt.Sprintf("event-%d", i) {
+			t.Errorf("Expected oldest first, got %s at %d", e.ID, i)
+		}
+	}
+	if limited := svc.ListPending(2); len(limited) != 2 || limited[1].ID != "event-1" {
+		t.Errorf("Expected the 2 oldest events, got %d", len(limited))
+	}
+
+	// Mutating a copy leaves the service's event alone
+	all[0].Data["entity_id"] = "changed"
+	all[0].Data["details"].(map[string]interface{})["tags"].([]interface{})[0] = "changed"
+	e, ok := svc.GetEvent("event-0")
+	if !ok {
+		t.Fatal("Expected GetEvent to find event-0")
+	}
+	if e.Data["entity_id"] != "entity-1" {
+		t.Errorf("Expected the service's data unchanged, got %v", e.Data["entity_id"])
+	}
+	if tag := e.Data["details"].(map[string]interface{})["tags"].([]interface{})[0]; tag != "a" {
+		t.Errorf("Expected nested data unchanged, got %v", tag)
+	}
+
+	if _, ok := svc.GetEvent("unknown"); ok {
+		t.Error("Expected GetEvent to report an unknown event as not found")
+	}
+}
+
+// Run with -race: snapshots are read while workers certify events.
+func TestOrderingServiceListPendingDuringCertification(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    1000,
+		BatchTimeout: time.Minute,
+		Workers:      4,
+		MaxPending:   1000,
+	}
+
+	svc := NewOrderingService(config)
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	const events = 200
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 2; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, e := range svc.ListPending(0) {
+					if e.Cert != nil && e.Cert.EventID != e.ID {
+						t.Errorf("Expected certification for %s, got %s", e.ID, e.Cert.EventID)
+					}
+				}
+				svc.GetEvent(fmt.Sprintf("event-%d", events/2))
+			}
+		}()
+	}
+
+	for i := 0; i < events; i++ {
+		event := &PendingEvent{
+			ID: fmt.Sprintf("event-%d", i),
+			Data: map[string]interface{}{
+				"entity_id": "entity-1",
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		certified := 0
+		for _, e := range svc.ListPending(0) {
+			if e.Cert != nil {
+				certified++
+			}
+		}
+		if certified == events {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d certified events, got %d", events, certified)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(done)
+	readers.Wait()
+}
+
+func TestBlockBuilderFlushExpired(t *testing.T) {
+	bb := NewBlockBuilder(10, 50*time.Millisecond)
+	if batch := bb.FlushExpired(); batch != nil {
+		t.Error("Expected nothing to flush from an empty builder")
+	}
+
+	bb.AddEvent(&PendingEvent{ID: "event-1"})
+	if batch := bb.FlushExpired(); batch != nil {
+		t.Error("Expected a fresh batch not to flush")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if batch := bb.FlushExpired(); len(batch) != 1 {
+		t.Errorf("Expected the expired batch to flush, got %d events", len(batch))
+	}
+}
+
 func BenchmarkOrderingServiceSubmit(b *testing.B) {
 	config := OrderingConfig{
 		BlockSize:    1000,
@@ -232,7 +977,7 @@ func BenchmarkOrderingServiceSubmit(b *testing.B) {
 
 	// Consumer
 	go func() {
-		for range svc.Blocks() {
+		for range svc.Blocks("") {
 		}
 	}()
 
@@ -250,3 +995,79 @@ func BenchmarkOrderingServiceSubmit(b *testing.B) {
 		_ = svc.SubmitEvent(event)
 	}
 }
+
+func TestOrderingServiceOnBlockFinalized(t *testing.T) {
+	config := OrderingConfig{
+		BlockSize:    3,
+		BatchTimeout: 100 * time.Millisecond,
+		Workers:      1,
+		MaxPending:   100,
+	}
+
+	svc := NewOrderingService(config)
+	svc.SetMerkleRootFunc(func(events []*PendingEvent) (string, error) {
+		return "root-" + events[0].ID, nil
+	})
+
+	finalized := make(chan FinalizedBlock, 10)
+	svc.OnBlockFinalized(func(block FinalizedBlock) {
+		finalized <- block
+	})
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// Two full blocks, then a partial one sealed by the timeout
+	for i := 0; i < 7; i++ {
+		event := &PendingEvent{
+			ID: fmt.Sprintf("event-%d", i),
+			Data: map[string]interface{}{
+				"entity_id": fmt.Sprintf("entity-%d", i),
+				"event":     "created",
+				"timestamp": float64(time.Now().Unix()),
+			},
+		}
+		if err := svc.SubmitEvent(event); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	expected := []struct {
+		count int
+		root  string
+	}{{3, "root-event-0"}, {3, "root-event-3"}, {1, "root-event-6"}}
+
+	for i, want := range expected {
+		select {
+		case block := <-finalized:
+			if block.Sequence != int64(i+1) {
+				t.Errorf("Block %d: expected sequence %d, got %d", i, i+1, block.Sequence)
+			}
+			if block.EventCount != want.count || len(block.EventIDs) != want.count {
+				t.Errorf("Block %d: expected %d events, got %d", i, want.count, block.EventCount)
+			}
+			if block.MerkleRoot != want.root {
+				t.Errorf("Block %d: expected root %s, got %s", i, want.root, block.MerkleRoot)
+			}
+			if block.Timestamp.IsZero() {
+				t.Errorf("Block %d: expected a timestamp", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for block %d", i)
+		}
+		<-svc.Blocks("")
+	}
+
+	svc.Stop()
+
+	// Nothing was left to flush on stop, so no further notification
+	select {
+	case block := <-finalized:
+		t.Errorf("Unexpected extra block %+v", block)
+	default:
+	}
+	if stats := svc.GetStats(); stats.BlocksCreated != 3 || stats.PendingCount != 0 {
+		t.Errorf("Expected 3 blocks and nothing pending, got %d and %d", stats.BlocksCreated, stats.PendingCount)
+	}
+}
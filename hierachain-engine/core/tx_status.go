@@ -0,0 +1,81 @@
+package core
+
+import "sync"
+
+// TxStatus is the lifecycle state of a transaction as reported to clients.
+type TxStatus string
+
+const (
+	// TxStatusUnknown means the transaction is neither pending nor recently confirmed.
+	TxStatusUnknown TxStatus = "UNKNOWN"
+	// TxStatusPending means the transaction is still in the mempool.
+	TxStatusPending TxStatus = "PENDING"
+	// TxStatusConfirmed means the transaction was processed and left the mempool.
+	TxStatusConfirmed TxStatus = "CONFIRMED"
+)
+
+// DefaultTxStatusCapacity is the number of recent confirmations a
+// TxStatusTracker remembers when no capacity is given.
+const DefaultTxStatusCapacity = 10000
+
+// TxStatusTracker answers status queries for transaction IDs. Pending
+// transactions are looked up in the mempool; processed ones are removed from
+// the mempool, so the tracker remembers the most recent confirmations in a
+// fixed-size ring and forgets the oldest once it is full.
+type TxStatusTracker struct {
+	mempool *Mempool
+
+	mu        sync.Mutex
+	confirmed map[string]struct{}
+	ring      []string // confirmed IDs in insertion order, overwritten oldest first
+	next      int
+}
+
+// NewTxStatusTracker creates a tracker over mempool that remembers up to
+// capacity confirmed transactions. A capacity of zero or less uses
+// DefaultTxStatusCapacity.
+func NewTxStatusTracker(mempool *Mempool, capacity int) *TxStatusTracker {
+	if capacity <= 0 {
+		capacity = DefaultTxStatusCapacity
+	}
+	return &TxStatusTracker{
+		mempool:   mempool,
+		confirmed: make(map[string]struct{}, capacity),
+		ring:      make([]string, capacity),
+	}
+}
+
+// MarkConfirmed records that the given transactions were processed. Call it
+// once they have been removed from the mempool, e.g. after PopBatch.
+func (t *TxStatusTracker) MarkConfirmed(txIDs ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range txIDs {
+		if _, ok := t.confirmed[id]; ok {
+			continue
+		}
+		if old := t.ring[t.next]; old != "" {
+			delete(t.confirmed, old)
+		}
+		t.ring[t.next] = id
+		t.confirmed[id] = struct{}{}
+		t.next = (t.next + 1) % len(t.ring)
+	}
+}
+
+// Status returns TxStatusPending if txID is in the mempool,
+// TxStatusConfirmed if it was recently marked confirmed, and
+// TxStatusUnknown otherwise.
+func (t *TxStatusTracker) Status(txID string) TxStatus {
+	if t.mempool != nil && t.mempool.Contains(txID) {
+		return TxStatusPending
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.confirmed[txID]; ok {
+		return TxStatusConfirmed
+	}
+	return TxStatusUnknown
+}
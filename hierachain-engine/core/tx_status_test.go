@@ -0,0 +1,54 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTxStatusTracker(t *testing.T) {
+	m := NewMempool(10)
+	tracker := NewTxStatusTracker(m, 2)
+
+	if err := m.Add(&Transaction{ID: "tx-1", EntityID: "entity-1", EventType: "created"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if got := tracker.Status("tx-1"); got != TxStatusPending {
+		t.Errorf("Expected %s for tx in mempool, got %s", TxStatusPending, got)
+	}
+	if got := tracker.Status("tx-missing"); got != TxStatusUnknown {
+		t.Errorf("Expected %s for unseen tx, got %s", TxStatusUnknown, got)
+	}
+
+	// Processing removes the tx from the mempool
+	for _, tx := range m.PopBatch(1) {
+		tracker.MarkConfirmed(tx.ID)
+	}
+	if got := tracker.Status("tx-1"); got != TxStatusConfirmed {
+		t.Errorf("Expected %s after processing, got %s", TxStatusConfirmed, got)
+	}
+}
+
+func TestTxStatusTrackerForgetsOldest(t *testing.T) {
+	tracker := NewTxStatusTracker(nil, 3)
+
+	for i := 0; i < 5; i++ {
+		tracker.MarkConfirmed(fmt.Sprintf("tx-%d", i))
+	}
+	// Re-marking a remembered ID does not consume a slot
+	tracker.MarkConfirmed("tx-4")
+
+	for i := 0; i < 5; i++ {
+		want := TxStatusConfirmed
+		if i < 2 {
+			want = TxStatusUnknown
+		}
+		if got := tracker.Status(fmt.Sprintf("tx-%d", i)); got != want {
+			t.Errorf("tx-%d: expected %s, got %s", i, want, got)
+		}
+	}
+
+	if n := len(tracker.confirmed); n != 3 {
+		t.Errorf("Expected 3 remembered confirmations, got %d", n)
+	}
+}
@@ -1,13 +1,29 @@
 package core
 
 import (
+	"container/heap"
 	"context"
 	"errors"
+	"hash/fnv"
+	"log"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// Common errors for worker pool submissions
+var (
+	ErrPoolShutdown = errors.New("worker pool is shut down")
+	ErrDraining     = errors.New("worker pool is draining")
+	ErrQueueFull    = errors.New("task queue is full")
+	ErrWorkerCount  = errors.New("worker count must be at least one")
+	ErrDrainTimeout = errors.New("worker pool drain timed out")
+)
+
 // Task represents a processing task for the worker pool.
 type Task struct {
 	ID          string
@@ -16,6 +32,17 @@ type Task struct {
 	Priority    int
 	CreatedAt   time.Time
 	Ctx         context.Context
+
+	// MaxRetries is how many more times ProcessFunc runs after it fails;
+	// zero disables retry. RetryBackoff is the wait before the first
+	// retry and doubles for each one after.
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// OnComplete, if set, receives the task's result instead of the
+	// Results channel. It runs on the worker goroutine, so it should be
+	// fast; a slow callback holds up the worker's other tasks.
+	OnComplete func(*Result)
 }
 
 // NewTask creates a new task with default values.
@@ -30,6 +57,70 @@ func NewTask(id string, data interface{}, fn func(interface{}) (interface{}, err
 	}
 }
 
+// PriorityFunc derives a task priority from a transaction.
+type PriorityFunc func(tx *Transaction) int
+
+// MetadataPriority returns a PriorityFunc that reads the priority from
+// tx.Metadata[key]. Integer, float and numeric string values are accepted,
+// so it works for both JSON and Arrow details; a missing or non-numeric
+// value falls back to tx.Priority.
+func MetadataPriority(key string) PriorityFunc {
+	return func(tx *Transaction) int {
+		switch v := tx.Metadata[key].(type) {
+		case int:
+			return v
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		case string:
+			if p, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return p
+			}
+		}
+		return tx.Priority
+	}
+}
+
+// NewTransactionTask creates a task that processes tx, with its priority
+// derived by priority. A nil priority uses tx.Priority.
+func NewTransactionTask(tx *Transaction, fn func(interface{}) (interface{}, error), priority PriorityFunc) *Task {
+	task := NewTask(tx.ID, tx, fn)
+	if priority != nil {
+		task.Priority = priority(tx)
+	} else {
+		task.Priority = tx.Priority
+	}
+	return task
+}
+
+// Middleware wraps a task's process function with cross-cutting behaviour
+// such as metrics, logging or tracing. It may call next or short-circuit.
+type Middleware func(next func(interface{}) (interface{}, error)) func(interface{}) (interface{}, error)
+
+// resultWarnInterval limits how often dropped-result warnings are logged.
+const resultWarnInterval = 10 * time.Second
+
+// PanicHandler is called when a task panics, e.g. to raise an alert.
+type PanicHandler func(taskID string, recovered interface{}, stack []byte)
+
+// MetricsSink receives worker pool measurements labelled with the pool
+// name, so one sink can serve many pools. It is the only metrics path for
+// a pool: completed and failed counts are the per-status counts of
+// ObserveTaskDuration. *api.Metrics implements it.
+type MetricsSink interface {
+	// ObserveTaskDuration records how long a finished task took.
+	ObserveTaskDuration(pool string, d time.Duration, success bool)
+	// SetActive reports the number of running tasks.
+	SetActive(pool string, n int)
+	// SetPending reports the number of queued tasks.
+	SetPending(pool string, n int)
+	// RecordDroppedResult records a result dropped because the result channel was full.
+	RecordDroppedResult(pool string)
+	// RecordQueueFull records a submission rejected because its queue was full.
+	RecordQueueFull(pool string)
+}
+
 // Result represents the result of task processing.
 type Result struct {
 	TaskID   string
@@ -38,6 +129,8 @@ type Result struct {
 	Error    error
 	Duration time.Duration
 	WorkerID int
+	Attempts int    // times ProcessFunc ran, retries included
+	Stack    []byte // goroutine stack captured when the task panicked
 }
 
 // PoolStats contains worker pool statistics.
@@ -49,76 +142,302 @@ type PoolStats struct {
 	Failed      int64   `json:"failed"`
 	Pending     int     `json:"pending"`
 	SuccessRate float64 `json:"success_rate"`
+	Dropped     int64   `json:"dropped_results"`
+	Rejected    int64   `json:"rejected"`
 }
 
 // WorkerPool manages a pool of goroutine workers for parallel processing.
 type WorkerPool struct {
 	name       string
-	workers    int
-	taskChan   chan *Task
+	workers    int // live worker count, guarded by mu
 	resultChan chan *Result
 	wg         sync.WaitGroup
 
+	// Shared queue of unkeyed tasks, highest priority first. taskReady
+	// holds one token per queued task so workers can wait for work in a
+	// select alongside their keyed queue and shutdown
+	queue       taskQueue
+	queueCap    int
+	queueSeq    uint64
+	queueClosed bool
+	queueMu     sync.Mutex
+	taskReady   chan struct{}
+
+	// Closed when a queued task is taken, for SubmitWithContext callers
+	// waiting for space; nil while nobody waits
+	queueSpace chan struct{}
+
+	// Per-worker keyed queues, exit signals and exit notifications,
+	// indexed by worker ID and guarded by mu. Slots at or above workers
+	// belong to workers retiring after a shrink
+	workerChans []chan *submission
+	workerQuit  []chan struct{}
+	workerDone  []chan struct{}
+
+	// Serializes Resize calls
+	resizeMu sync.Mutex
+
+	// Middleware applied to every task at dispatch time
+	middleware []Middleware
+
+	// Optional callback for recovered panics
+	panicHandler PanicHandler
+
+	// Logger for operational warnings
+	logger *log.Logger
+
+	// Optional sink for real-time metrics, guarded by mu
+	sink MetricsSink
+
+	// ID of the task each busy worker is executing, keyed by worker ID
+	executing   map[int]string
+	executingMu sync.Mutex
+
+	// Queued and running submissions, by task ID, for Cancel
+	cancels   map[string]map[*submission]struct{}
+	cancelsMu sync.Mutex
+
 	// Atomic counters for thread-safe statistics
 	active    int64
 	completed int64
 	failed    int64
+	dropped   int64
+	rejected  int64
+
+	// Tasks submitted but not yet finished; idleCh is closed and replaced
+	// each time it drops to zero
+	outstanding int64
+	idleCh      chan struct{}
+	idleMu      sync.Mutex
+
+	// Unix nanoseconds of the last dropped-result warning
+	lastDropWarn int64
 
 	// Control
-	ctx     context.Context
-	cancel  context.CancelFunc
-	running bool
-	mu      sync.RWMutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	running  bool
+	draining bool
+	mu       sync.RWMutex
 }
 
-// NewWorkerPool creates a new worker pool with the specified number of workers.
-func NewWorkerPool(name string, workers int) *WorkerPool {
+// defaultBufferPerWorker is the default task and result buffer per worker.
+const defaultBufferPerWorker = 100
+
+// WorkerPoolConfig contains configuration for a worker pool.
+type WorkerPoolConfig struct {
+	// Workers is the number of worker goroutines; zero or less means one
+	Workers int
+	// TaskBuffer is the capacity of the shared task queue; Submit returns
+	// ErrQueueFull beyond it. Zero or less means 100 per worker
+	TaskBuffer int
+	// ResultBuffer is the capacity of the Results channel; results beyond
+	// it are dropped until consumed. Zero or less means 100 per worker
+	ResultBuffer int
+}
+
+// DefaultWorkerPoolConfig returns the configuration NewWorkerPool uses.
+func DefaultWorkerPoolConfig(workers int) WorkerPoolConfig {
 	if workers <= 0 {
 		workers = 1
 	}
+	return WorkerPoolConfig{
+		Workers:      workers,
+		TaskBuffer:   workers * defaultBufferPerWorker,
+		ResultBuffer: workers * defaultBufferPerWorker,
+	}
+}
+
+// NewWorkerPool creates a new worker pool with the specified number of workers.
+func NewWorkerPool(name string, workers int) *WorkerPool {
+	return NewWorkerPoolWithConfig(name, DefaultWorkerPoolConfig(workers))
+}
+
+// NewWorkerPoolWithMetrics creates a new worker pool that reports task
+// durations and load to sink under its name.
+func NewWorkerPoolWithMetrics(name string, workers int, sink MetricsSink) *WorkerPool {
+	return newWorkerPool(name, DefaultWorkerPoolConfig(workers), sink)
+}
+
+// NewWorkerPoolWithConfig creates a new worker pool from config. The buffer
+// sizes trade memory for headroom: a small result buffer drops results
+// sooner when the consumer falls behind, a large one holds more of them.
+func NewWorkerPoolWithConfig(name string, config WorkerPoolConfig) *WorkerPool {
+	return newWorkerPool(name, config, nil)
+}
+
+// newWorkerPool creates a worker pool from config reporting to sink, which
+// may be nil.
+func newWorkerPool(name string, config WorkerPoolConfig, sink MetricsSink) *WorkerPool {
+	defaults := DefaultWorkerPoolConfig(config.Workers)
+	workers := defaults.Workers
+	if config.TaskBuffer <= 0 {
+		config.TaskBuffer = defaults.TaskBuffer
+	}
+	if config.ResultBuffer <= 0 {
+		config.ResultBuffer = defaults.ResultBuffer
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	pool := &WorkerPool{
 		name:       name,
 		workers:    workers,
-		taskChan:   make(chan *Task, workers*100), // buffered channel
-		resultChan: make(chan *Result, workers*100),
+		queueCap:   config.TaskBuffer,
+		taskReady:  make(chan struct{}, config.TaskBuffer),
+		resultChan: make(chan *Result, config.ResultBuffer),
 		ctx:        ctx,
 		cancel:     cancel,
 		running:    true,
+		logger:     log.Default(),
+		sink:       sink,
+		idleCh:     make(chan struct{}),
+		executing:  make(map[int]string),
+		cancels:    make(map[string]map[*submission]struct{}),
 	}
 
 	// Start workers
 	for i := 0; i < workers; i++ {
-		pool.wg.Add(1)
-		go pool.worker(i)
+		pool.startWorker(i)
 	}
 
 	return pool
 }
 
+// startWorker starts a worker in slot id with a fresh keyed queue. The
+// caller holds mu, or owns the pool during construction.
+func (p *WorkerPool) startWorker(id int) {
+	own := make(chan *submission, defaultBufferPerWorker)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	if id < len(p.workerChans) {
+		p.workerChans[id] = own
+		p.workerQuit[id] = quit
+		p.workerDone[id] = done
+	} else {
+		p.workerChans = append(p.workerChans, own)
+		p.workerQuit = append(p.workerQuit, quit)
+		p.workerDone = append(p.workerDone, done)
+	}
+
+	p.wg.Add(1)
+	go p.worker(id, own, quit, done)
+}
+
 // worker is the goroutine that processes tasks.
-func (p *WorkerPool) worker(id int) {
+func (p *WorkerPool) worker(id int, own chan *submission, quit, done chan struct{}) {
 	defer p.wg.Done()
+	defer close(done)
 
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
-		case task, ok := <-p.taskChan:
+		case <-quit:
+			p.drainOwn(id, own)
+			return
+		case sub := <-own:
+			p.processTask(id, sub)
+		case _, ok := <-p.taskReady:
 			if !ok {
 				return
 			}
-			p.processTask(id, task)
+			p.processTask(id, p.dequeue())
+		}
+	}
+}
+
+// drainOwn runs the keyed tasks left in a retiring worker's queue. No new
+// ones arrive once the worker is told to quit.
+func (p *WorkerPool) drainOwn(id int, own chan *submission) {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case sub := <-own:
+			p.processTask(id, sub)
+		default:
+			return
+		}
+	}
+}
+
+// Resize changes the number of workers to n. Growing starts new workers
+// at once; shrinking tells the surplus workers to exit once they finish
+// their current task and any keyed tasks already queued to them, so no
+// accepted task is dropped. Growing into a slot whose worker is still
+// retiring from an earlier shrink waits for it to exit, so Resize must not
+// be called from inside a task. A key may map to a different worker after
+// a resize: per-key ordering holds among tasks submitted between resizes,
+// so call WaitIdle first if it must hold across one.
+func (p *WorkerPool) Resize(n int) error {
+	if n < 1 {
+		return ErrWorkerCount
+	}
+
+	p.resizeMu.Lock()
+	defer p.resizeMu.Unlock()
+
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return ErrPoolShutdown
+	}
+	current := p.workers
+	if n <= current {
+		for id := n; id < current; id++ {
+			close(p.workerQuit[id])
 		}
+		p.workers = n
+		p.mu.Unlock()
+		return nil
+	}
+	var retiring []chan struct{}
+	if current < len(p.workerDone) {
+		retiring = append(retiring, p.workerDone[current:]...)
+	}
+	p.mu.Unlock()
+
+	for _, done := range retiring {
+		select {
+		case <-done:
+		case <-p.ctx.Done():
+			return ErrPoolShutdown
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.running {
+		return ErrPoolShutdown
+	}
+	for id := current; id < n; id++ {
+		p.startWorker(id)
 	}
+	p.workers = n
+	return nil
 }
 
-// processTask executes a single task and sends the result.
-func (p *WorkerPool) processTask(workerID int, task *Task) {
+// processTask executes a single submitted task and sends the result.
+func (p *WorkerPool) processTask(workerID int, sub *submission) {
+	task := sub.task
 	atomic.AddInt64(&p.active, 1)
-	defer atomic.AddInt64(&p.active, -1)
+	p.reportLoad()
+	p.executingMu.Lock()
+	p.executing[workerID] = task.ID
+	p.executingMu.Unlock()
+
+	// Runs after panic recovery, so every exit path is covered
+	defer func() {
+		p.executingMu.Lock()
+		delete(p.executing, workerID)
+		p.executingMu.Unlock()
+
+		atomic.AddInt64(&p.active, -1)
+		p.reportLoad()
+		p.finishOutstanding()
+	}()
 
 	start := time.Now()
 
@@ -132,29 +451,34 @@ func (p *WorkerPool) processTask(workerID int, task *Task) {
 		if r := recover(); r != nil {
 			result.Success = false
 			result.Error = errors.New("panic in task processing: " + panicToString(r))
+			result.Stack = debug.Stack()
 			result.Duration = time.Since(start)
-			atomic.AddInt64(&p.failed, 1)
-			p.sendResult(result)
+			p.recordOutcome(false, result.Duration)
+
+			p.mu.RLock()
+			handler := p.panicHandler
+			p.mu.RUnlock()
+			if handler != nil {
+				handler(task.ID, r, result.Stack)
+			}
+
+			p.sendResult(sub, result)
 		}
 	}()
 
 	// Check context cancellation
-	if task.Ctx != nil {
-		select {
-		case <-task.Ctx.Done():
-			result.Success = false
-			result.Error = task.Ctx.Err()
-			result.Duration = time.Since(start)
-			atomic.AddInt64(&p.failed, 1)
-			p.sendResult(result)
-			return
-		default:
-		}
+	if err := sub.ctx.Err(); err != nil {
+		result.Success = false
+		result.Error = err
+		result.Duration = time.Since(start)
+		p.recordOutcome(false, result.Duration)
+		p.sendResult(sub, result)
+		return
 	}
 
 	// Execute the task
 	if task.ProcessFunc != nil {
-		data, err := task.ProcessFunc(task.Data)
+		data, err := p.run(sub, result)
 		result.Data = data
 		result.Error = err
 		result.Success = err == nil
@@ -165,13 +489,146 @@ func (p *WorkerPool) processTask(workerID int, task *Task) {
 
 	result.Duration = time.Since(start)
 
-	if result.Success {
+	p.recordOutcome(result.Success, result.Duration)
+	p.sendResult(sub, result)
+}
+
+// run calls the task's process function, retrying failures up to
+// MaxRetries times with exponential backoff, and counts attempts in
+// result. A backoff is cut short with the context's error when the task's
+// Ctx is done or it is cancelled, or with ErrPoolShutdown when the pool
+// shuts down.
+func (p *WorkerPool) run(sub *submission, result *Result) (interface{}, error) {
+	task := sub.task
+	fn := p.wrap(task.ProcessFunc)
+	backoff := task.RetryBackoff
+
+	for {
+		result.Attempts++
+		data, err := fn(task.Data)
+		if err == nil || result.Attempts > task.MaxRetries {
+			return data, err
+		}
+
+		if err := p.waitRetry(sub.ctx, backoff); err != nil {
+			return nil, err
+		}
+		if next := backoff * 2; next > backoff {
+			backoff = next
+		}
+	}
+}
+
+// waitRetry sleeps for backoff unless ctx is done or the pool shuts down.
+func (p *WorkerPool) waitRetry(ctx context.Context, backoff time.Duration) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if backoff <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.ctx.Done():
+		return ErrPoolShutdown
+	}
+}
+
+// recordOutcome counts a finished task that ran for d.
+func (p *WorkerPool) recordOutcome(success bool, d time.Duration) {
+	if success {
 		atomic.AddInt64(&p.completed, 1)
 	} else {
 		atomic.AddInt64(&p.failed, 1)
 	}
 
-	p.sendResult(result)
+	if sink := p.metricsSink(); sink != nil {
+		sink.ObserveTaskDuration(p.name, d, success)
+	}
+}
+
+// reportLoad pushes the current active and pending counts to the metrics sink.
+func (p *WorkerPool) reportLoad() {
+	sink := p.metricsSink()
+	if sink == nil {
+		return
+	}
+
+	sink.SetActive(p.name, int(atomic.LoadInt64(&p.active)))
+	sink.SetPending(p.name, p.pending())
+}
+
+// rejectFull counts a submission rejected by a full queue.
+func (p *WorkerPool) rejectFull() {
+	atomic.AddInt64(&p.rejected, 1)
+	if sink := p.metricsSink(); sink != nil {
+		sink.RecordQueueFull(p.name)
+	}
+}
+
+// metricsSink returns the registered metrics sink, or nil.
+func (p *WorkerPool) metricsSink() MetricsSink {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.sink
+}
+
+// SetMetrics replaces the sink that receives pool events as they happen,
+// including one given to NewWorkerPoolWithMetrics. Passing nil disables
+// reporting.
+func (p *WorkerPool) SetMetrics(sink MetricsSink) {
+	p.mu.Lock()
+	p.sink = sink
+	p.mu.Unlock()
+
+	p.reportLoad()
+}
+
+// wrap composes the registered middleware around fn.
+// The first registered middleware is the outermost.
+func (p *WorkerPool) wrap(fn func(interface{}) (interface{}, error)) func(interface{}) (interface{}, error) {
+	p.mu.RLock()
+	middleware := p.middleware
+	p.mu.RUnlock()
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		fn = middleware[i](fn)
+	}
+	return fn
+}
+
+// Use registers middleware that wraps every task's process function.
+// Middleware composes in registration order and applies to tasks
+// dispatched after the call.
+func (p *WorkerPool) Use(mw Middleware) {
+	if mw == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	middleware := make([]Middleware, len(p.middleware), len(p.middleware)+1)
+	copy(middleware, p.middleware)
+	p.middleware = append(middleware, mw)
+}
+
+// SetPanicHandler registers a callback invoked with the recovered value
+// and stack trace whenever a task panics.
+func (p *WorkerPool) SetPanicHandler(handler PanicHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.panicHandler = handler
 }
 
 // panicToString converts a recovered panic value to a string.
@@ -186,61 +643,556 @@ func panicToString(r interface{}) string {
 	}
 }
 
-// sendResult sends a result to the result channel (non-blocking).
-func (p *WorkerPool) sendResult(result *Result) {
+// sendResult sends a result to the result channel (non-blocking). The
+// submission is untracked first, so a callback may submit the task again.
+func (p *WorkerPool) sendResult(sub *submission, result *Result) {
+	p.untrackCancel(sub)
+
+	if sub.onComplete != nil {
+		p.complete(sub.onComplete, result)
+		return
+	}
+
 	select {
 	case p.resultChan <- result:
 	default:
 		// Channel full, result dropped (caller should consume results)
+		dropped := atomic.AddInt64(&p.dropped, 1)
+		p.warnDroppedResults(dropped)
+		if sink := p.metricsSink(); sink != nil {
+			sink.RecordDroppedResult(p.name)
+		}
 	}
 }
 
-// Submit adds a task to the worker pool for processing.
-func (p *WorkerPool) Submit(task *Task) error {
+// complete hands result to a task's callback. A panicking callback is
+// logged rather than counted against the task, which has already finished.
+func (p *WorkerPool) complete(callback func(*Result), result *Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.mu.RLock()
+			logger := p.logger
+			p.mu.RUnlock()
+
+			if logger != nil {
+				logger.Printf("Warning: worker pool %q completion callback for task %s panicked: %s",
+					p.name, result.TaskID, panicToString(r))
+			}
+		}
+	}()
+	callback(result)
+}
+
+// warnDroppedResults logs a rate-limited warning when results are being dropped.
+func (p *WorkerPool) warnDroppedResults(dropped int64) {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&p.lastDropWarn)
+	if last != 0 && now-last < int64(resultWarnInterval) {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&p.lastDropWarn, last, now) {
+		return
+	}
+
 	p.mu.RLock()
-	running := p.running
+	logger := p.logger
 	p.mu.RUnlock()
 
-	if !running {
-		return errors.New("worker pool is shut down")
+	if logger != nil {
+		logger.Printf("Warning: worker pool %q result channel is full, %d results dropped so far; "+
+			"is a consumer reading Results()?", p.name, dropped)
 	}
+}
+
+// SetLogger sets the logger used for operational warnings.
+// Passing nil disables logging.
+func (p *WorkerPool) SetLogger(logger *log.Logger) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.logger = logger
+}
+
+// Submit adds a task to the worker pool for processing. Workers take the
+// queued task with the highest Priority first, and tasks of equal
+// priority in submission order. It returns ErrPoolShutdown, ErrDraining
+// or ErrQueueFull if the task is not accepted.
+func (p *WorkerPool) Submit(task *Task) error {
+	return p.submit(task, task.OnComplete)
+}
+
+// submit is Submit with the callback that receives the result, which
+// replaces the task's OnComplete for this submission only.
+func (p *WorkerPool) submit(task *Task, onComplete func(*Result)) error {
+	if err := p.admit(); err != nil {
+		return err
+	}
+	sub := p.trackCancel(task, onComplete)
+
+	if err := p.enqueue(sub); err != nil {
+		p.untrackCancel(sub)
+		p.finishOutstanding()
+		if errors.Is(err, ErrQueueFull) {
+			p.rejectFull()
+		}
+		return err
+	}
+	p.reportLoad()
+	return nil
+}
+
+// SubmitMany adds tasks to the worker pool in one pass, taking the queue
+// lock once. It accepts tasks in order until the queue is full and returns
+// how many were accepted, with ErrQueueFull if that is fewer than all of
+// them; tasks[accepted:] were not queued. It returns 0 and ErrPoolShutdown
+// or ErrDraining if the pool does not accept tasks.
+func (p *WorkerPool) SubmitMany(tasks []*Task) (int, error) {
+	if len(tasks) == 0 {
+		return 0, nil
+	}
+	if err := p.admitN(len(tasks)); err != nil {
+		return 0, err
+	}
+	subs := make([]*submission, len(tasks))
+	for i, task := range tasks {
+		subs[i] = p.trackCancel(task, task.OnComplete)
+	}
+
+	accepted, err := p.enqueueMany(subs)
+	for _, sub := range subs[accepted:] {
+		p.untrackCancel(sub)
+		p.finishOutstanding()
+		if errors.Is(err, ErrQueueFull) {
+			p.rejectFull()
+		}
+	}
+	if accepted > 0 {
+		p.reportLoad()
+	}
+	return accepted, err
+}
+
+// enqueueMany adds submissions to the shared queue until it is full and
+// returns how many were added.
+func (p *WorkerPool) enqueueMany(subs []*submission) (int, error) {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	for i, sub := range subs {
+		if err := p.enqueueLocked(sub); err != nil {
+			return i, err
+		}
+	}
+	return len(subs), nil
+}
+
+// SubmitWithContext adds a task to the worker pool like Submit, but when
+// the queue is full it waits for space instead of returning ErrQueueFull.
+// It returns ctx's error if ctx ends first, or ErrPoolShutdown if the pool
+// shuts down while it waits.
+func (p *WorkerPool) SubmitWithContext(task *Task, ctx context.Context) error {
+	if err := p.admit(); err != nil {
+		return err
+	}
+	sub := p.trackCancel(task, task.OnComplete)
+
+	for {
+		space, err := p.enqueueOrWait(sub)
+		if err == nil && space == nil {
+			p.reportLoad()
+			return nil
+		}
+		if err == nil {
+			select {
+			case <-space:
+				continue
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-p.ctx.Done():
+				err = ErrPoolShutdown
+			}
+		}
+
+		p.untrackCancel(sub)
+		p.finishOutstanding()
+		return err
+	}
+}
+
+// enqueueOrWait adds sub to the shared queue, or returns a channel that
+// is closed when a queued task is taken if the queue is full.
+func (p *WorkerPool) enqueueOrWait(sub *submission) (<-chan struct{}, error) {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	err := p.enqueueLocked(sub)
+	if !errors.Is(err, ErrQueueFull) {
+		return nil, err
+	}
+	if p.queueSpace == nil {
+		p.queueSpace = make(chan struct{})
+	}
+	return p.queueSpace, nil
+}
+
+// enqueue adds sub to the shared queue and hands workers a token for it.
+func (p *WorkerPool) enqueue(sub *submission) error {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+	return p.enqueueLocked(sub)
+}
+
+// enqueueLocked is enqueue for callers holding queueMu.
+func (p *WorkerPool) enqueueLocked(sub *submission) error {
+	if p.queueClosed {
+		return ErrPoolShutdown
+	}
+	if len(p.queue) >= p.queueCap {
+		return ErrQueueFull
+	}
+
+	p.queueSeq++
+	heap.Push(&p.queue, queuedTask{sub: sub, seq: p.queueSeq})
+	// Never blocks: there are at most as many tokens as queued tasks
+	p.taskReady <- struct{}{}
+	return nil
+}
+
+// dequeue removes the highest priority task from the shared queue. The
+// caller must hold a token from taskReady, which guarantees one is queued.
+func (p *WorkerPool) dequeue() *submission {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	if p.queueSpace != nil {
+		close(p.queueSpace)
+		p.queueSpace = nil
+	}
+	return heap.Pop(&p.queue).(queuedTask).sub
+}
+
+// closeQueue stops the shared queue accepting tasks and wakes idle workers.
+func (p *WorkerPool) closeQueue() {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	if !p.queueClosed {
+		p.queueClosed = true
+		close(p.taskReady)
+	}
+}
+
+// queuedTask is a submission in the shared queue with its submission
+// number.
+type queuedTask struct {
+	sub *submission
+	seq uint64
+}
+
+// taskQueue implements heap.Interface, ordering by Priority, highest
+// first, then by submission order.
+type taskQueue []queuedTask
+
+func (q taskQueue) Len() int { return len(q) }
+
+func (q taskQueue) Less(i, j int) bool {
+	if q[i].sub.task.Priority != q[j].sub.task.Priority {
+		return q[i].sub.task.Priority > q[j].sub.task.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q taskQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *taskQueue) Push(x interface{}) { *q = append(*q, x.(queuedTask)) }
+
+func (q *taskQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = queuedTask{} // avoid memory leak
+	*q = old[:n-1]
+	return item
+}
 
+// admit checks that the pool accepts submissions and counts the task as
+// outstanding. Both happen under the lock so Drain waits for every task
+// admitted before it began.
+func (p *WorkerPool) admit() error {
+	return p.admitN(1)
+}
+
+// admitN is admit for n tasks at once.
+func (p *WorkerPool) admitN(n int) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if !p.running {
+		return ErrPoolShutdown
+	}
+	if p.draining {
+		return ErrDraining
+	}
+	atomic.AddInt64(&p.outstanding, int64(n))
+	return nil
+}
+
+// SubmitKeyed adds a task to the queue of the worker that owns key.
+// All tasks submitted with the same key run on the same worker, one at a
+// time and in submission order, so per-key state can be kept without
+// locking. Keyed tasks share the worker with unkeyed ones.
+func (p *WorkerPool) SubmitKeyed(key string, task *Task) error {
+	if err := p.admit(); err != nil {
+		return err
+	}
+	sub := p.trackCancel(task, task.OnComplete)
+
+	// Send under the lock so Resize never retires a worker after its
+	// queue was chosen
+	p.mu.RLock()
+	var queued bool
 	select {
-	case p.taskChan <- task:
-		return nil
+	case p.workerChans[p.workerFor(key)] <- sub:
+		queued = true
 	default:
-		return errors.New("task queue is full")
 	}
+	p.mu.RUnlock()
+
+	if !queued {
+		p.untrackCancel(sub)
+		p.finishOutstanding()
+		p.rejectFull()
+		return ErrQueueFull
+	}
+	p.reportLoad()
+	return nil
 }
 
-// SubmitAndWait submits a task and waits for its result.
-func (p *WorkerPool) SubmitAndWait(task *Task, timeout time.Duration) (*Result, error) {
-	if err := p.Submit(task); err != nil {
-		return nil, err
+// submission is one accepted submission of a task. The worker runs it
+// with ctx, derived from the task's Ctx, which Cancel cancels; onComplete
+// receives its result, or the Results channel if nil. The task itself is
+// never modified, so it can be submitted again, even from onComplete.
+type submission struct {
+	task       *Task
+	ctx        context.Context
+	cancel     context.CancelFunc
+	onComplete func(*Result)
+}
+
+// trackCancel starts a submission of task and records it for Cancel.
+func (p *WorkerPool) trackCancel(task *Task, onComplete func(*Result)) *submission {
+	parent := task.Ctx
+	if parent == nil {
+		parent = context.Background()
 	}
+	ctx, cancel := context.WithCancel(parent)
+	sub := &submission{task: task, ctx: ctx, cancel: cancel, onComplete: onComplete}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	p.cancelsMu.Lock()
+	defer p.cancelsMu.Unlock()
 
+	subs := p.cancels[task.ID]
+	if subs == nil {
+		subs = make(map[*submission]struct{})
+		p.cancels[task.ID] = subs
+	}
+	subs[sub] = struct{}{}
+	return sub
+}
+
+// untrackCancel forgets a finished or rejected submission and releases
+// its context.
+func (p *WorkerPool) untrackCancel(sub *submission) {
+	p.cancelsMu.Lock()
+	defer p.cancelsMu.Unlock()
+
+	subs := p.cancels[sub.task.ID]
+	if _, ok := subs[sub]; ok {
+		sub.cancel()
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(p.cancels, sub.task.ID)
+		}
+	}
+}
+
+// submittedContext returns the context of a queued or running submission
+// of task, or nil if there is none.
+func (p *WorkerPool) submittedContext(task *Task) context.Context {
+	p.cancelsMu.Lock()
+	defer p.cancelsMu.Unlock()
+
+	for sub := range p.cancels[task.ID] {
+		if sub.task == task {
+			return sub.ctx
+		}
+	}
+	return nil
+}
+
+// Cancel cancels the task with taskID if it is still queued or running,
+// and reports whether one was found; every such task is cancelled if the
+// ID was submitted more than once. A queued task then fails with
+// context.Canceled without running. A running task sees the cancellation
+// through its Ctx or TaskContext, and retries stop.
+func (p *WorkerPool) Cancel(taskID string) bool {
+	p.cancelsMu.Lock()
+	defer p.cancelsMu.Unlock()
+
+	subs, ok := p.cancels[taskID]
+	for sub := range subs {
+		sub.cancel()
+	}
+	return ok
+}
+
+// finishOutstanding marks one submitted task as finished, waking
+// WaitIdle callers when none remain.
+func (p *WorkerPool) finishOutstanding() {
+	if atomic.AddInt64(&p.outstanding, -1) != 0 {
+		return
+	}
+
+	p.idleMu.Lock()
+	close(p.idleCh)
+	p.idleCh = make(chan struct{})
+	p.idleMu.Unlock()
+}
+
+// WaitIdle blocks until no task is queued or running, then returns nil
+// with the pool still accepting work. Every task whose Submit or
+// SubmitKeyed returned before WaitIdle was called has finished by then;
+// tasks submitted concurrently may or may not have. It returns the
+// context's error if ctx ends first, or an error if the pool shuts down.
+func (p *WorkerPool) WaitIdle(ctx context.Context) error {
 	for {
+		// Take the channel before checking, so a drop to zero after the
+		// check closes the channel being waited on
+		p.idleMu.Lock()
+		idle := p.idleCh
+		p.idleMu.Unlock()
+
+		if atomic.LoadInt64(&p.outstanding) == 0 {
+			return nil
+		}
+
 		select {
+		case <-idle:
 		case <-ctx.Done():
-			return nil, ctx.Err()
-		case result := <-p.resultChan:
-			if result.TaskID == task.ID {
-				return result, nil
-			}
-			// Put back non-matching results (not ideal, but simple)
-			p.sendResult(result)
+			return ctx.Err()
+		case <-p.ctx.Done():
+			return ErrPoolShutdown
 		}
 	}
 }
 
-// Results returns the result channel for consuming results.
+// workerFor maps a key to a worker ID. The caller holds mu.
+func (p *WorkerPool) workerFor(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(p.workers))
+}
+
+// WorkerQueueDepths returns the number of keyed tasks waiting in each
+// worker's dedicated queue, indexed by worker ID.
+func (p *WorkerPool) WorkerQueueDepths() []int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	depths := make([]int, p.workers)
+	for i := range depths {
+		depths[i] = len(p.workerChans[i])
+	}
+	return depths
+}
+
+// SubmitAndWait submits a task and waits for its result. The result is
+// delivered to the caller only, not to the Results channel; a callback
+// already set in task.OnComplete still runs first. The task itself is
+// left unchanged, so it can be submitted again.
+func (p *WorkerPool) SubmitAndWait(task *Task, timeout time.Duration) (*Result, error) {
+	done := make(chan *Result, 1)
+	callback := task.OnComplete
+
+	// The hook belongs to this submission only
+	err := p.submit(task, func(result *Result) {
+		// Deferred so a panicking callback still releases the caller
+		defer func() { done <- result }()
+		if callback != nil {
+			callback(result)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		return result, nil
+	case <-timer.C:
+		return nil, context.DeadlineExceeded
+	}
+}
+
+// Results returns the result channel for consuming results. Results of
+// tasks with an OnComplete callback are not sent to it.
 func (p *WorkerPool) Results() <-chan *Result {
 	return p.resultChan
 }
 
+// Context returns a context that is cancelled when the pool shuts down.
+// Long-running process functions can watch it to abort promptly on
+// Shutdown or ShutdownWithTimeout. It is independent of a task's own Ctx,
+// which is only checked before the task starts; a function that should
+// stop on either can use TaskContext.
+func (p *WorkerPool) Context() context.Context {
+	return p.ctx
+}
+
+// TaskContext returns a context derived from the task's Ctx that is also
+// cancelled when the pool shuts down, and by Cancel while the task is
+// queued or running. Its values and deadline come from the
+// task's Ctx; its Err reports context.Canceled on pool shutdown. The caller
+// must call the returned cancel function to release resources.
+func (p *WorkerPool) TaskContext(task *Task) (context.Context, context.CancelFunc) {
+	parent := context.Background()
+	if task != nil {
+		if ctx := p.submittedContext(task); ctx != nil {
+			parent = ctx
+		} else if task.Ctx != nil {
+			parent = task.Ctx
+		}
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(p.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// ActiveTasks returns the IDs of the tasks currently being executed,
+// ordered by worker ID. It is a snapshot for debugging a stuck pool.
+func (p *WorkerPool) ActiveTasks() []string {
+	p.executingMu.Lock()
+	defer p.executingMu.Unlock()
+
+	workerIDs := make([]int, 0, len(p.executing))
+	for workerID := range p.executing {
+		workerIDs = append(workerIDs, workerID)
+	}
+	sort.Ints(workerIDs)
+
+	ids := make([]string, len(workerIDs))
+	for i, workerID := range workerIDs {
+		ids[i] = p.executing[workerID]
+	}
+	return ids
+}
+
 // GetStats returns current worker pool statistics.
 func (p *WorkerPool) GetStats() PoolStats {
 	completed := atomic.LoadInt64(&p.completed)
@@ -252,15 +1204,79 @@ func (p *WorkerPool) GetStats() PoolStats {
 		successRate = float64(completed) / float64(total) * 100
 	}
 
+	p.mu.RLock()
+	workers := p.workers
+	p.mu.RUnlock()
+
 	return PoolStats{
 		Name:        p.name,
-		Workers:     p.workers,
+		Workers:     workers,
 		Active:      atomic.LoadInt64(&p.active),
 		Completed:   completed,
 		Failed:      failed,
-		Pending:     len(p.taskChan),
+		Pending:     p.pending(),
 		SuccessRate: successRate,
+		Dropped:     atomic.LoadInt64(&p.dropped),
+		Rejected:    atomic.LoadInt64(&p.rejected),
+	}
+}
+
+// pending returns the number of queued tasks, keyed queues included.
+func (p *WorkerPool) pending() int {
+	p.queueMu.Lock()
+	pending := len(p.queue)
+	p.queueMu.Unlock()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, ch := range p.workerChans {
+		pending += len(ch)
 	}
+	return pending
+}
+
+// Drain stops accepting tasks, waits for queued and running tasks to
+// finish, then shuts the pool down. Submissions during the drain fail with
+// ErrDraining so callers can retry elsewhere. If ctx ends first, Drain
+// returns its error and leaves the pool draining; Shutdown or
+// ShutdownWithTimeout then stops it.
+func (p *WorkerPool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return nil
+	}
+	p.draining = true
+	p.mu.Unlock()
+
+	if err := p.WaitIdle(ctx); err != nil {
+		return err
+	}
+	p.Shutdown()
+	return nil
+}
+
+// DrainWithTimeout drains the pool like Drain, giving queued and running
+// tasks up to timeout to finish; the worker context is not cancelled before
+// then. If the timeout elapses first, the pool is shut down anyway: queued
+// tasks may be abandoned, running ones see Context cancelled, and it returns
+// ErrDrainTimeout without waiting for them or closing the Results channel.
+func (p *WorkerPool) DrainWithTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := p.Drain(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	p.mu.Lock()
+	p.running = false
+	p.mu.Unlock()
+
+	p.cancel()
+	p.closeQueue()
+	return ErrDrainTimeout
 }
 
 // Shutdown gracefully shuts down the worker pool.
@@ -274,7 +1290,7 @@ func (p *WorkerPool) Shutdown() {
 	p.mu.Unlock()
 
 	p.cancel()
-	close(p.taskChan)
+	p.closeQueue()
 	p.wg.Wait()
 	close(p.resultChan)
 }
@@ -290,7 +1306,7 @@ func (p *WorkerPool) ShutdownWithTimeout(timeout time.Duration) error {
 	p.mu.Unlock()
 
 	p.cancel()
-	close(p.taskChan)
+	p.closeQueue()
 
 	done := make(chan struct{})
 	go func() {
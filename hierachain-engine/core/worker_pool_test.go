@@ -1,8 +1,14 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -133,27 +139,1143 @@ func TestWorkerPoolConcurrency(t *testing.T) {
 	}
 }
 
+func TestWorkerPoolWaitIdle(t *testing.T) {
+	pool := NewWorkerPool("barrier", 4)
+	defer pool.Shutdown()
+
+	var processed int64
+	submitBatch := func(phase, n int) {
+		for i := 0; i < n; i++ {
+			task := NewTask(fmt.Sprintf("phase-%d-%d", phase, i), nil, func(data interface{}) (interface{}, error) {
+				time.Sleep(time.Millisecond)
+				atomic.AddInt64(&processed, 1)
+				return nil, nil
+			})
+			var err error
+			if i%2 == 0 {
+				err = pool.Submit(task)
+			} else {
+				err = pool.SubmitKeyed(task.ID, task)
+			}
+			if err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Each phase is fully processed when WaitIdle returns, and the pool
+	// keeps accepting work afterwards
+	for phase := 1; phase <= 2; phase++ {
+		submitBatch(phase, 40)
+		if err := pool.WaitIdle(ctx); err != nil {
+			t.Fatalf("WaitIdle failed in phase %d: %v", phase, err)
+		}
+		if got := atomic.LoadInt64(&processed); got != int64(40*phase) {
+			t.Errorf("Expected %d tasks processed after phase %d, got %d", 40*phase, phase, got)
+		}
+		stats := pool.GetStats()
+		if stats.Active != 0 || stats.Pending != 0 {
+			t.Errorf("Expected idle pool, got active %d pending %d", stats.Active, stats.Pending)
+		}
+	}
+	if !pool.IsRunning() {
+		t.Error("WaitIdle should not shut the pool down")
+	}
+
+	// The context bounds the wait
+	release := make(chan struct{})
+	blocker := NewTask("blocker", nil, func(data interface{}) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	if err := pool.Submit(blocker); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	if err := pool.WaitIdle(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+	close(release)
+	if err := pool.WaitIdle(ctx); err != nil {
+		t.Errorf("WaitIdle after release failed: %v", err)
+	}
+}
+
+func TestWorkerPoolDrainRejectsSubmissions(t *testing.T) {
+	pool := NewWorkerPool("drain", 1)
+
+	var processed int64
+	release := make(chan struct{})
+	blocker := NewTask("blocker", nil, func(data interface{}) (interface{}, error) {
+		<-release
+		atomic.AddInt64(&processed, 1)
+		return nil, nil
+	})
+	if err := pool.Submit(blocker); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- pool.Drain(context.Background())
+	}()
+
+	// Submissions are accepted until the drain begins, then rejected with
+	// ErrDraining on every submit path
+	newTask := func(id string) *Task {
+		return NewTask(id, nil, func(data interface{}) (interface{}, error) {
+			atomic.AddInt64(&processed, 1)
+			return nil, nil
+		})
+	}
+	accepted := int64(1)
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := pool.Submit(newTask("probe"))
+		if errors.Is(err, ErrDraining) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected nil or ErrDraining, got %v", err)
+		}
+		accepted++
+		if time.Now().After(deadline) {
+			t.Fatal("Pool never started draining")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := pool.SubmitKeyed("key", newTask("keyed")); !errors.Is(err, ErrDraining) {
+		t.Errorf("Expected ErrDraining from SubmitKeyed, got %v", err)
+	}
+	if !pool.IsRunning() {
+		t.Error("Pool should still be running while draining")
+	}
+
+	// Accepted work finishes before the pool shuts down
+	close(release)
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatalf("Drain failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain did not return")
+	}
+	if got := atomic.LoadInt64(&processed); got != accepted {
+		t.Errorf("Expected %d accepted tasks processed, got %d", accepted, got)
+	}
+
+	if err := pool.Submit(newTask("late")); !errors.Is(err, ErrPoolShutdown) {
+		t.Errorf("Expected ErrPoolShutdown after drain, got %v", err)
+	}
+}
+
+func TestWorkerPoolDrainWithTimeout(t *testing.T) {
+	pool := NewWorkerPool("drain-timeout", 1)
+
+	var processed int64
+	for i := 0; i < 20; i++ {
+		task := NewTask(fmt.Sprintf("task-%d", i), nil, func(data interface{}) (interface{}, error) {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&processed, 1)
+			return nil, nil
+		})
+		if err := pool.Submit(task); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	if err := pool.DrainWithTimeout(5 * time.Second); err != nil {
+		t.Fatalf("DrainWithTimeout failed: %v", err)
+	}
+	if got := atomic.LoadInt64(&processed); got != 20 {
+		t.Errorf("Expected all 20 queued tasks processed, got %d", got)
+	}
+	if pool.IsRunning() {
+		t.Error("Pool should not be running after drain")
+	}
+}
+
+func TestWorkerPoolDrainWithTimeoutExpires(t *testing.T) {
+	pool := NewWorkerPool("drain-expired", 1)
+
+	started := make(chan struct{})
+	blocker := NewTask("blocker", nil, func(data interface{}) (interface{}, error) {
+		close(started)
+		<-pool.Context().Done()
+		return nil, nil
+	})
+	if err := pool.Submit(blocker); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+
+	queued := NewTask("queued", nil, func(data interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err := pool.Submit(queued); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	if err := pool.DrainWithTimeout(50 * time.Millisecond); !errors.Is(err, ErrDrainTimeout) {
+		t.Fatalf("Expected ErrDrainTimeout, got %v", err)
+	}
+	if pool.IsRunning() {
+		t.Error("Pool should not be running after drain timeout")
+	}
+	select {
+	case <-pool.Context().Done():
+	default:
+		t.Error("Expected the pool context to be cancelled after drain timeout")
+	}
+
+	if err := pool.Submit(queued); !errors.Is(err, ErrPoolShutdown) {
+		t.Errorf("Expected ErrPoolShutdown after drain timeout, got %v", err)
+	}
+}
+
+func TestWorkerPoolActiveTasks(t *testing.T) {
+	pool := NewWorkerPool("active", 3)
+	defer pool.Shutdown()
+
+	release := make(chan struct{})
+	slow := func(data interface{}) (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+	for _, id := range []string{"slow-a", "slow-b"} {
+		if err := pool.Submit(NewTask(id, nil, slow)); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	// A panicking task must not linger in the snapshot
+	if err := pool.Submit(NewTask("panics", nil, func(data interface{}) (interface{}, error) {
+		panic("boom")
+	})); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var active []string
+	for time.Now().Before(deadline) {
+		active = pool.ActiveTasks()
+		if len(active) == 2 && pool.GetStats().Failed == 1 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range active {
+		seen[id] = true
+	}
+	if len(active) != 2 || !seen["slow-a"] || !seen["slow-b"] {
+		t.Errorf("Expected slow-a and slow-b executing, got %v", active)
+	}
+
+	close(release)
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if active := pool.ActiveTasks(); len(active) != 0 {
+		t.Errorf("Expected no executing tasks, got %v", active)
+	}
+}
+
 func TestWorkerPoolShutdown(t *testing.T) {
 	pool := NewWorkerPool("test", 4)
 
-	// Submit a task
-	task := NewTask("task-1", nil, func(data interface{}) (interface{}, error) {
-		time.Sleep(10 * time.Millisecond)
+	// Submit a task
+	task := NewTask("task-1", nil, func(data interface{}) (interface{}, error) {
+		time.Sleep(10 * time.Millisecond)
+		return nil, nil
+	})
+	_ = pool.Submit(task)
+
+	// Shutdown
+	pool.Shutdown()
+
+	if pool.IsRunning() {
+		t.Error("Pool should not be running after shutdown")
+	}
+
+	// Submit after shutdown should fail
+	err := pool.Submit(task)
+	if err == nil {
+		t.Error("Submit after shutdown should fail")
+	}
+}
+
+func TestWorkerPoolContextCancelledOnShutdown(t *testing.T) {
+	pool := NewWorkerPool("test", 1)
+
+	started := make(chan struct{})
+	observed := make(chan error, 1)
+
+	task := NewTask("long-task", nil, nil)
+	task.ProcessFunc = func(data interface{}) (interface{}, error) {
+		ctx, cancel := pool.TaskContext(task)
+		defer cancel()
+
+		close(started)
+		select {
+		case <-ctx.Done():
+			observed <- ctx.Err()
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+			observed <- nil
+			return nil, nil
+		}
+	}
+
+	if err := pool.Submit(task); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+
+	if pool.Context().Err() != nil {
+		t.Fatal("Pool context cancelled before shutdown")
+	}
+
+	pool.Shutdown()
+
+	if pool.Context().Err() == nil {
+		t.Error("Pool context not cancelled after shutdown")
+	}
+	select {
+	case err := <-observed:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected task to observe context.Canceled, got %v", err)
+		}
+	default:
+		t.Error("Task did not finish before Shutdown returned")
+	}
+}
+
+func TestWorkerPoolTaskContextKeepsTaskCancellation(t *testing.T) {
+	pool := NewWorkerPool("test", 1)
+	defer pool.Shutdown()
+
+	taskCtx, cancelTask := context.WithCancel(context.Background())
+	task := NewTask("task", nil, nil)
+	task.Ctx = taskCtx
+
+	ctx, cancel := pool.TaskContext(task)
+	defer cancel()
+
+	cancelTask()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("TaskContext not cancelled with the task's Ctx")
+	}
+	if pool.Context().Err() != nil {
+		t.Error("Cancelling a task must not cancel the pool")
+	}
+}
+
+func TestWorkerPoolSubmitKeyed(t *testing.T) {
+	pool := NewWorkerPool("keyed", 4)
+	defer pool.Shutdown()
+
+	var mu sync.Mutex
+	var order []int
+	running := int64(0)
+	overlapped := int64(0)
+
+	numTasks := 10
+	for i := 0; i < numTasks; i++ {
+		i := i
+		task := NewTask(fmt.Sprintf("acct-%d", i), nil, func(data interface{}) (interface{}, error) {
+			if atomic.AddInt64(&running, 1) > 1 {
+				atomic.StoreInt64(&overlapped, 1)
+			}
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			atomic.AddInt64(&running, -1)
+			return nil, nil
+		})
+		if err := pool.SubmitKeyed("account-42", task); err != nil {
+			t.Fatalf("SubmitKeyed failed: %v", err)
+		}
+	}
+
+	workerID := -1
+	for i := 0; i < numTasks; i++ {
+		select {
+		case result := <-pool.Results():
+			if workerID == -1 {
+				workerID = result.WorkerID
+			} else if result.WorkerID != workerID {
+				t.Errorf("Task %s ran on worker %d, expected %d", result.TaskID, result.WorkerID, workerID)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timeout waiting for keyed results")
+		}
+	}
+
+	if workerID != pool.workerFor("account-42") {
+		t.Errorf("Expected worker %d for key, got %d", pool.workerFor("account-42"), workerID)
+	}
+	if atomic.LoadInt64(&overlapped) != 0 {
+		t.Error("Tasks for the same key ran concurrently")
+	}
+	mu.Lock()
+	for i, got := range order {
+		if got != i {
+			t.Errorf("Expected submission order, got %v", order)
+			break
+		}
+	}
+	mu.Unlock()
+
+	depths := pool.WorkerQueueDepths()
+	if len(depths) != 4 {
+		t.Fatalf("Expected 4 queue depths, got %d", len(depths))
+	}
+	for i, depth := range depths {
+		if depth != 0 {
+			t.Errorf("Expected empty queue for worker %d, got %d", i, depth)
+		}
+	}
+}
+
+func TestWorkerPoolSubmitPriority(t *testing.T) {
+	pool := NewWorkerPool("priority", 1)
+	defer pool.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocker := NewTask("blocker", nil, func(data interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	if err := pool.Submit(blocker); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+
+	// Queue while the only worker is busy, then check run order
+	var mu sync.Mutex
+	var order []string
+	for _, tc := range []struct {
+		id       string
+		priority int
+	}{
+		{"low-1", 1}, {"high-1", 10}, {"mid", 5}, {"low-2", 1}, {"high-2", 10},
+	} {
+		id := tc.id
+		task := NewTask(id, nil, func(data interface{}) (interface{}, error) {
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+			return nil, nil
+		})
+		task.Priority = tc.priority
+		if err := pool.Submit(task); err != nil {
+			t.Fatalf("Submit %s failed: %v", id, err)
+		}
+	}
+	if stats := pool.GetStats(); stats.Pending != 5 {
+		t.Errorf("Expected 5 pending tasks, got %d", stats.Pending)
+	}
+
+	close(release)
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"high-1", "high-2", "mid", "low-1", "low-2"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %d tasks run, got %v", len(expected), order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected run order %v, got %v", expected, order)
+			break
+		}
+	}
+}
+
+func TestWorkerPoolShutdownWithQueuedTasks(t *testing.T) {
+	pool := NewWorkerPoolWithConfig("queued", WorkerPoolConfig{Workers: 2, TaskBuffer: 4})
+
+	release := make(chan struct{})
+	for i := 0; i < 6; i++ {
+		task := NewTask(fmt.Sprintf("task-%d", i), nil, func(data interface{}) (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+		task.Priority = i
+		err := pool.Submit(task)
+		if err != nil && !errors.Is(err, ErrQueueFull) {
+			t.Fatalf("Unexpected Submit error: %v", err)
+		}
+	}
+	if err := pool.Submit(NewTask("overflow", nil, nil)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	close(release)
+	if err := pool.ShutdownWithTimeout(5 * time.Second); err != nil {
+		t.Fatalf("ShutdownWithTimeout failed: %v", err)
+	}
+	if err := pool.Submit(NewTask("late", nil, nil)); !errors.Is(err, ErrPoolShutdown) {
+		t.Errorf("Expected ErrPoolShutdown after shutdown, got %v", err)
+	}
+}
+
+func TestWorkerPoolSubmitWithContext(t *testing.T) {
+	pool := NewWorkerPoolWithConfig("backpressure", WorkerPoolConfig{Workers: 1, TaskBuffer: 1})
+	defer pool.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocker := NewTask("blocker", nil, func(data interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	if err := pool.Submit(blocker); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+
+	noop := func(data interface{}) (interface{}, error) { return nil, nil }
+	if err := pool.Submit(NewTask("fill", nil, noop)); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if err := pool.Submit(NewTask("rejected", nil, noop)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull from Submit, got %v", err)
+	}
+
+	// A full queue times out with the context's error
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.SubmitWithContext(NewTask("timeout", nil, noop), short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+
+	// and unblocks once a worker frees a slot
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- pool.SubmitWithContext(NewTask("waiting", nil, noop), context.Background())
+	}()
+	select {
+	case err := <-submitted:
+		t.Fatalf("Expected SubmitWithContext to block on a full queue, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-submitted:
+		if err != nil {
+			t.Errorf("SubmitWithContext failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SubmitWithContext did not unblock")
+	}
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if stats := pool.GetStats(); stats.Completed != 3 {
+		t.Errorf("Expected 3 completed tasks, got %d", stats.Completed)
+	}
+}
+
+func TestWorkerPoolSubmitMany(t *testing.T) {
+	pool := NewWorkerPoolWithConfig("many", WorkerPoolConfig{Workers: 1, TaskBuffer: 5})
+	defer pool.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocker := NewTask("blocker", nil, func(data interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	if err := pool.Submit(blocker); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+
+	var processed int64
+	tasks := make([]*Task, 8)
+	for i := range tasks {
+		tasks[i] = NewTask(fmt.Sprintf("task-%d", i), nil, func(data interface{}) (interface{}, error) {
+			atomic.AddInt64(&processed, 1)
+			return nil, nil
+		})
+	}
+
+	accepted, err := pool.SubmitMany(tasks)
+	if accepted != 5 || !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected 5 accepted and ErrQueueFull, got %d and %v", accepted, err)
+	}
+	if stats := pool.GetStats(); stats.Pending != 5 || stats.Rejected != 3 {
+		t.Errorf("Expected 5 pending and 3 rejected, got %d and %d", stats.Pending, stats.Rejected)
+	}
+
+	close(release)
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if got := atomic.LoadInt64(&processed); got != 5 {
+		t.Errorf("Expected the 5 accepted tasks processed, got %d", got)
+	}
+
+	// The rest can be resubmitted once there is room
+	if accepted, err := pool.SubmitMany(tasks[5:]); accepted != 3 || err != nil {
+		t.Errorf("Expected 3 accepted, got %d and %v", accepted, err)
+	}
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if got := atomic.LoadInt64(&processed); got != 8 {
+		t.Errorf("Expected 8 tasks processed, got %d", got)
+	}
+
+	pool.Shutdown()
+	if accepted, err := pool.SubmitMany(tasks); accepted != 0 || !errors.Is(err, ErrPoolShutdown) {
+		t.Errorf("Expected 0 accepted and ErrPoolShutdown, got %d and %v", accepted, err)
+	}
+}
+
+func TestWorkerPoolResizeUpIncreasesThroughput(t *testing.T) {
+	pool := NewWorkerPool("resize-up", 1)
+	defer pool.Shutdown()
+	go func() {
+		for range pool.Results() {
+		}
+	}()
+
+	// Time a batch of sleeping tasks, standing in for sustained I/O-bound load
+	runBatch := func(prefix string) time.Duration {
+		start := time.Now()
+		for i := 0; i < 32; i++ {
+			task := NewTask(fmt.Sprintf("%s-%d", prefix, i), nil, func(data interface{}) (interface{}, error) {
+				time.Sleep(5 * time.Millisecond)
+				return nil, nil
+			})
+			if err := pool.Submit(task); err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+		}
+		if err := pool.WaitIdle(context.Background()); err != nil {
+			t.Fatalf("WaitIdle failed: %v", err)
+		}
+		return time.Since(start)
+	}
+
+	before := runBatch("before")
+	if err := pool.Resize(8); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if stats := pool.GetStats(); stats.Workers != 8 {
+		t.Errorf("Expected 8 workers, got %d", stats.Workers)
+	}
+	after := runBatch("after")
+
+	if after*2 > before {
+		t.Errorf("Expected at least twice the throughput after resizing, took %v before and %v after", before, after)
+	}
+}
+
+func TestWorkerPoolResizeDownKeepsTasks(t *testing.T) {
+	pool := NewWorkerPool("resize-down", 4)
+	defer pool.Shutdown()
+
+	var received int64
+	go func() {
+		for range pool.Results() {
+			atomic.AddInt64(&received, 1)
+		}
+	}()
+
+	// Hold every worker busy and queue keyed work behind each of them
+	release := make(chan struct{})
+	var processed int64
+	newTask := func(id string) *Task {
+		return NewTask(id, nil, func(data interface{}) (interface{}, error) {
+			<-release
+			atomic.AddInt64(&processed, 1)
+			return nil, nil
+		})
+	}
+	submitted := int64(0)
+	for i := 0; i < 4; i++ {
+		if err := pool.Submit(newTask(fmt.Sprintf("busy-%d", i))); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		submitted++
+	}
+	for i := 0; i < 16; i++ {
+		if err := pool.SubmitKeyed(fmt.Sprintf("key-%d", i), newTask(fmt.Sprintf("keyed-%d", i))); err != nil {
+			t.Fatalf("SubmitKeyed failed: %v", err)
+		}
+		submitted++
+	}
+
+	if err := pool.Resize(1); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if stats := pool.GetStats(); stats.Workers != 1 {
+		t.Errorf("Expected 1 worker, got %d", stats.Workers)
+	}
+	if depths := pool.WorkerQueueDepths(); len(depths) != 1 {
+		t.Errorf("Expected 1 keyed queue, got %d", len(depths))
+	}
+
+	close(release)
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if got := atomic.LoadInt64(&processed); got != submitted {
+		t.Errorf("Expected %d tasks processed, got %d", submitted, got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&received) != submitted && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := atomic.LoadInt64(&received); got != submitted {
+		t.Errorf("Expected %d results, got %d", submitted, got)
+	}
+
+	// Growing again reuses the retired slots
+	if err := pool.Resize(3); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if err := pool.Resize(0); !errors.Is(err, ErrWorkerCount) {
+		t.Errorf("Expected ErrWorkerCount, got %v", err)
+	}
+	pool.Shutdown()
+	if err := pool.Resize(2); !errors.Is(err, ErrPoolShutdown) {
+		t.Errorf("Expected ErrPoolShutdown after shutdown, got %v", err)
+	}
+}
+
+func TestWorkerPoolResizeConcurrentSubmit(t *testing.T) {
+	pool := NewWorkerPool("resize-concurrent", 2)
+	go func() {
+		for range pool.Results() {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var processed, accepted int64
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				task := NewTask(fmt.Sprintf("%d-%d", g, i), nil, func(data interface{}) (interface{}, error) {
+					atomic.AddInt64(&processed, 1)
+					return nil, nil
+				})
+				var err error
+				if i%2 == 0 {
+					err = pool.Submit(task)
+				} else {
+					err = pool.SubmitKeyed(strconv.Itoa(i), task)
+				}
+				if err == nil {
+					atomic.AddInt64(&accepted, 1)
+				}
+			}
+		}(g)
+	}
+	for _, n := range []int{6, 1, 4, 2, 8} {
+		if err := pool.Resize(n); err != nil {
+			t.Fatalf("Resize(%d) failed: %v", n, err)
+		}
+	}
+	wg.Wait()
+
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if got, want := atomic.LoadInt64(&processed), atomic.LoadInt64(&accepted); got != want {
+		t.Errorf("Expected %d tasks processed, got %d", want, got)
+	}
+	pool.Shutdown()
+}
+
+func TestWorkerPoolRetry(t *testing.T) {
+	pool := NewWorkerPool("retry", 1)
+	defer pool.Shutdown()
+
+	transient := errors.New("transient")
+	var calls int64
+	task := NewTask("flaky", nil, func(data interface{}) (interface{}, error) {
+		if atomic.AddInt64(&calls, 1) < 3 {
+			return nil, transient
+		}
+		return "ok", nil
+	})
+	task.MaxRetries = 5
+	task.RetryBackoff = time.Millisecond
+
+	result, err := pool.SubmitAndWait(task, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if !result.Success || result.Data != "ok" {
+		t.Errorf("Expected success after retries, got %v", result.Error)
+	}
+	if result.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", result.Attempts)
+	}
+
+	// Exhausted retries report the last error
+	failing := NewTask("failing", nil, func(data interface{}) (interface{}, error) {
+		return nil, transient
+	})
+	failing.MaxRetries = 2
+	result, err = pool.SubmitAndWait(failing, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if result.Success || !errors.Is(result.Error, transient) {
+		t.Errorf("Expected transient error, got %v", result.Error)
+	}
+	if result.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", result.Attempts)
+	}
+
+	// Retry is opt-in
+	atomic.StoreInt64(&calls, 0)
+	plain := NewTask("plain", nil, func(data interface{}) (interface{}, error) {
+		atomic.AddInt64(&calls, 1)
+		return nil, transient
+	})
+	result, err = pool.SubmitAndWait(plain, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if result.Attempts != 1 || atomic.LoadInt64(&calls) != 1 {
+		t.Errorf("Expected a single attempt without retry, got %d", result.Attempts)
+	}
+}
+
+func TestWorkerPoolRetryCancelledDuringBackoff(t *testing.T) {
+	pool := NewWorkerPool("retry-cancel", 1)
+	defer pool.Shutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	task := NewTask("cancelled", nil, func(data interface{}) (interface{}, error) {
+		cancel()
+		return nil, errors.New("transient")
+	})
+	task.Ctx = ctx
+	task.MaxRetries = 3
+	task.RetryBackoff = time.Hour
+
+	start := time.Now()
+	result, err := pool.SubmitAndWait(task, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if !errors.Is(result.Error, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", result.Error)
+	}
+	if result.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", result.Attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected backoff to abort promptly, took %v", elapsed)
+	}
+}
+
+func TestWorkerPoolOnComplete(t *testing.T) {
+	pool := NewWorkerPool("callback", 4)
+	defer pool.Shutdown()
+
+	// Each submitter gets its own result, none reach the shared channel
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			got := make(chan *Result, 1)
+			task := NewTask(fmt.Sprintf("task-%d", g), g, func(data interface{}) (interface{}, error) {
+				return data.(int) * 10, nil
+			})
+			task.OnComplete = func(result *Result) { got <- result }
+			if err := pool.Submit(task); err != nil {
+				t.Errorf("Submit failed: %v", err)
+				return
+			}
+			select {
+			case result := <-got:
+				if result.TaskID != task.ID || result.Data != g*10 {
+					t.Errorf("Expected result %d for %s, got %v for %s", g*10, task.ID, result.Data, result.TaskID)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("Callback for %s never ran", task.ID)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	select {
+	case result := <-pool.Results():
+		t.Errorf("Expected no result on the shared channel, got %s", result.TaskID)
+	default:
+	}
+}
+
+func TestWorkerPoolSubmitAndWaitLeavesOtherResults(t *testing.T) {
+	pool := NewWorkerPool("wait", 1)
+	defer pool.Shutdown()
+
+	other := NewTask("other", nil, func(data interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err := pool.Submit(other); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	task := NewTask("mine", nil, func(data interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	result, err := pool.SubmitAndWait(task, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if result.TaskID != "mine" {
+		t.Errorf("Expected result for mine, got %s", result.TaskID)
+	}
+
+	select {
+	case result := <-pool.Results():
+		if result.TaskID != "other" {
+			t.Errorf("Expected other on the shared channel, got %s", result.TaskID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Result for other was not delivered to the shared channel")
+	}
+}
+
+func TestWorkerPoolSubmitAndWaitReusesTask(t *testing.T) {
+	pool := NewWorkerPool("wait-again", 1)
+	defer pool.Shutdown()
+
+	var callbacks int64
+	task := NewTask("again", nil, func(data interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	task.OnComplete = func(result *Result) { atomic.AddInt64(&callbacks, 1) }
+	callback := reflect.ValueOf(task.OnComplete).Pointer()
+
+	for i := 0; i < 3; i++ {
+		result, err := pool.SubmitAndWait(task, 5*time.Second)
+		if err != nil {
+			t.Fatalf("SubmitAndWait %d failed: %v", i, err)
+		}
+		if !result.Success {
+			t.Errorf("Expected call %d to succeed, got %v", i, result.Error)
+		}
+	}
+
+	if got := atomic.LoadInt64(&callbacks); got != 3 {
+		t.Errorf("Expected OnComplete to run 3 times, got %d", got)
+	}
+	if reflect.ValueOf(task.OnComplete).Pointer() != callback {
+		t.Error("Expected SubmitAndWait to leave the task's OnComplete unchanged")
+	}
+}
+
+func TestWorkerPoolOnCompletePanic(t *testing.T) {
+	pool := NewWorkerPool("callback-panic", 1)
+	defer pool.Shutdown()
+
+	var logs safeBuffer
+	pool.SetLogger(log.New(&logs, "", 0))
+
+	task := NewTask("panicky", nil, func(data interface{}) (interface{}, error) {
 		return nil, nil
 	})
-	_ = pool.Submit(task)
+	task.OnComplete = func(result *Result) { panic("boom") }
+	result, err := pool.SubmitAndWait(task, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if !result.Success {
+		t.Errorf("Expected the task to succeed, got %v", result.Error)
+	}
+	if !strings.Contains(logs.String(), "completion callback for task panicky panicked") {
+		t.Errorf("Expected a callback panic warning, got %q", logs.String())
+	}
+	if stats := pool.GetStats(); stats.Completed != 1 || stats.Failed != 0 {
+		t.Errorf("Expected 1 completed and 0 failed, got %d and %d", stats.Completed, stats.Failed)
+	}
+}
 
-	// Shutdown
-	pool.Shutdown()
+func TestWorkerPoolCancel(t *testing.T) {
+	pool := NewWorkerPool("cancel", 1)
+	defer pool.Shutdown()
 
-	if pool.IsRunning() {
-		t.Error("Pool should not be running after shutdown")
+	started := make(chan struct{})
+	running := NewTask("running", nil, nil)
+	results := make(chan *Result, 2)
+	running.OnComplete = func(result *Result) { results <- result }
+	running.ProcessFunc = func(data interface{}) (interface{}, error) {
+		ctx, cancel := pool.TaskContext(running)
+		defer cancel()
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if err := pool.Submit(running); err != nil {
+		t.Fatalf("Submit failed: %v", err)
 	}
+	<-started
 
-	// Submit after shutdown should fail
-	err := pool.Submit(task)
-	if err == nil {
-		t.Error("Submit after shutdown should fail")
+	var ranQueued int64
+	queued := NewTask("queued", nil, func(data interface{}) (interface{}, error) {
+		atomic.AddInt64(&ranQueued, 1)
+		return nil, nil
+	})
+	queued.OnComplete = func(result *Result) { results <- result }
+	if err := pool.Submit(queued); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	if !pool.Cancel("queued") {
+		t.Error("Expected Cancel to find the queued task")
+	}
+	if !pool.Cancel("running") {
+		t.Error("Expected Cancel to find the running task")
+	}
+	if pool.Cancel("unknown") {
+		t.Error("Expected Cancel to report an unknown task as not found")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case result := <-results:
+			if !errors.Is(result.Error, context.Canceled) {
+				t.Errorf("Expected context.Canceled for %s, got %v", result.TaskID, result.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Cancelled tasks did not finish")
+		}
+	}
+	if got := atomic.LoadInt64(&ranQueued); got != 0 {
+		t.Errorf("Expected the cancelled queued task not to run, ran %d times", got)
+	}
+
+	// Finished tasks are forgotten
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if pool.Cancel("running") || pool.Cancel("queued") {
+		t.Error("Expected Cancel to report finished tasks as not found")
+	}
+	pool.cancelsMu.Lock()
+	tracked := len(pool.cancels)
+	pool.cancelsMu.Unlock()
+	if tracked != 0 {
+		t.Errorf("Expected no tracked tasks after completion, got %d", tracked)
+	}
+}
+
+func TestWorkerPoolResubmitFromCallback(t *testing.T) {
+	pool := NewWorkerPool("resubmit", 1)
+	defer pool.Shutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	task := NewTask("again", nil, func(data interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	task.Ctx = ctx
+
+	results := make(chan *Result, 3)
+	var runs int64
+	task.OnComplete = func(result *Result) {
+		results <- result
+		if atomic.AddInt64(&runs, 1) < 3 {
+			if err := pool.Submit(task); err != nil {
+				t.Errorf("Resubmit failed: %v", err)
+			}
+		}
+	}
+	if err := pool.Submit(task); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case result := <-results:
+			if !result.Success {
+				t.Errorf("Expected run %d to succeed, got %v", i, result.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Run %d did not finish", i)
+		}
+	}
+
+	if err := pool.WaitIdle(context.Background()); err != nil {
+		t.Fatalf("WaitIdle failed: %v", err)
+	}
+	if task.Ctx != ctx {
+		t.Error("Expected the pool to leave the task's Ctx unchanged")
+	}
+	pool.cancelsMu.Lock()
+	tracked := len(pool.cancels)
+	pool.cancelsMu.Unlock()
+	if tracked != 0 {
+		t.Errorf("Expected no tracked tasks after completion, got %d", tracked)
+	}
+}
+
+func TestNewTransactionTaskPriority(t *testing.T) {
+	byMetadata := MetadataPriority("priority")
+
+	tests := []struct {
+		name     string
+		tx       *Transaction
+		source   PriorityFunc
+		expected int
+	}{
+		{"default uses tx priority", &Transaction{ID: "tx-1", Priority: 3}, nil, 3},
+		{"numeric metadata", &Transaction{ID: "tx-2", Metadata: map[string]interface{}{"priority": float64(10)}}, byMetadata, 10},
+		{"string metadata", &Transaction{ID: "tx-3", Metadata: map[string]interface{}{"priority": "7"}}, byMetadata, 7},
+		{"invalid metadata falls back", &Transaction{ID: "tx-4", Priority: 2, Metadata: map[string]interface{}{"priority": "high"}}, byMetadata, 2},
+		{"missing metadata falls back", &Transaction{ID: "tx-5", Priority: 1}, byMetadata, 1},
+		{"custom function", &Transaction{ID: "tx-6", EventType: "block"}, func(tx *Transaction) int {
+			if tx.EventType == "block" {
+				return 100
+			}
+			return 0
+		}, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := NewTransactionTask(tt.tx, func(data interface{}) (interface{}, error) {
+				return data, nil
+			}, tt.source)
+
+			if task.Priority != tt.expected {
+				t.Errorf("Expected priority %d, got %d", tt.expected, task.Priority)
+			}
+			if task.ID != tt.tx.ID || task.Data != tt.tx {
+				t.Error("Task should carry the transaction ID and data")
+			}
+		})
 	}
 }
 
@@ -191,6 +1313,403 @@ func TestWorkerPoolStats(t *testing.T) {
 	}
 }
 
+func TestWorkerPoolMiddleware(t *testing.T) {
+	pool := NewWorkerPool("middleware", 1)
+	defer pool.Shutdown()
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(s string) {
+		mu.Lock()
+		calls = append(calls, s)
+		mu.Unlock()
+	}
+
+	pool.Use(func(next func(interface{}) (interface{}, error)) func(interface{}) (interface{}, error) {
+		return func(data interface{}) (interface{}, error) {
+			record("first-before")
+			out, err := next(data)
+			record("first-after")
+			return out, err
+		}
+	})
+	pool.Use(func(next func(interface{}) (interface{}, error)) func(interface{}) (interface{}, error) {
+		return func(data interface{}) (interface{}, error) {
+			if data == "blocked" {
+				return nil, errors.New("blocked by middleware")
+			}
+			record("second-before")
+			return next(data)
+		}
+	})
+
+	task := NewTask("mw-1", "ok", func(data interface{}) (interface{}, error) {
+		record("task")
+		return data, nil
+	})
+	result, err := pool.SubmitAndWait(task, time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Task should succeed, got %v", result.Error)
+	}
+
+	expected := []string{"first-before", "second-before", "task", "first-after"}
+	mu.Lock()
+	got := append([]string(nil), calls...)
+	calls = nil
+	mu.Unlock()
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected call order %v, got %v", expected, got)
+	}
+
+	// Short-circuit: the task function must not run
+	blocked := NewTask("mw-2", "blocked", func(data interface{}) (interface{}, error) {
+		record("task")
+		return data, nil
+	})
+	result, err = pool.SubmitAndWait(blocked, time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	if result.Success {
+		t.Error("Short-circuited task should fail")
+	}
+
+	expected = []string{"first-before", "first-after"}
+	mu.Lock()
+	got = append([]string(nil), calls...)
+	mu.Unlock()
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected call order %v, got %v", expected, got)
+	}
+}
+
+func TestWorkerPoolPanicStack(t *testing.T) {
+	pool := NewWorkerPool("panic", 1)
+	defer pool.Shutdown()
+
+	handled := make(chan []byte, 1)
+	pool.SetPanicHandler(func(taskID string, recovered interface{}, stack []byte) {
+		if taskID != "panic-1" {
+			t.Errorf("Expected task ID 'panic-1', got %s", taskID)
+		}
+		handled <- stack
+	})
+
+	task := NewTask("panic-1", nil, func(data interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	result, err := pool.SubmitAndWait(task, time.Second)
+	if err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+
+	if result.Success {
+		t.Error("Panicking task should fail")
+	}
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "boom") {
+		t.Errorf("Expected panic message in error, got %v", result.Error)
+	}
+	if len(result.Stack) == 0 {
+		t.Fatal("Expected stack trace in result")
+	}
+	if !strings.Contains(string(result.Stack), "worker_pool_test.go") {
+		t.Errorf("Stack does not include the panicking frame:\n%s", result.Stack)
+	}
+
+	select {
+	case stack := <-handled:
+		if len(stack) == 0 {
+			t.Error("Panic handler received empty stack")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Panic handler was not called")
+	}
+}
+
+func TestWorkerPoolDroppedResultsWarning(t *testing.T) {
+	pool := NewWorkerPool("no-consumer", 1)
+	defer pool.Shutdown()
+
+	var logBuf safeBuffer
+	pool.SetLogger(log.New(&logBuf, "", 0))
+
+	// Result channel holds 100 results; nobody reads them
+	numTasks := 150
+	for i := 0; i < numTasks; i++ {
+		task := NewTask(fmt.Sprintf("task-%d", i), nil, func(data interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		for pool.Submit(task) != nil {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.GetStats().Completed < int64(numTasks) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stats := pool.GetStats()
+	if stats.Dropped != int64(numTasks-100) {
+		t.Errorf("Expected %d dropped results, got %d", numTasks-100, stats.Dropped)
+	}
+	if !strings.Contains(logBuf.String(), "result channel is full") {
+		t.Errorf("Expected dropped-result warning, got log: %q", logBuf.String())
+	}
+}
+
+func TestWorkerPoolConfigBuffers(t *testing.T) {
+	pool := NewWorkerPoolWithConfig("buffers", WorkerPoolConfig{Workers: 1, TaskBuffer: 2, ResultBuffer: 3})
+	defer pool.Shutdown()
+	pool.SetLogger(nil)
+
+	release := make(chan struct{})
+	blocking := func(data interface{}) (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+
+	// Occupy the only worker so queued tasks stay in the task buffer
+	if err := pool.Submit(NewTask("task-0", nil, blocking)); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.GetStats().Active != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 1; i <= 2; i++ {
+		if err := pool.Submit(NewTask(fmt.Sprintf("task-%d", i), nil, blocking)); err != nil {
+			t.Fatalf("Submit %d within the task buffer failed: %v", i, err)
+		}
+	}
+	if err := pool.Submit(NewTask("task-3", nil, blocking)); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull beyond the task buffer, got %v", err)
+	}
+
+	// Five results with nobody reading: three fit, two are dropped
+	close(release)
+	for i := 4; i <= 5; i++ {
+		for pool.Submit(NewTask(fmt.Sprintf("task-%d", i), nil, blocking)) != nil {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for pool.GetStats().Completed < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if stats := pool.GetStats(); stats.Completed != 5 || stats.Dropped != 2 {
+		t.Errorf("Expected 5 completed and 2 dropped, got %d and %d", stats.Completed, stats.Dropped)
+	}
+	if n := len(pool.Results()); n != 3 {
+		t.Errorf("Expected 3 buffered results, got %d", n)
+	}
+}
+
+func TestWorkerPoolConfigDefaults(t *testing.T) {
+	pool := NewWorkerPoolWithConfig("defaults", WorkerPoolConfig{Workers: 2})
+	defer pool.Shutdown()
+
+	if pool.queueCap != 200 || cap(pool.resultChan) != 200 {
+		t.Errorf("Expected default buffers of 200, got %d and %d", pool.queueCap, cap(pool.resultChan))
+	}
+	if cfg := DefaultWorkerPoolConfig(0); cfg.Workers != 1 || cfg.TaskBuffer != 100 || cfg.ResultBuffer != 100 {
+		t.Errorf("Unexpected default config: %+v", cfg)
+	}
+}
+
+// recordingSink is a MetricsSink that records what it receives.
+type recordingSink struct {
+	durations map[string][]time.Duration
+	failures  int
+	active    map[string]int
+	pending   map[string]int
+	dropped   map[string]int
+	rejected  map[string]int
+	mu        sync.Mutex
+}
+
+func newRecordingSink() *recordingSink {
+	return &recordingSink{
+		durations: make(map[string][]time.Duration),
+		active:    make(map[string]int),
+		pending:   make(map[string]int),
+		dropped:   make(map[string]int),
+		rejected:  make(map[string]int),
+	}
+}
+
+func (s *recordingSink) ObserveTaskDuration(pool string, d time.Duration, success bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.durations[pool] = append(s.durations[pool], d)
+	if !success {
+		s.failures++
+	}
+}
+
+func (s *recordingSink) SetActive(pool string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active[pool] = n
+}
+
+func (s *recordingSink) SetPending(pool string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[pool] = n
+}
+
+func (s *recordingSink) RecordDroppedResult(pool string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped[pool]++
+}
+
+func (s *recordingSink) RecordQueueFull(pool string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected[pool]++
+}
+
+func TestWorkerPoolMetrics(t *testing.T) {
+	pool := NewWorkerPool("metrics", 1)
+	defer pool.Shutdown()
+	pool.SetLogger(nil)
+
+	sink := newRecordingSink()
+	pool.SetMetrics(sink)
+
+	// Hold the only worker so submitted tasks stay pending
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocker := NewTask("blocker", nil, func(data interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, errors.New("blocker failed")
+	})
+	if err := pool.Submit(blocker); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-started
+
+	// Fill the task queue (capacity 100), then overflow it once
+	for i := 0; i < 100; i++ {
+		task := NewTask(fmt.Sprintf("task-%d", i), nil, func(data interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		if err := pool.Submit(task); err != nil {
+			t.Fatalf("Submit %d failed: %v", i, err)
+		}
+	}
+	if err := pool.Submit(NewTask("overflow", nil, nil)); err == nil {
+		t.Fatal("Expected queue full error")
+	}
+
+	sink.mu.Lock()
+	if sink.active["metrics"] != 1 || sink.pending["metrics"] != 100 {
+		t.Errorf("Expected active 1 and pending 100, got %d and %d", sink.active["metrics"], sink.pending["metrics"])
+	}
+	if sink.rejected["metrics"] != 1 {
+		t.Errorf("Expected 1 queue-full rejection, got %d", sink.rejected["metrics"])
+	}
+	sink.mu.Unlock()
+
+	// 101 results against a result channel of 100: one is dropped
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.GetStats().Completed < 100 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	for pool.GetStats().Active > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	sink.mu.Lock()
+	finished, failed := len(sink.durations["metrics"]), sink.failures
+	if finished-failed != 100 || failed != 1 {
+		t.Errorf("Expected 100 completed and 1 failed, got %d and %d", finished-failed, failed)
+	}
+	if sink.dropped["metrics"] != 1 {
+		t.Errorf("Expected 1 dropped result, got %d", sink.dropped["metrics"])
+	}
+	if sink.active["metrics"] != 0 || sink.pending["metrics"] != 0 {
+		t.Errorf("Expected idle gauges, got active %d pending %d", sink.active["metrics"], sink.pending["metrics"])
+	}
+	sink.mu.Unlock()
+
+	if stats := pool.GetStats(); stats.Rejected != 1 {
+		t.Errorf("Expected stats to report 1 rejection, got %d", stats.Rejected)
+	}
+}
+
+func TestWorkerPoolMetricsSink(t *testing.T) {
+	sink := newRecordingSink()
+	first := NewWorkerPoolWithMetrics("first", 2, sink)
+	defer first.Shutdown()
+	second := NewWorkerPoolWithMetrics("second", 1, sink)
+	defer second.Shutdown()
+
+	slow := func(data interface{}) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := first.SubmitAndWait(NewTask(fmt.Sprintf("first-%d", i), nil, slow), 5*time.Second); err != nil {
+			t.Fatalf("SubmitAndWait failed: %v", err)
+		}
+	}
+	failing := NewTask("second-0", nil, func(data interface{}) (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+	if _, err := second.SubmitAndWait(failing, 5*time.Second); err != nil {
+		t.Fatalf("SubmitAndWait failed: %v", err)
+	}
+	for _, pool := range []*WorkerPool{first, second} {
+		if err := pool.WaitIdle(context.Background()); err != nil {
+			t.Fatalf("WaitIdle failed: %v", err)
+		}
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.durations["first"]) != 3 || len(sink.durations["second"]) != 1 {
+		t.Errorf("Expected 3 and 1 durations, got %d and %d", len(sink.durations["first"]), len(sink.durations["second"]))
+	}
+	for _, d := range sink.durations["first"] {
+		if d < 5*time.Millisecond {
+			t.Errorf("Expected durations of at least 5ms, got %v", d)
+		}
+	}
+	if sink.failures != 1 {
+		t.Errorf("Expected 1 failure, got %d", sink.failures)
+	}
+	if sink.active["first"] != 0 || sink.pending["first"] != 0 {
+		t.Errorf("Expected idle gauges, got active %d pending %d", sink.active["first"], sink.pending["first"])
+	}
+}
+
+// safeBuffer is a bytes.Buffer safe for concurrent use by a logger.
+type safeBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
 func BenchmarkWorkerPoolSubmit(b *testing.B) {
 	pool := NewWorkerPool("bench", 8)
 	defer pool.Shutdown()
@@ -211,6 +1730,47 @@ func BenchmarkWorkerPoolSubmit(b *testing.B) {
 	}
 }
 
+// benchmarkSubmitBatch submits batches of 1000 tasks with submit while
+// the pool drains them.
+func benchmarkSubmitBatch(b *testing.B, submit func(pool *WorkerPool, tasks []*Task)) {
+	pool := NewWorkerPoolWithConfig("bench-batch", WorkerPoolConfig{Workers: 8, TaskBuffer: 1000})
+	defer pool.Shutdown()
+	pool.SetLogger(nil)
+
+	go func() {
+		for range pool.Results() {
+		}
+	}()
+
+	tasks := make([]*Task, 1000)
+	for i := range tasks {
+		tasks[i] = NewTask(fmt.Sprintf("task-%d", i), i, func(data interface{}) (interface{}, error) {
+			return data, nil
+		})
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		submit(pool, tasks)
+		_ = pool.WaitIdle(context.Background())
+	}
+}
+
+func BenchmarkWorkerPoolSubmitLoop(b *testing.B) {
+	benchmarkSubmitBatch(b, func(pool *WorkerPool, tasks []*Task) {
+		for _, task := range tasks {
+			_ = pool.Submit(task)
+		}
+	})
+}
+
+func BenchmarkWorkerPoolSubmitMany(b *testing.B) {
+	benchmarkSubmitBatch(b, func(pool *WorkerPool, tasks []*Task) {
+		_, _ = pool.SubmitMany(tasks)
+	})
+}
+
 func BenchmarkWorkerPoolThroughput(b *testing.B) {
 	pool := NewWorkerPool("throughput", 16)
 	defer pool.Shutdown()
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// ErrMemoryLimitExceeded is returned when an allocation would push a
+// LimitedAllocator past its configured limit.
+var ErrMemoryLimitExceeded = errors.New("arrow memory limit exceeded")
+
+// LimitedAllocator wraps an Arrow allocator and caps the total number of
+// outstanding bytes. The Arrow allocator interface cannot return errors, so
+// an allocation beyond the limit panics with an error wrapping
+// ErrMemoryLimitExceeded; Converter and IPCWriter recover it and return it
+// from the failing call.
+type LimitedAllocator struct {
+	mem   memory.Allocator
+	limit int64
+
+	// Atomic byte counters
+	current int64
+	peak    int64
+}
+
+// NewLimitedAllocator creates a LimitedAllocator backed by mem.
+// A nil mem uses the default Arrow allocator.
+func NewLimitedAllocator(mem memory.Allocator, limit int64) *LimitedAllocator {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	return &LimitedAllocator{
+		mem:   mem,
+		limit: limit,
+	}
+}
+
+// Allocate implements memory.Allocator.
+func (a *LimitedAllocator) Allocate(size int) []byte {
+	a.reserve(int64(size))
+	return a.mem.Allocate(size)
+}
+
+// Reallocate implements memory.Allocator.
+func (a *LimitedAllocator) Reallocate(size int, b []byte) []byte {
+	delta := int64(size - len(b))
+	if delta > 0 {
+		a.reserve(delta)
+	} else {
+		atomic.AddInt64(&a.current, delta)
+	}
+	return a.mem.Reallocate(size, b)
+}
+
+// Free implements memory.Allocator.
+func (a *LimitedAllocator) Free(b []byte) {
+	atomic.AddInt64(&a.current, -int64(len(b)))
+	a.mem.Free(b)
+}
+
+// reserve accounts for n more bytes, panicking if the limit would be exceeded.
+func (a *LimitedAllocator) reserve(n int64) {
+	for {
+		current := atomic.LoadInt64(&a.current)
+		next := current + n
+		if next > a.limit {
+			panic(fmt.Errorf("%w: requested %d bytes with %d of %d in use",
+				ErrMemoryLimitExceeded, n, current, a.limit))
+		}
+		if atomic.CompareAndSwapInt64(&a.current, current, next) {
+			a.updatePeak(next)
+			return
+		}
+	}
+}
+
+// updatePeak raises the peak watermark to n if it is higher.
+func (a *LimitedAllocator) updatePeak(n int64) {
+	for {
+		peak := atomic.LoadInt64(&a.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&a.peak, peak, n) {
+			return
+		}
+	}
+}
+
+// CurrentBytes returns the number of bytes currently allocated.
+func (a *LimitedAllocator) CurrentBytes() int64 {
+	return atomic.LoadInt64(&a.current)
+}
+
+// PeakBytes returns the highest number of bytes allocated at once.
+func (a *LimitedAllocator) PeakBytes() int64 {
+	return atomic.LoadInt64(&a.peak)
+}
+
+// Limit returns the configured byte limit.
+func (a *LimitedAllocator) Limit() int64 {
+	return a.limit
+}
+
+// recoverMemoryLimit turns a memory limit panic raised by LimitedAllocator
+// into an error assigned to err. Any other panic is re-raised.
+func recoverMemoryLimit(err *error) {
+	if r := recover(); r != nil {
+		if e, ok := r.(error); ok && errors.Is(e, ErrMemoryLimitExceeded) {
+			*err = e
+			return
+		}
+		panic(r)
+	}
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLimitedAllocatorRejectsOverLimit(t *testing.T) {
+	mem := NewLimitedAllocator(nil, 16*1024)
+	c := NewConverterWithAllocator(mem)
+
+	// Small batch fits within the limit
+	small := []EventJSON{{EntityID: "e1", Event: "created", Timestamp: 1.0}}
+	record, err := c.EventsToArrowBatch(small)
+	if err != nil {
+		t.Fatalf("Small batch failed: %v", err)
+	}
+	if mem.CurrentBytes() == 0 {
+		t.Error("Expected outstanding bytes while record is alive")
+	}
+	record.Release()
+
+	if mem.CurrentBytes() != 0 {
+		t.Errorf("Expected 0 bytes after release, got %d", mem.CurrentBytes())
+	}
+
+	// Large batch exceeds the limit and fails gracefully
+	large := make([]EventJSON, 1000)
+	for i := range large {
+		large[i] = EventJSON{
+			EntityID:  fmt.Sprintf("entity-%d", i),
+			Event:     "created",
+			Timestamp: float64(i),
+			Data:      []byte(strings.Repeat("x", 64)),
+		}
+	}
+
+	record, err = c.EventsToArrowBatch(large)
+	if !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Fatalf("Expected ErrMemoryLimitExceeded, got %v", err)
+	}
+	if record != nil {
+		t.Error("Expected nil record on failure")
+	}
+
+	if mem.CurrentBytes() != 0 {
+		t.Errorf("Expected partial allocations to be freed, got %d bytes", mem.CurrentBytes())
+	}
+	if peak := mem.PeakBytes(); peak == 0 || peak > mem.Limit() {
+		t.Errorf("Expected peak within (0, %d], got %d", mem.Limit(), peak)
+	}
+
+	// Allocator remains usable after a failure
+	record, err = c.EventsToArrowBatch(small)
+	if err != nil {
+		t.Fatalf("Small batch after failure: %v", err)
+	}
+	record.Release()
+}
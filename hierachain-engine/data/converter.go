@@ -1,15 +1,42 @@
 package data
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"time"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 )
 
+// ErrDuplicateDetailKey is returned in strict mode when an event's details
+// object contains the same key more than once.
+var ErrDuplicateDetailKey = errors.New("duplicate key in details map")
+
+// ErrEventDataTooLarge is returned when an event's Data exceeds the
+// converter's per-event limit under DataSizeReject.
+var ErrEventDataTooLarge = errors.New("event data exceeds size limit")
+
+// ErrSchemaMismatch is returned when a record passed to ArrowBatchToJSON or
+// WriteJSON does not have the event column layout.
+var ErrSchemaMismatch = errors.New("record does not match event schema")
+
+// DataSizePolicy controls how EventsToArrowBatch handles an event whose
+// Data exceeds the per-event limit.
+type DataSizePolicy int
+
+const (
+	// DataSizeReject fails the whole batch with ErrEventDataTooLarge
+	DataSizeReject DataSizePolicy = iota
+	// DataSizeTruncate keeps the first limit bytes of the Data
+	DataSizeTruncate
+)
+
 // EventJSON represents an event in JSON format for conversion.
 type EventJSON struct {
 	EntityID  string            `json:"entity_id"`
@@ -34,6 +61,13 @@ type TransactionJSON struct {
 type Converter struct {
 	allocator memory.Allocator
 	schema    *arrow.Schema
+
+	// strictDetails rejects duplicate details keys instead of keeping the last value
+	strictDetails bool
+
+	// Per-event Data size limit (0 means unlimited) and how to enforce it
+	maxDataSize    int
+	dataSizePolicy DataSizePolicy
 }
 
 // NewConverter creates a new Converter with the default memory allocator.
@@ -52,18 +86,52 @@ func NewConverterWithSchema(schema *arrow.Schema) *Converter {
 	}
 }
 
+// NewConverterWithAllocator creates a Converter that allocates from mem.
+// Pass a LimitedAllocator to bound the memory used by conversions.
+func NewConverterWithAllocator(mem memory.Allocator) *Converter {
+	return &Converter{
+		allocator: mem,
+		schema:    EventSchema(),
+	}
+}
+
+// NewConverterWithTimestampUnit creates a Converter whose records store
+// timestamps as Arrow timestamps in unit (see EventSchemaWithTimestamp).
+// EventJSON timestamps stay fractional Unix seconds in both directions.
+func NewConverterWithTimestampUnit(unit arrow.TimeUnit) *Converter {
+	return NewConverterWithSchema(EventSchemaWithTimestamp(unit))
+}
+
 // EventsToArrowBatch converts a slice of EventJSON to Arrow RecordBatch.
-func (c *Converter) EventsToArrowBatch(events []EventJSON) (arrow.Record, error) {
+// It returns an error wrapping ErrMemoryLimitExceeded if the converter's
+// allocator runs out of budget, or wrapping ErrEventDataTooLarge if an
+// event's Data is over the limit set with SetMaxEventDataSize.
+func (c *Converter) EventsToArrowBatch(events []EventJSON) (record arrow.Record, err error) {
 	if len(events) == 0 {
 		return nil, errors.New("empty events slice")
 	}
 
+	// Reject oversized events before allocating anything
+	if c.maxDataSize > 0 && c.dataSizePolicy == DataSizeReject {
+		for i, event := range events {
+			if len(event.Data) > c.maxDataSize {
+				return nil, fmt.Errorf("%w: row %d (entity %q) has %d bytes, limit is %d",
+					ErrEventDataTooLarge, i, event.EntityID, len(event.Data), c.maxDataSize)
+			}
+		}
+	}
+
+	defer recoverMemoryLimit(&err)
+
 	builder := array.NewRecordBuilder(c.allocator, c.schema)
 	defer builder.Release()
 
 	entityIDBuilder := builder.Field(0).(*array.StringBuilder)
 	eventBuilder := builder.Field(1).(*array.StringBuilder)
-	timestampBuilder := builder.Field(2).(*array.Float64Builder)
+	appendTimestamp, err := timestampAppender(builder.Field(2))
+	if err != nil {
+		return nil, err
+	}
 	detailsBuilder := builder.Field(3).(*array.MapBuilder)
 	dataBuilder := builder.Field(4).(*array.BinaryBuilder)
 
@@ -73,7 +141,7 @@ func (c *Converter) EventsToArrowBatch(events []EventJSON) (arrow.Record, error)
 	for _, event := range events {
 		entityIDBuilder.Append(event.EntityID)
 		eventBuilder.Append(event.Event)
-		timestampBuilder.Append(event.Timestamp)
+		appendTimestamp(event.Timestamp)
 
 		if len(event.Details) > 0 {
 			detailsBuilder.Append(true)
@@ -86,7 +154,11 @@ func (c *Converter) EventsToArrowBatch(events []EventJSON) (arrow.Record, error)
 		}
 
 		if event.Data != nil {
-			dataBuilder.Append(event.Data)
+			data := event.Data
+			if c.maxDataSize > 0 && len(data) > c.maxDataSize {
+				data = data[:c.maxDataSize] // DataSizeTruncate
+			}
+			dataBuilder.Append(data)
 		} else {
 			dataBuilder.AppendNull()
 		}
@@ -95,91 +167,402 @@ func (c *Converter) EventsToArrowBatch(events []EventJSON) (arrow.Record, error)
 	return builder.NewRecord(), nil
 }
 
+// timestampAppender returns a function appending fractional Unix seconds to
+// a Float64 or Timestamp column builder.
+func timestampAppender(b array.Builder) (func(float64), error) {
+	switch b := b.(type) {
+	case *array.Float64Builder:
+		return b.Append, nil
+	case *array.TimestampBuilder:
+		perSecond := unitsPerSecond(b.Type().(*arrow.TimestampType).Unit)
+		return func(ts float64) {
+			b.Append(arrow.Timestamp(math.Round(ts * perSecond)))
+		}, nil
+	default:
+		return nil, errors.New("timestamp column must be Float64 or Timestamp")
+	}
+}
+
+// unitsPerSecond returns how many of unit make up one second.
+func unitsPerSecond(unit arrow.TimeUnit) float64 {
+	return float64(time.Second / unit.Multiplier())
+}
+
 // JSONToArrowBatch converts JSON bytes to Arrow RecordBatch.
 func (c *Converter) JSONToArrowBatch(jsonData []byte) (arrow.Record, error) {
 	var events []EventJSON
 	if err := json.Unmarshal(jsonData, &events); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
+
+	// encoding/json silently keeps the last value for repeated keys,
+	// so strict mode has to inspect the raw details objects.
+	if c.strictDetails {
+		if err := checkDetailKeys(jsonData); err != nil {
+			return nil, err
+		}
+	}
+
 	return c.EventsToArrowBatch(events)
 }
 
+// DefaultStreamBatchRows is the batch size StreamJSONToArrow uses when
+// batchRows is not positive.
+const DefaultStreamBatchRows = 1024
+
+// StreamJSONToArrow decodes a JSON array of events from r one element at a
+// time and emits a record for every batchRows events, plus a final shorter
+// record for any remainder. Unlike JSONToArrowBatch the whole input is never
+// held in memory, only the current batch.
+//
+// Each record sent on the first channel is owned by the caller, who must
+// Release it. The caller must also drain that channel until it is closed;
+// the error channel then yields at most one error. If the input is not a
+// JSON array, or any element fails to decode or convert, the error is sent
+// and both channels are closed without emitting further records.
+func (c *Converter) StreamJSONToArrow(r io.Reader, batchRows int) (<-chan arrow.Record, <-chan error) {
+	if batchRows <= 0 {
+		batchRows = DefaultStreamBatchRows
+	}
+
+	records := make(chan arrow.Record)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(records)
+
+		if err := c.streamEvents(r, batchRows, records); err != nil {
+			errs <- err
+		}
+	}()
+
+	return records, errs
+}
+
+// streamEvents is the body of StreamJSONToArrow's decoding goroutine.
+func (c *Converter) streamEvents(r io.Reader, batchRows int, records chan<- arrow.Record) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read JSON: %w", err)
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("failed to unmarshal JSON: expected array, got %v", tok)
+	}
+
+	flush := func(batch []EventJSON) error {
+		record, err := c.EventsToArrowBatch(batch)
+		if err != nil {
+			return err
+		}
+		records <- record
+		return nil
+	}
+
+	batch := make([]EventJSON, 0, batchRows)
+	for i := 0; dec.More(); i++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("failed to unmarshal JSON: event %d: %w", i, err)
+		}
+
+		var event EventJSON
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal JSON: event %d: %w", i, err)
+		}
+		if c.strictDetails {
+			var details struct {
+				Details json.RawMessage `json:"details"`
+			}
+			if err := json.Unmarshal(raw, &details); err == nil {
+				if key, ok := duplicateKey(details.Details); ok {
+					return fmt.Errorf("%w: event %d, key %q", ErrDuplicateDetailKey, i, key)
+				}
+			}
+		}
+
+		batch = append(batch, event)
+		if len(batch) == batchRows {
+			if err := flush(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("failed to read JSON: %w", err)
+	}
+
+	if len(batch) > 0 {
+		return flush(batch)
+	}
+	return nil
+}
+
+// SetStrictDetailKeys controls how duplicate keys in a details object are handled.
+// When strict, JSONToArrowBatch returns ErrDuplicateDetailKey; otherwise the
+// last value wins. Either way the emitted map column has unique keys.
+func (c *Converter) SetStrictDetailKeys(strict bool) {
+	c.strictDetails = strict
+}
+
+// SetMaxEventDataSize caps the Data of each event converted by
+// EventsToArrowBatch at limit bytes, enforced according to policy.
+// A limit of zero or less removes the cap. This bounds a single event;
+// use a LimitedAllocator to bound a whole batch.
+func (c *Converter) SetMaxEventDataSize(limit int, policy DataSizePolicy) {
+	if limit < 0 {
+		limit = 0
+	}
+	c.maxDataSize = limit
+	c.dataSizePolicy = policy
+}
+
+// checkDetailKeys scans the raw details object of every event for repeated keys.
+func checkDetailKeys(jsonData []byte) error {
+	var raw []struct {
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	for i, event := range raw {
+		if key, ok := duplicateKey(event.Details); ok {
+			return fmt.Errorf("%w: event %d, key %q", ErrDuplicateDetailKey, i, key)
+		}
+	}
+	return nil
+}
+
+// duplicateKey returns the first key that appears more than once in a JSON object.
+func duplicateKey(raw json.RawMessage) (string, bool) {
+	if len(raw) == 0 {
+		return "", false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return "", false
+	}
+
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return "", false
+		}
+		if seen[key] {
+			return key, true
+		}
+		seen[key] = true
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
 // ArrowBatchToJSON converts an Arrow RecordBatch back to JSON bytes.
+// A record without the event column layout fails with ErrSchemaMismatch.
 func (c *Converter) ArrowBatchToJSON(record arrow.Record) ([]byte, error) {
 	if record == nil || record.NumRows() == 0 {
 		return []byte("[]"), nil
 	}
 
+	cols, err := eventColumnsOf(record)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]EventJSON, record.NumRows())
+	for i := range events {
+		if events[i], err = cols.row(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(events)
+}
+
+// WriteJSON streams an Arrow RecordBatch to w as a JSON array, encoding one
+// event at a time instead of buffering the whole document. With pretty set
+// the output matches json.MarshalIndent with two-space indentation.
+func (c *Converter) WriteJSON(w io.Writer, record arrow.Record, pretty bool) error {
+	if record == nil || record.NumRows() == 0 {
+		_, err := io.WriteString(w, "[]")
+		return err
+	}
+
+	cols, err := eventColumnsOf(record)
+	if err != nil {
+		return err
+	}
+
+	prefix, sep, suffix := "[", ",", "]"
+	if pretty {
+		prefix, sep, suffix = "[\n  ", ",\n  ", "\n]"
+	}
+
+	if _, err := io.WriteString(w, prefix); err != nil {
+		return err
+	}
+
+	for i := 0; i < int(record.NumRows()); i++ {
+		event, err := cols.row(i)
+		if err != nil {
+			return err
+		}
+
+		var encoded []byte
+		if pretty {
+			encoded, err = json.MarshalIndent(event, "  ", "  ")
+		} else {
+			encoded, err = json.Marshal(event)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to marshal event %d: %w", i, err)
+		}
+
+		if i > 0 {
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, suffix)
+	return err
+}
+
+// eventColumns holds the typed columns of an event record.
+type eventColumns struct {
+	entityIDs  *array.String
+	events     *array.String
+	timestamps arrow.Array // Float64 seconds or Timestamp
+	details    *array.Map
+	data       *array.Binary
+}
+
+// eventColumnsOf validates the layout of an event record and returns its
+// columns. Records may come from another process, so every column is type
+// checked and a mismatch is reported as ErrSchemaMismatch rather than left
+// to panic on a type assertion later.
+func eventColumnsOf(record arrow.Record) (*eventColumns, error) {
 	// Validate column count to prevent index out of bounds
 	if record.NumCols() < 5 {
-		return nil, fmt.Errorf("invalid record: expected at least 5 columns, got %d", record.NumCols())
+		return nil, fmt.Errorf("%w: expected at least 5 columns, got %d", ErrSchemaMismatch, record.NumCols())
 	}
 
-	// Safe type assertions with error checking
 	entityIDCol, ok := record.Column(0).(*array.String)
 	if !ok {
-		return nil, errors.New("column 0 (entity_id) is not a String array")
+		return nil, columnTypeError(0, "entity_id", "String", record.Column(0))
 	}
 	eventCol, ok := record.Column(1).(*array.String)
 	if !ok {
-		return nil, errors.New("column 1 (event) is not a String array")
+		return nil, columnTypeError(1, "event", "String", record.Column(1))
 	}
-	timestampCol, ok := record.Column(2).(*array.Float64)
-	if !ok {
-		return nil, errors.New("column 2 (timestamp) is not a Float64 array")
+	timestampCol := record.Column(2)
+	switch timestampCol.(type) {
+	case *array.Float64, *array.Timestamp:
+	default:
+		return nil, columnTypeError(2, "timestamp", "Float64 or Timestamp", timestampCol)
 	}
 	detailsCol, ok := record.Column(3).(*array.Map)
 	if !ok {
-		return nil, errors.New("column 3 (details) is not a Map array")
+		return nil, columnTypeError(3, "details", "Map", record.Column(3))
 	}
 	dataCol, ok := record.Column(4).(*array.Binary)
 	if !ok {
-		return nil, errors.New("column 4 (data) is not a Binary array")
+		return nil, columnTypeError(4, "data", "Binary", record.Column(4))
 	}
 
-	events := make([]EventJSON, record.NumRows())
+	return &eventColumns{
+		entityIDs:  entityIDCol,
+		events:     eventCol,
+		timestamps: timestampCol,
+		details:    detailsCol,
+		data:       dataCol,
+	}, nil
+}
 
-	for i := int64(0); i < record.NumRows(); i++ {
-		idx := int(i)
+// columnTypeError reports that column idx holds col instead of the expected array type.
+func columnTypeError(idx int, name, expected string, col arrow.Array) error {
+	return fmt.Errorf("%w: column %d (%s): expected %s, got %T", ErrSchemaMismatch, idx, name, expected, col)
+}
 
-		// Bounds check for each column access
-		if idx >= entityIDCol.Len() || idx >= eventCol.Len() || idx >= timestampCol.Len() {
-			return nil, fmt.Errorf("index %d out of bounds for column data", idx)
-		}
+// row builds the EventJSON for row idx.
+func (c *eventColumns) row(idx int) (EventJSON, error) {
+	// Bounds check for each column access
+	if idx >= c.entityIDs.Len() || idx >= c.events.Len() || idx >= c.timestamps.Len() {
+		return EventJSON{}, fmt.Errorf("index %d out of bounds for column data", idx)
+	}
 
-		events[idx] = EventJSON{
-			EntityID:  entityIDCol.Value(idx),
-			Event:     eventCol.Value(idx),
-			Timestamp: timestampCol.Value(idx),
-		}
+	event := EventJSON{
+		EntityID:  c.entityIDs.Value(idx),
+		Event:     c.events.Value(idx),
+		Timestamp: c.timestampAt(idx),
+	}
 
-		if idx < detailsCol.Len() && !detailsCol.IsNull(idx) {
-			events[idx].Details = extractMapValues(detailsCol, idx)
-		}
+	if idx < c.details.Len() && !c.details.IsNull(idx) {
+		event.Details = extractMapValues(c.details, idx)
+	}
 
-		if idx < dataCol.Len() && !dataCol.IsNull(idx) {
-			events[idx].Data = dataCol.Value(idx)
-		}
+	if idx < c.data.Len() && !c.data.IsNull(idx) {
+		event.Data = c.data.Value(idx)
 	}
 
-	return json.Marshal(events)
+	return event, nil
+}
+
+// timestampAt returns the timestamp of row idx as fractional Unix seconds.
+func (c *eventColumns) timestampAt(idx int) float64 {
+	switch col := c.timestamps.(type) {
+	case *array.Float64:
+		return col.Value(idx)
+	case *array.Timestamp:
+		unit := col.DataType().(*arrow.TimestampType).Unit
+		return float64(col.Value(idx)) / unitsPerSecond(unit)
+	default:
+		return 0
+	}
 }
 
 // extractMapValues extracts key-value pairs from a Map column at the given index.
+// Entries with a null key or value are skipped rather than read as empty
+// strings, and a map whose keys or items are not strings yields no entries,
+// since records from other producers may not follow the schema exactly.
 func extractMapValues(mapCol *array.Map, idx int) map[string]string {
 	result := make(map[string]string)
 
-	offsets := mapCol.Offsets()
-	start := offsets[idx]
-	end := offsets[idx+1]
-
-	keys := mapCol.Keys().(*array.String)
-	values := mapCol.Items().(*array.String)
+	keys, ok := mapCol.Keys().(*array.String)
+	if !ok {
+		return result
+	}
+	values, ok := mapCol.Items().(*array.String)
+	if !ok {
+		return result
+	}
 
-	for j := start; j < end; j++ {
-		key := keys.Value(int(j))
-		value := values.Value(int(j))
-		result[key] = value
+	start, end := mapCol.ValueOffsets(idx)
+	for j := int(start); j < int(end); j++ {
+		if keys.IsNull(j) || values.IsNull(j) {
+			continue
+		}
+		result[keys.Value(j)] = values.Value(j)
 	}
 
 	return result
@@ -214,3 +597,27 @@ func ValidateSchema(record arrow.Record, expected *arrow.Schema) error {
 
 	return nil
 }
+
+// SliceRecord returns a zero-copy view of length rows of record starting at
+// offset, for paginating a record without re-converting it. A length past
+// the end of the record is clamped to the remaining rows. The slice holds
+// its own reference to the underlying buffers, so the caller must Release
+// it independently of record.
+func SliceRecord(record arrow.Record, offset, length int64) (arrow.Record, error) {
+	if record == nil {
+		return nil, errors.New("record is nil")
+	}
+	if offset < 0 || offset > record.NumRows() {
+		return nil, fmt.Errorf("offset %d out of range [0, %d]", offset, record.NumRows())
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("negative slice length %d", length)
+	}
+
+	end := offset + length
+	if end > record.NumRows() || end < offset {
+		end = record.NumRows()
+	}
+
+	return record.NewSlice(offset, end), nil
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -68,3 +70,65 @@ func FuzzEventsToArrowBatch(f *testing.F) {
 		}
 	})
 }
+
+// FuzzConverterRoundTripOracle checks that JSON -> Arrow -> JSON preserves every
+// event, using encoding/json as the reference decoder.
+// Run with: go test -fuzz=FuzzConverterRoundTripOracle -fuzztime=30s ./hierachain-engine/data/
+func FuzzConverterRoundTripOracle(f *testing.F) {
+	f.Add([]byte(`[{"entity_id":"test","event":"create","timestamp":1234567890.0}]`))
+	f.Add([]byte(`[{"entity_id":null,"event":null,"timestamp":null,"details":null,"data":null}]`))
+	f.Add([]byte(`[{"entity_id":"a","event":"b","timestamp":1,"details":{}}]`))
+	f.Add([]byte(`[{"entity_id":"a","event":"b","timestamp":1,"details":{"":""},"data":""}]`))
+	f.Add([]byte(`[{"entity_id":"\u00e9\u4e2d\ud83d\ude00","event":"\u0000","timestamp":-0.0,"details":{"\u65e5":"\u672c"}}]`))
+	f.Add([]byte(`[{"entity_id":"x","event":"y","timestamp":1.7976931348623157e308,"data":"AAEC/w=="}]`))
+	f.Add([]byte(`[{"entity_id":"x","event":"y","timestamp":5e-324},{"entity_id":"z","event":"w","timestamp":-1e300}]`))
+	f.Add([]byte(`[{"entity_id":"x","event":"y","timestamp":1,"details":{"k":"1","k":"2"}}]`))
+	f.Add([]byte(`[null,{}]`))
+
+	c := NewConverter()
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		record, err := c.JSONToArrowBatch(input)
+		if err != nil {
+			return // Rejected inputs are covered by FuzzJSONToArrowBatch
+		}
+		defer record.Release()
+
+		var expected []EventJSON
+		if err := json.Unmarshal(input, &expected); err != nil {
+			t.Fatalf("Converter accepted input the reference decoder rejects: %v", err)
+		}
+
+		output, err := c.ArrowBatchToJSON(record)
+		if err != nil {
+			t.Fatalf("ArrowBatchToJSON failed: %v", err)
+		}
+
+		var actual []EventJSON
+		if err := json.Unmarshal(output, &actual); err != nil {
+			t.Fatalf("Round-trip output is not valid JSON: %v", err)
+		}
+
+		expected = normalizeEvents(expected)
+		actual = normalizeEvents(actual)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("Round-trip mismatch:\ninput:    %s\nexpected: %+v\nactual:   %+v", input, expected, actual)
+		}
+	})
+}
+
+// normalizeEvents applies the converter's documented null/empty rules:
+// empty details maps and empty data payloads are emitted as null.
+func normalizeEvents(events []EventJSON) []EventJSON {
+	out := make([]EventJSON, len(events))
+	for i, event := range events {
+		if len(event.Details) == 0 {
+			event.Details = nil
+		}
+		if len(event.Data) == 0 {
+			event.Data = nil
+		}
+		out[i] = event
+	}
+	return out
+}
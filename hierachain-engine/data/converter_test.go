@@ -0,0 +1,440 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestConverterDuplicateDetailKeys(t *testing.T) {
+	input := []byte(`[{"entity_id":"e1","event":"created","timestamp":1.0,"details":{"a":"1","b":"2","a":"3"}}]`)
+
+	// Strict mode rejects the batch
+	strict := NewConverter()
+	strict.SetStrictDetailKeys(true)
+	if _, err := strict.JSONToArrowBatch(input); !errors.Is(err, ErrDuplicateDetailKey) {
+		t.Fatalf("Expected ErrDuplicateDetailKey, got %v", err)
+	}
+
+	// Lenient mode keeps the last value
+	lenient := NewConverter()
+	record, err := lenient.JSONToArrowBatch(input)
+	if err != nil {
+		t.Fatalf("Lenient conversion failed: %v", err)
+	}
+	defer record.Release()
+
+	details, ok := record.Column(3).(*array.Map)
+	if !ok {
+		t.Fatal("Column 3 is not a Map array")
+	}
+
+	offsets := details.Offsets()
+	keys := details.Keys().(*array.String)
+	values := details.Items().(*array.String)
+
+	seen := make(map[string]string)
+	for j := offsets[0]; j < offsets[1]; j++ {
+		key := keys.Value(int(j))
+		if _, dup := seen[key]; dup {
+			t.Errorf("Duplicate key %q in map column", key)
+		}
+		seen[key] = values.Value(int(j))
+	}
+
+	if len(seen) != 2 {
+		t.Errorf("Expected 2 keys, got %d", len(seen))
+	}
+	if seen["a"] != "3" {
+		t.Errorf("Expected last value '3' for key 'a', got %q", seen["a"])
+	}
+}
+
+func TestConverterNullDetailValues(t *testing.T) {
+	// Build the record directly, as a non-Go producer might
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), EventSchema())
+	defer builder.Release()
+
+	builder.Field(0).(*array.StringBuilder).Append("e1")
+	builder.Field(1).(*array.StringBuilder).Append("created")
+	builder.Field(2).(*array.Float64Builder).Append(1.0)
+	details := builder.Field(3).(*array.MapBuilder)
+	details.Append(true)
+	details.KeyBuilder().(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	items := details.ItemBuilder().(*array.StringBuilder)
+	items.AppendNull()
+	items.Append("2")
+	builder.Field(4).(*array.BinaryBuilder).AppendNull()
+
+	record := builder.NewRecord()
+	defer record.Release()
+
+	output, err := NewConverter().ArrowBatchToJSON(record)
+	if err != nil {
+		t.Fatalf("ArrowBatchToJSON failed: %v", err)
+	}
+
+	var events []EventJSON
+	if err := json.Unmarshal(output, &events); err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if _, ok := events[0].Details["a"]; ok {
+		t.Errorf("Expected null value for key 'a' to be skipped, got %v", events[0].Details)
+	}
+	if events[0].Details["b"] != "2" {
+		t.Errorf("Expected value '2' for key 'b', got %v", events[0].Details)
+	}
+}
+
+func TestConverterStrictDetailKeysUnique(t *testing.T) {
+	c := NewConverter()
+	c.SetStrictDetailKeys(true)
+
+	input := []byte(`[{"entity_id":"e1","event":"created","timestamp":1.0,"details":{"a":"1","b":"2"}}]`)
+	record, err := c.JSONToArrowBatch(input)
+	if err != nil {
+		t.Fatalf("Strict conversion of unique keys failed: %v", err)
+	}
+	defer record.Release()
+
+	if record.NumRows() != 1 {
+		t.Errorf("Expected 1 row, got %d", record.NumRows())
+	}
+}
+
+func TestConverterMaxEventDataSize(t *testing.T) {
+	events := []EventJSON{
+		{EntityID: "e1", Event: "created", Timestamp: 1.0, Data: []byte("small")},
+		{EntityID: "e2", Event: "created", Timestamp: 2.0, Data: bytes.Repeat([]byte("x"), 64)},
+		{EntityID: "e3", Event: "created", Timestamp: 3.0},
+	}
+
+	// Reject mode fails the batch, naming the offending row
+	reject := NewConverter()
+	reject.SetMaxEventDataSize(16, DataSizeReject)
+	_, err := reject.EventsToArrowBatch(events)
+	if !errors.Is(err, ErrEventDataTooLarge) {
+		t.Fatalf("Expected ErrEventDataTooLarge, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "row 1") || !strings.Contains(err.Error(), `"e2"`) {
+		t.Errorf("Expected error to name row 1 and entity e2, got %v", err)
+	}
+
+	// Truncate mode keeps the first limit bytes
+	truncate := NewConverter()
+	truncate.SetMaxEventDataSize(16, DataSizeTruncate)
+	record, err := truncate.EventsToArrowBatch(events)
+	if err != nil {
+		t.Fatalf("Truncating conversion failed: %v", err)
+	}
+	defer record.Release()
+
+	data := record.Column(4).(*array.Binary)
+	if string(data.Value(0)) != "small" {
+		t.Errorf("Expected data under the limit unchanged, got %q", data.Value(0))
+	}
+	if len(data.Value(1)) != 16 {
+		t.Errorf("Expected data truncated to 16 bytes, got %d", len(data.Value(1)))
+	}
+	if !data.IsNull(2) {
+		t.Error("Expected null data to stay null")
+	}
+
+	// Removing the cap accepts the batch unchanged
+	reject.SetMaxEventDataSize(0, DataSizeReject)
+	record2, err := reject.EventsToArrowBatch(events)
+	if err != nil {
+		t.Fatalf("Uncapped conversion failed: %v", err)
+	}
+	defer record2.Release()
+	if n := len(record2.Column(4).(*array.Binary).Value(1)); n != 64 {
+		t.Errorf("Expected 64 bytes without a cap, got %d", n)
+	}
+}
+
+func TestConverterWriteJSON(t *testing.T) {
+	c := NewConverter()
+	events := []EventJSON{
+		{EntityID: "e1", Event: "created", Timestamp: 1.0, Details: map[string]string{"k": "v"}},
+		{EntityID: "e2", Event: "updated", Timestamp: 2.0, Data: []byte("payload")},
+	}
+
+	record, err := c.EventsToArrowBatch(events)
+	if err != nil {
+		t.Fatalf("EventsToArrowBatch failed: %v", err)
+	}
+	defer record.Release()
+
+	// Compact streaming output matches ArrowBatchToJSON
+	expected, err := c.ArrowBatchToJSON(record)
+	if err != nil {
+		t.Fatalf("ArrowBatchToJSON failed: %v", err)
+	}
+
+	var compact bytes.Buffer
+	if err := c.WriteJSON(&compact, record, false); err != nil {
+		t.Fatalf("WriteJSON compact failed: %v", err)
+	}
+	if compact.String() != string(expected) {
+		t.Errorf("Compact output mismatch:\n got: %s\nwant: %s", compact.String(), expected)
+	}
+
+	// Pretty output matches json.MarshalIndent of the same events
+	var decoded []EventJSON
+	if err := json.Unmarshal(expected, &decoded); err != nil {
+		t.Fatalf("Failed to decode compact output: %v", err)
+	}
+	want, err := json.MarshalIndent(decoded, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent failed: %v", err)
+	}
+
+	var pretty bytes.Buffer
+	if err := c.WriteJSON(&pretty, record, true); err != nil {
+		t.Fatalf("WriteJSON pretty failed: %v", err)
+	}
+	if pretty.String() != string(want) {
+		t.Errorf("Pretty output mismatch:\n got: %s\nwant: %s", pretty.String(), want)
+	}
+
+	// Empty record
+	var empty bytes.Buffer
+	if err := c.WriteJSON(&empty, nil, true); err != nil {
+		t.Fatalf("WriteJSON empty failed: %v", err)
+	}
+	if empty.String() != "[]" {
+		t.Errorf("Expected [] for nil record, got %s", empty.String())
+	}
+}
+
+// failingWriter returns an error once more than limit bytes are written.
+type failingWriter struct {
+	written int
+	limit   int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		return 0, errors.New("write limit reached")
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
+func TestConverterWriteJSONPropagatesWriterError(t *testing.T) {
+	c := NewConverter()
+	events := make([]EventJSON, 50)
+	for i := range events {
+		events[i] = EventJSON{EntityID: "e", Event: "created", Timestamp: float64(i)}
+	}
+
+	record, err := c.EventsToArrowBatch(events)
+	if err != nil {
+		t.Fatalf("EventsToArrowBatch failed: %v", err)
+	}
+	defer record.Release()
+
+	w := &failingWriter{limit: 256}
+	if err := c.WriteJSON(w, record, false); err == nil {
+		t.Fatal("Expected writer error to be returned")
+	}
+	if w.written == 0 {
+		t.Error("Expected partial output before the writer failed")
+	}
+}
+
+func TestSliceRecord(t *testing.T) {
+	c := NewConverter()
+	events := make([]EventJSON, 5)
+	for i := range events {
+		events[i] = EventJSON{EntityID: fmt.Sprintf("e%d", i), Event: "created", Timestamp: float64(i)}
+	}
+
+	record, err := c.EventsToArrowBatch(events)
+	if err != nil {
+		t.Fatalf("EventsToArrowBatch failed: %v", err)
+	}
+	defer record.Release()
+
+	tests := []struct {
+		name          string
+		offset        int64
+		length        int64
+		expectedRows  int64
+		expectedFirst string
+	}{
+		{"first page", 0, 2, 2, "e0"},
+		{"middle page", 2, 2, 2, "e2"},
+		{"last row", 4, 1, 1, "e4"},
+		{"clamped last page", 3, 10, 2, "e3"},
+		{"empty at end", 5, 2, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slice, err := SliceRecord(record, tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("SliceRecord failed: %v", err)
+			}
+			defer slice.Release()
+
+			if slice.NumRows() != tt.expectedRows {
+				t.Errorf("Expected %d rows, got %d", tt.expectedRows, slice.NumRows())
+			}
+			if tt.expectedRows > 0 {
+				first := slice.Column(0).(*array.String).Value(0)
+				if first != tt.expectedFirst {
+					t.Errorf("Expected first entity %s, got %s", tt.expectedFirst, first)
+				}
+			}
+		})
+	}
+
+	for _, bad := range [][2]int64{{-1, 1}, {6, 1}, {0, -1}} {
+		if _, err := SliceRecord(record, bad[0], bad[1]); err == nil {
+			t.Errorf("Expected error for offset %d length %d", bad[0], bad[1])
+		}
+	}
+
+	// A slice stays valid after its parent is released
+	parent, err := c.EventsToArrowBatch(events)
+	if err != nil {
+		t.Fatalf("EventsToArrowBatch failed: %v", err)
+	}
+	slice, err := SliceRecord(parent, 1, 1)
+	if err != nil {
+		t.Fatalf("SliceRecord failed: %v", err)
+	}
+	parent.Release()
+	if got := slice.Column(0).(*array.String).Value(0); got != "e1" {
+		t.Errorf("Expected e1, got %s", got)
+	}
+	slice.Release()
+}
+
+func TestConverterStreamJSONToArrow(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < 7; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"entity_id":"e%d","event":"created","timestamp":%d.0}`, i, i)
+	}
+	sb.WriteString("]")
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	records, errs := NewConverterWithAllocator(mem).StreamJSONToArrow(strings.NewReader(sb.String()), 3)
+
+	var sizes []int64
+	var entities []string
+	for record := range records {
+		sizes = append(sizes, record.NumRows())
+		col := record.Column(0).(*array.String)
+		for i := 0; i < col.Len(); i++ {
+			entities = append(entities, col.Value(i))
+		}
+		record.Release()
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("StreamJSONToArrow failed: %v", err)
+	}
+
+	if fmt.Sprint(sizes) != "[3 3 1]" {
+		t.Errorf("Expected batches [3 3 1], got %v", sizes)
+	}
+	if len(entities) != 7 || entities[0] != "e0" || entities[6] != "e6" {
+		t.Errorf("Expected entities e0..e6 in order, got %v", entities)
+	}
+}
+
+func TestConverterStreamJSONToArrowErrors(t *testing.T) {
+	drain := func(c *Converter, input string) (int, error) {
+		records, errs := c.StreamJSONToArrow(strings.NewReader(input), 2)
+		n := 0
+		for record := range records {
+			n++
+			record.Release()
+		}
+		return n, <-errs
+	}
+
+	// Top-level object instead of array
+	if n, err := drain(NewConverter(), `{"entity_id":"e1"}`); err == nil || n != 0 {
+		t.Errorf("Expected error and no records for non-array input, got %d records, err %v", n, err)
+	}
+
+	// Malformed element after a full batch: the first batch is still delivered
+	input := `[{"entity_id":"e1","timestamp":1},{"entity_id":"e2","timestamp":2},{"entity_id":3}]`
+	if n, err := drain(NewConverter(), input); err == nil || n != 1 {
+		t.Errorf("Expected error after 1 record, got %d records, err %v", n, err)
+	}
+
+	// Strict mode still catches duplicate details keys
+	strict := NewConverter()
+	strict.SetStrictDetailKeys(true)
+	input = `[{"entity_id":"e1","timestamp":1,"details":{"a":"1","a":"2"}}]`
+	if _, err := drain(strict, input); !errors.Is(err, ErrDuplicateDetailKey) {
+		t.Errorf("Expected ErrDuplicateDetailKey, got %v", err)
+	}
+
+	// Empty array is not an error
+	if n, err := drain(NewConverter(), `[]`); err != nil || n != 0 {
+		t.Errorf("Expected no records and no error for empty array, got %d records, err %v", n, err)
+	}
+}
+
+func TestConverterArrowBatchToJSONSchemaMismatch(t *testing.T) {
+	converter := NewConverter()
+
+	// entity_id as Int64 instead of String
+	fields := EventSchema().Fields()
+	fields[0].Type = arrow.PrimitiveTypes.Int64
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), arrow.NewSchema(fields, nil))
+	defer builder.Release()
+
+	builder.Field(0).(*array.Int64Builder).Append(42)
+	builder.Field(1).(*array.StringBuilder).Append("created")
+	builder.Field(2).(*array.Float64Builder).Append(1.0)
+	builder.Field(3).(*array.MapBuilder).AppendNull()
+	builder.Field(4).(*array.BinaryBuilder).AppendNull()
+	record := builder.NewRecord()
+	defer record.Release()
+
+	_, err := converter.ArrowBatchToJSON(record)
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("Expected ErrSchemaMismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "column 0") || !strings.Contains(err.Error(), "*array.Int64") {
+		t.Errorf("Expected error to name column 0 and its type, got %q", err)
+	}
+
+	if err := converter.WriteJSON(&bytes.Buffer{}, record, false); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("Expected ErrSchemaMismatch from WriteJSON, got %v", err)
+	}
+
+	// Too few columns
+	short := arrow.NewSchema(fields[:2], nil)
+	shortBuilder := array.NewRecordBuilder(memory.NewGoAllocator(), short)
+	defer shortBuilder.Release()
+	shortBuilder.Field(0).(*array.Int64Builder).Append(1)
+	shortBuilder.Field(1).(*array.StringBuilder).Append("created")
+	shortRecord := shortBuilder.NewRecord()
+	defer shortRecord.Release()
+
+	if _, err := converter.ArrowBatchToJSON(shortRecord); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("Expected ErrSchemaMismatch for short record, got %v", err)
+	}
+}
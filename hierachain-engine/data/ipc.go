@@ -3,16 +3,31 @@ package data
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/ipc"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 )
 
+// ErrUnsupportedCodec is returned by NewIPCWriterWithCompression for an
+// unknown codec name.
+var ErrUnsupportedCodec = errors.New("unsupported IPC compression codec")
+
+// IPC body compression codecs accepted by NewIPCWriterWithCompression.
+const (
+	CodecZstd = "zstd"
+	CodecLZ4  = "lz4"
+)
+
 // IPCWriter writes Arrow RecordBatches to IPC format.
 type IPCWriter struct {
 	allocator memory.Allocator
+
+	// compression is the ipc.WithZstd/WithLZ4 option, or nil for uncompressed output
+	compression ipc.Option
 }
 
 // NewIPCWriter creates a new IPCWriter.
@@ -22,11 +37,47 @@ func NewIPCWriter() *IPCWriter {
 	}
 }
 
+// NewIPCWriterWithAllocator creates an IPCWriter that allocates from mem.
+func NewIPCWriterWithAllocator(mem memory.Allocator) *IPCWriter {
+	return &IPCWriter{
+		allocator: mem,
+	}
+}
+
+// NewIPCWriterWithCompression creates an IPCWriter whose output compresses
+// record bodies with codec, either CodecZstd or CodecLZ4. The Deserialize
+// methods of any IPCWriter read compressed and uncompressed streams alike.
+func NewIPCWriterWithCompression(codec string) (*IPCWriter, error) {
+	w := NewIPCWriter()
+
+	switch codec {
+	case CodecZstd:
+		w.compression = ipc.WithZstd()
+	case CodecLZ4:
+		w.compression = ipc.WithLZ4()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCodec, codec)
+	}
+
+	return w, nil
+}
+
+// writerOptions returns the ipc.NewWriter options for a stream of schema.
+func (w *IPCWriter) writerOptions(schema *arrow.Schema) []ipc.Option {
+	opts := []ipc.Option{ipc.WithSchema(schema), ipc.WithAllocator(w.allocator)}
+	if w.compression != nil {
+		opts = append(opts, w.compression)
+	}
+	return opts
+}
+
 // SerializeToIPC serializes an Arrow Record to IPC bytes.
-func (w *IPCWriter) SerializeToIPC(record arrow.Record) ([]byte, error) {
+func (w *IPCWriter) SerializeToIPC(record arrow.Record) (_ []byte, err error) {
+	defer recoverMemoryLimit(&err)
+
 	var buf bytes.Buffer
 
-	writer := ipc.NewWriter(&buf, ipc.WithSchema(record.Schema()))
+	writer := ipc.NewWriter(&buf, w.writerOptions(record.Schema())...)
 	defer writer.Close()
 
 	if err := writer.Write(record); err != nil {
@@ -41,8 +92,10 @@ func (w *IPCWriter) SerializeToIPC(record arrow.Record) ([]byte, error) {
 }
 
 // DeserializeFromIPC deserializes IPC bytes to an Arrow Record.
-func (w *IPCWriter) DeserializeFromIPC(data []byte) (arrow.Record, error) {
-	reader, err := ipc.NewReader(bytes.NewReader(data))
+func (w *IPCWriter) DeserializeFromIPC(data []byte) (_ arrow.Record, err error) {
+	defer recoverMemoryLimit(&err)
+
+	reader, err := ipc.NewReader(bytes.NewReader(data), ipc.WithAllocator(w.allocator))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create reader: %w", err)
 	}
@@ -61,14 +114,34 @@ func (w *IPCWriter) DeserializeFromIPC(data []byte) (arrow.Record, error) {
 	return record, nil
 }
 
+// DeserializeFromIPCExpecting deserializes IPC bytes and checks the record
+// against the expected schema, so a mismatched payload from another
+// component fails here with a descriptive error rather than later when
+// columns are cast.
+func (w *IPCWriter) DeserializeFromIPCExpecting(data []byte, expected *arrow.Schema) (arrow.Record, error) {
+	record, err := w.DeserializeFromIPC(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateSchema(record, expected); err != nil {
+		record.Release()
+		return nil, fmt.Errorf("unexpected IPC schema: %w", err)
+	}
+
+	return record, nil
+}
+
 // SerializeMultipleToIPC serializes multiple records to IPC bytes.
-func (w *IPCWriter) SerializeMultipleToIPC(records []arrow.Record) ([]byte, error) {
+func (w *IPCWriter) SerializeMultipleToIPC(records []arrow.Record) (_ []byte, err error) {
 	if len(records) == 0 {
 		return nil, fmt.Errorf("no records to serialize")
 	}
 
+	defer recoverMemoryLimit(&err)
+
 	var buf bytes.Buffer
-	writer := ipc.NewWriter(&buf, ipc.WithSchema(records[0].Schema()))
+	writer := ipc.NewWriter(&buf, w.writerOptions(records[0].Schema())...)
 	defer writer.Close()
 
 	for i, record := range records {
@@ -85,8 +158,10 @@ func (w *IPCWriter) SerializeMultipleToIPC(records []arrow.Record) ([]byte, erro
 }
 
 // DeserializeAllFromIPC deserializes IPC bytes to all Arrow Records.
-func (w *IPCWriter) DeserializeAllFromIPC(data []byte) ([]arrow.Record, error) {
-	reader, err := ipc.NewReader(bytes.NewReader(data))
+func (w *IPCWriter) DeserializeAllFromIPC(data []byte) (_ []arrow.Record, err error) {
+	defer recoverMemoryLimit(&err)
+
+	reader, err := ipc.NewReader(bytes.NewReader(data), ipc.WithAllocator(w.allocator))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create reader: %w", err)
 	}
@@ -109,3 +184,27 @@ func (w *IPCWriter) DeserializeAllFromIPC(data []byte) ([]arrow.Record, error) {
 
 	return records, nil
 }
+
+// StreamRecords reads an IPC stream from r and calls fn with each record in
+// turn, so only one record is held in memory at a time. A record is released
+// once fn returns; fn must Retain it to keep it past the call. An error from
+// fn stops iteration and is returned as is.
+func (w *IPCWriter) StreamRecords(r io.Reader, fn func(arrow.Record) error) (err error) {
+	defer recoverMemoryLimit(&err)
+
+	reader, err := ipc.NewReader(r, ipc.WithAllocator(w.allocator))
+	if err != nil {
+		return fmt.Errorf("failed to create reader: %w", err)
+	}
+	defer reader.Release()
+
+	// The reader owns each record and releases it on the next call to Next
+	// or on Release, so a retained record outlives it safely.
+	for reader.Next() {
+		if err := fn(reader.Record()); err != nil {
+			return err
+		}
+	}
+
+	return reader.Err()
+}
@@ -0,0 +1,177 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestDeserializeFromIPCExpecting(t *testing.T) {
+	writer := NewIPCWriter()
+
+	record, err := NewConverter().EventsToArrowBatch([]EventJSON{{EntityID: "e1", Event: "created", Timestamp: 1.0}})
+	if err != nil {
+		t.Fatalf("EventsToArrowBatch failed: %v", err)
+	}
+	defer record.Release()
+
+	data, err := writer.SerializeToIPC(record)
+	if err != nil {
+		t.Fatalf("SerializeToIPC failed: %v", err)
+	}
+
+	got, err := writer.DeserializeFromIPCExpecting(data, EventSchema())
+	if err != nil {
+		t.Fatalf("Matching schema rejected: %v", err)
+	}
+	if got.NumRows() != 1 {
+		t.Errorf("Expected 1 row, got %d", got.NumRows())
+	}
+	got.Release()
+
+	// A record with a different layout is rejected up front
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "entity_id", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).Append(42)
+	wrong := builder.NewRecord()
+	defer wrong.Release()
+
+	data, err = writer.SerializeToIPC(wrong)
+	if err != nil {
+		t.Fatalf("SerializeToIPC failed: %v", err)
+	}
+
+	if _, err := writer.DeserializeFromIPCExpecting(data, EventSchema()); err == nil {
+		t.Fatal("Expected schema mismatch error")
+	} else if !strings.Contains(err.Error(), "field count mismatch") {
+		t.Errorf("Expected descriptive mismatch error, got %v", err)
+	}
+}
+
+func TestIPCWriterCompression(t *testing.T) {
+	events := make([]EventJSON, 1000)
+	for i := range events {
+		events[i] = EventJSON{
+			EntityID:  "entity-1",
+			Event:     "created",
+			Timestamp: 1704067200.0,
+			Details:   map[string]string{"source": "test"},
+			Data:      []byte(strings.Repeat("repetitive payload ", 8)),
+		}
+	}
+
+	record, err := NewConverter().EventsToArrowBatch(events)
+	if err != nil {
+		t.Fatalf("EventsToArrowBatch failed: %v", err)
+	}
+	defer record.Release()
+
+	plain, err := NewIPCWriter().SerializeToIPC(record)
+	if err != nil {
+		t.Fatalf("SerializeToIPC failed: %v", err)
+	}
+
+	for _, codec := range []string{CodecZstd, CodecLZ4} {
+		writer, err := NewIPCWriterWithCompression(codec)
+		if err != nil {
+			t.Fatalf("%s: NewIPCWriterWithCompression failed: %v", codec, err)
+		}
+
+		compressed, err := writer.SerializeToIPC(record)
+		if err != nil {
+			t.Fatalf("%s: SerializeToIPC failed: %v", codec, err)
+		}
+		if len(compressed)*4 > len(plain) {
+			t.Errorf("%s: expected compressed size under a quarter of %d bytes, got %d", codec, len(plain), len(compressed))
+		}
+
+		// A plain writer reads the compressed stream transparently
+		got, err := NewIPCWriter().DeserializeFromIPCExpecting(compressed, EventSchema())
+		if err != nil {
+			t.Fatalf("%s: DeserializeFromIPC failed: %v", codec, err)
+		}
+		if got.NumRows() != int64(len(events)) {
+			t.Errorf("%s: expected %d rows, got %d", codec, len(events), got.NumRows())
+		}
+		data := got.Column(4).(*array.Binary)
+		if string(data.Value(999)) != string(events[999].Data) {
+			t.Errorf("%s: data did not round-trip, got %q", codec, data.Value(999))
+		}
+		got.Release()
+	}
+
+	if _, err := NewIPCWriterWithCompression("gzip"); !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("Expected ErrUnsupportedCodec, got %v", err)
+	}
+}
+
+func TestIPCWriterStreamRecords(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	converter := NewConverterWithAllocator(mem)
+	writer := NewIPCWriterWithAllocator(mem)
+
+	var records []arrow.Record
+	for i := 0; i < 3; i++ {
+		record, err := converter.EventsToArrowBatch([]EventJSON{
+			{EntityID: "e1", Event: "created", Timestamp: float64(i)},
+			{EntityID: "e2", Event: "updated", Timestamp: float64(i)},
+		})
+		if err != nil {
+			t.Fatalf("EventsToArrowBatch failed: %v", err)
+		}
+		records = append(records, record)
+	}
+	data, err := writer.SerializeMultipleToIPC(records)
+	for _, record := range records {
+		record.Release()
+	}
+	if err != nil {
+		t.Fatalf("SerializeMultipleToIPC failed: %v", err)
+	}
+
+	// Every record is visited; a retained one stays usable after the stream ends
+	var rows int64
+	var kept arrow.Record
+	err = writer.StreamRecords(bytes.NewReader(data), func(record arrow.Record) error {
+		rows += record.NumRows()
+		if kept == nil {
+			record.Retain()
+			kept = record
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamRecords failed: %v", err)
+	}
+	if rows != 6 {
+		t.Errorf("Expected 6 rows, got %d", rows)
+	}
+	if kept.Column(2).(*array.Float64).Value(0) != 0 {
+		t.Errorf("Expected retained record to be the first batch")
+	}
+	kept.Release()
+
+	// A callback error stops iteration and is returned
+	errStop := errors.New("stop")
+	calls := 0
+	err = writer.StreamRecords(bytes.NewReader(data), func(arrow.Record) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected iteration to stop after 1 call, got %d", calls)
+	}
+}
@@ -0,0 +1,218 @@
+package data
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// ErrUnsortedInput is returned when a record passed to
+// MergeSortedByTimestamp is not itself sorted.
+var ErrUnsortedInput = errors.New("input record is not sorted by timestamp")
+
+// MergeSortedByTimestamp merges records that are each sorted by timestamp,
+// then entity_id, into a single record in the same order. Null timestamps
+// sort last and null entity IDs sort after non-null ones; rows that are
+// still tied keep input order, so the result is deterministic.
+//
+// All records must share a schema with a Float64 "timestamp" column. A
+// String "entity_id" column, if present, breaks timestamp ties. Empty
+// records are skipped. An input that is not sorted fails with an error
+// wrapping ErrUnsortedInput. The caller must Release the result.
+func MergeSortedByTimestamp(records []arrow.Record) (arrow.Record, error) {
+	if len(records) == 0 {
+		return nil, errors.New("no records to merge")
+	}
+
+	schema := records[0].Schema()
+	cursors := make(mergeHeap, 0, len(records))
+	var total int64
+	for i, record := range records {
+		if record == nil {
+			return nil, fmt.Errorf("record %d is nil", i)
+		}
+		if !record.Schema().Equal(schema) {
+			return nil, fmt.Errorf("record %d schema does not match record 0", i)
+		}
+
+		cursor, err := newMergeCursor(i, record)
+		if err != nil {
+			return nil, err
+		}
+		if record.NumRows() > 0 {
+			cursors = append(cursors, cursor)
+			total += record.NumRows()
+		}
+	}
+	heap.Init(&cursors)
+
+	// Collect the merged order as runs of consecutive rows from one input,
+	// so each run is copied with a single slice
+	var runs []mergeRun
+	for cursors.Len() > 0 {
+		cursor := cursors[0]
+		row := cursor.row
+
+		if n := len(runs); n > 0 && runs[n-1].input == cursor.input && runs[n-1].end == row {
+			runs[n-1].end++
+		} else {
+			runs = append(runs, mergeRun{input: cursor.input, start: row, end: row + 1})
+		}
+
+		cursor.row++
+		if cursor.row == cursor.rows {
+			heap.Pop(&cursors)
+			continue
+		}
+		if cursor.compare(cursor.row, cursor, row) < 0 {
+			return nil, fmt.Errorf("%w: record %d row %d", ErrUnsortedInput, cursor.input, cursor.row)
+		}
+		heap.Fix(&cursors, 0)
+	}
+
+	if total == 0 {
+		return records[0].NewSlice(0, 0), nil
+	}
+
+	columns := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, col := range columns {
+			if col != nil {
+				col.Release()
+			}
+		}
+	}()
+
+	for i := range columns {
+		slices := make([]arrow.Array, len(runs))
+		for j, run := range runs {
+			slices[j] = array.NewSlice(records[run.input].Column(i), run.start, run.end)
+		}
+		merged, err := array.Concatenate(slices, memory.DefaultAllocator)
+		for _, slice := range slices {
+			slice.Release()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge column %s: %w", schema.Field(i).Name, err)
+		}
+		columns[i] = merged
+	}
+
+	return array.NewRecord(schema, columns, total), nil
+}
+
+// mergeRun is a range of consecutive rows [start, end) from one input.
+type mergeRun struct {
+	input      int
+	start, end int64
+}
+
+// mergeCursor is the read position within one input record.
+type mergeCursor struct {
+	input      int
+	row, rows  int64
+	timestamps *array.Float64
+	entityIDs  *array.String // nil if the record has no entity_id column
+}
+
+// newMergeCursor creates a cursor at the first row of record.
+func newMergeCursor(input int, record arrow.Record) (*mergeCursor, error) {
+	col, err := namedColumn(record, "timestamp", true)
+	if err != nil {
+		return nil, err
+	}
+	timestamps, ok := col.(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("column %q is not a Float64 array", "timestamp")
+	}
+
+	entityIDs, err := stringColumn(record, "entity_id", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mergeCursor{
+		input:      input,
+		rows:       record.NumRows(),
+		timestamps: timestamps,
+		entityIDs:  entityIDs,
+	}, nil
+}
+
+// compare orders row of c against row other of o by timestamp then entity
+// ID, returning a negative, zero or positive value.
+func (c *mergeCursor) compare(row int64, o *mergeCursor, other int64) int {
+	if cmp := compareNullable(c.timestamps.IsNull(int(row)), o.timestamps.IsNull(int(other))); cmp != 0 {
+		return cmp
+	}
+	if !c.timestamps.IsNull(int(row)) {
+		a, b := c.timestamps.Value(int(row)), o.timestamps.Value(int(other))
+		if a < b {
+			return -1
+		}
+		if a > b {
+			return 1
+		}
+	}
+
+	if c.entityIDs == nil || o.entityIDs == nil {
+		return 0
+	}
+	if cmp := compareNullable(c.entityIDs.IsNull(int(row)), o.entityIDs.IsNull(int(other))); cmp != 0 {
+		return cmp
+	}
+	if c.entityIDs.IsNull(int(row)) {
+		return 0
+	}
+	a, b := c.entityIDs.Value(int(row)), o.entityIDs.Value(int(other))
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareNullable orders non-null values before nulls. It returns zero if
+// both or neither are null.
+func compareNullable(aNull, bNull bool) int {
+	switch {
+	case aNull == bNull:
+		return 0
+	case aNull:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// mergeHeap orders cursors by their current row, then by input index.
+type mergeHeap []*mergeCursor
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if cmp := h[i].compare(h[i].row, h[j], h[j].row); cmp != 0 {
+		return cmp < 0
+	}
+	return h[i].input < h[j].input
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeCursor)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	cursor := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return cursor
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// mergeRow is one event row; a nil timestamp is null.
+type mergeRow struct {
+	entityID  string
+	timestamp *float64
+}
+
+func ts(v float64) *float64 { return &v }
+
+// buildEventRecord builds an EventSchema record from rows.
+func buildEventRecord(t *testing.T, rows []mergeRow) arrow.Record {
+	t.Helper()
+
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), EventSchema())
+	defer builder.Release()
+
+	for _, row := range rows {
+		builder.Field(0).(*array.StringBuilder).Append(row.entityID)
+		builder.Field(1).(*array.StringBuilder).Append("created")
+		if row.timestamp != nil {
+			builder.Field(2).(*array.Float64Builder).Append(*row.timestamp)
+		} else {
+			builder.Field(2).(*array.Float64Builder).AppendNull()
+		}
+		builder.Field(3).(*array.MapBuilder).AppendNull()
+		builder.Field(4).(*array.BinaryBuilder).AppendNull()
+	}
+
+	return builder.NewRecord()
+}
+
+func TestMergeSortedByTimestamp(t *testing.T) {
+	inputs := [][]mergeRow{
+		{{"a", ts(1)}, {"c", ts(4)}, {"a", ts(7)}, {"z", nil}},
+		{{"b", ts(2)}, {"b", ts(4)}, {"x", ts(9)}},
+		{},
+		{{"a", ts(4)}, {"d", ts(5)}, {"a", nil}},
+	}
+
+	records := make([]arrow.Record, len(inputs))
+	for i, rows := range inputs {
+		records[i] = buildEventRecord(t, rows)
+		defer records[i].Release()
+	}
+
+	merged, err := MergeSortedByTimestamp(records)
+	if err != nil {
+		t.Fatalf("MergeSortedByTimestamp failed: %v", err)
+	}
+	defer merged.Release()
+
+	expected := []mergeRow{
+		{"a", ts(1)}, {"b", ts(2)},
+		{"a", ts(4)}, {"b", ts(4)}, {"c", ts(4)}, // timestamp ties ordered by entity_id
+		{"d", ts(5)}, {"a", ts(7)}, {"x", ts(9)},
+		{"a", nil}, {"z", nil}, // null timestamps last
+	}
+	if merged.NumRows() != int64(len(expected)) {
+		t.Fatalf("Expected %d rows, got %d", len(expected), merged.NumRows())
+	}
+
+	entityIDs := merged.Column(0).(*array.String)
+	timestamps := merged.Column(2).(*array.Float64)
+	for i, want := range expected {
+		if entityIDs.Value(i) != want.entityID {
+			t.Errorf("Row %d: expected entity %s, got %s", i, want.entityID, entityIDs.Value(i))
+		}
+		if want.timestamp == nil {
+			if !timestamps.IsNull(i) {
+				t.Errorf("Row %d: expected null timestamp, got %v", i, timestamps.Value(i))
+			}
+		} else if timestamps.IsNull(i) || timestamps.Value(i) != *want.timestamp {
+			t.Errorf("Row %d: expected timestamp %v", i, *want.timestamp)
+		}
+	}
+}
+
+func TestMergeSortedByTimestampEdgeCases(t *testing.T) {
+	if _, err := MergeSortedByTimestamp(nil); err == nil {
+		t.Error("Expected error for no records")
+	}
+
+	// All-empty inputs merge to an empty record with the shared schema
+	empty := buildEventRecord(t, nil)
+	defer empty.Release()
+	merged, err := MergeSortedByTimestamp([]arrow.Record{empty, empty})
+	if err != nil {
+		t.Fatalf("Merging empty records failed: %v", err)
+	}
+	if merged.NumRows() != 0 || !merged.Schema().Equal(EventSchema()) {
+		t.Errorf("Expected empty EventSchema record, got %d rows", merged.NumRows())
+	}
+	merged.Release()
+
+	// Unsorted input is rejected
+	unsorted := buildEventRecord(t, []mergeRow{{"a", ts(3)}, {"b", ts(1)}})
+	defer unsorted.Release()
+	if _, err := MergeSortedByTimestamp([]arrow.Record{unsorted}); !errors.Is(err, ErrUnsortedInput) {
+		t.Errorf("Expected ErrUnsortedInput, got %v", err)
+	}
+
+	// Mismatched schemas are rejected
+	txs := buildTransactionRecord(t, []string{"tx-0"}, []string{"e"}, []string{"created"})
+	defer txs.Release()
+	if _, err := MergeSortedByTimestamp([]arrow.Record{empty, txs}); err == nil {
+		t.Error("Expected error for mismatched schemas")
+	}
+}
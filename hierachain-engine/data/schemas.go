@@ -36,6 +36,17 @@ func EventSchema() *arrow.Schema {
 	)
 }
 
+// EventSchemaWithTimestamp returns EventSchema with the timestamp stored as
+// an Arrow UTC timestamp in the given unit instead of float64 seconds. This
+// keeps sub-millisecond precision and is understood natively by Arrow
+// tooling, but it does NOT match the Rust schema: records in this layout
+// must not be sent to HieraChain-Consensus. EventSchema remains the default.
+func EventSchemaWithTimestamp(unit arrow.TimeUnit) *arrow.Schema {
+	fields := EventSchema().Fields()
+	fields[2].Type = &arrow.TimestampType{Unit: unit, TimeZone: "UTC"}
+	return arrow.NewSchema(fields, nil)
+}
+
 // BlockHeaderSchema returns the Arrow schema for a Block Header.
 // Matches Rust: src/core/schemas.rs::get_block_header_schema()
 //
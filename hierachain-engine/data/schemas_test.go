@@ -2,9 +2,11 @@ package data
 
 import (
 	"encoding/json"
+	"math"
 	"testing"
 
 	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
 )
 
 func TestEventSchema(t *testing.T) {
@@ -124,6 +126,63 @@ func TestConverterJSONToArrowRoundTrip(t *testing.T) {
 	}
 }
 
+func TestConverterTimestampUnits(t *testing.T) {
+	events := []EventJSON{
+		{EntityID: "entity-1", Event: "created", Timestamp: 1704067200.123456},
+		{EntityID: "entity-2", Event: "updated", Timestamp: 1704067300.5},
+	}
+
+	tests := []struct {
+		unit      arrow.TimeUnit
+		first     arrow.Timestamp
+		tolerance float64
+	}{
+		{arrow.Millisecond, 1704067200123, 1e-3},
+		{arrow.Microsecond, 1704067200123456, 1e-6},
+	}
+
+	for _, tt := range tests {
+		converter := NewConverterWithTimestampUnit(tt.unit)
+
+		record, err := converter.EventsToArrowBatch(events)
+		if err != nil {
+			t.Fatalf("%s: failed to convert to Arrow: %v", tt.unit, err)
+		}
+
+		timestamps, ok := record.Column(2).(*array.Timestamp)
+		if !ok {
+			t.Fatalf("%s: expected Timestamp column, got %s", tt.unit, record.Column(2).DataType())
+		}
+		if unit := timestamps.DataType().(*arrow.TimestampType).Unit; unit != tt.unit {
+			t.Errorf("Expected unit %s, got %s", tt.unit, unit)
+		}
+		if timestamps.Value(0) != tt.first {
+			t.Errorf("%s: expected first timestamp %d, got %d", tt.unit, tt.first, timestamps.Value(0))
+		}
+
+		jsonBytes, err := converter.ArrowBatchToJSON(record)
+		record.Release()
+		if err != nil {
+			t.Fatalf("%s: failed to convert to JSON: %v", tt.unit, err)
+		}
+
+		var result []EventJSON
+		if err := json.Unmarshal(jsonBytes, &result); err != nil {
+			t.Fatalf("%s: failed to unmarshal JSON: %v", tt.unit, err)
+		}
+		for i, event := range result {
+			if math.Abs(event.Timestamp-events[i].Timestamp) > tt.tolerance {
+				t.Errorf("%s: expected timestamp %v, got %v", tt.unit, events[i].Timestamp, event.Timestamp)
+			}
+		}
+	}
+
+	// The default layout still matches the Rust float64 schema
+	if NewConverter().schema.Field(2).Type.ID() != arrow.FLOAT64 {
+		t.Error("Expected default timestamp column to be Float64")
+	}
+}
+
 func TestValidateSchema(t *testing.T) {
 	converter := NewConverter()
 
@@ -0,0 +1,263 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/VanDung-dev/HieraChain-Engine/hierachain-engine/core"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// RecordToTransactions converts a record in TransactionSchema layout into
+// mempool transactions. Columns are matched by name, so extra columns such
+// as the ZK proof fields are ignored. The arrow_payload column maps to Data,
+// details to Metadata, and a non-null signature is stored in
+// Metadata["signature"]. Every transaction is validated; the first invalid
+// row fails the whole record with an error wrapping core.ErrInvalidTx.
+func RecordToTransactions(record arrow.Record) ([]*core.Transaction, error) {
+	if record == nil {
+		return nil, errors.New("record is nil")
+	}
+
+	cols, err := transactionColumnsOf(record)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]*core.Transaction, record.NumRows())
+	for i := range txs {
+		tx := &core.Transaction{
+			ID:        cols.txIDs.Value(i),
+			EntityID:  cols.entityIDs.Value(i),
+			EventType: cols.eventTypes.Value(i),
+			Timestamp: floatToTime(cols.timestamps.Value(i)),
+		}
+
+		if cols.payloads != nil && !cols.payloads.IsNull(i) {
+			tx.Data = cols.payloads.Value(i)
+		}
+
+		if cols.details != nil && !cols.details.IsNull(i) {
+			tx.Metadata = make(map[string]interface{})
+			for k, v := range extractMapValues(cols.details, i) {
+				tx.Metadata[k] = v
+			}
+		}
+
+		if cols.signatures != nil && !cols.signatures.IsNull(i) {
+			if tx.Metadata == nil {
+				tx.Metadata = make(map[string]interface{})
+			}
+			tx.Metadata["signature"] = cols.signatures.Value(i)
+		}
+
+		if err := tx.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", core.ErrInvalidTx, i, err)
+		}
+		txs[i] = tx
+	}
+
+	return txs, nil
+}
+
+// transactionColumns holds the typed columns of a transaction record.
+// Optional columns absent from the record are nil.
+type transactionColumns struct {
+	txIDs      *array.String
+	entityIDs  *array.String
+	eventTypes *array.String
+	signatures *array.String
+	timestamps *array.Float64
+	payloads   *array.Binary
+	details    *array.Map
+}
+
+// transactionColumnsOf looks up the TransactionSchema columns of record by
+// name and checks their types.
+func transactionColumnsOf(record arrow.Record) (*transactionColumns, error) {
+	cols := &transactionColumns{}
+	var (
+		err error
+		ok  bool
+	)
+
+	cols.txIDs, err = stringColumn(record, "tx_id", true)
+	if err != nil {
+		return nil, err
+	}
+	cols.entityIDs, err = stringColumn(record, "entity_id", true)
+	if err != nil {
+		return nil, err
+	}
+	cols.eventTypes, err = stringColumn(record, "event_type", true)
+	if err != nil {
+		return nil, err
+	}
+	cols.signatures, err = stringColumn(record, "signature", false)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := namedColumn(record, "timestamp", true)
+	if err != nil {
+		return nil, err
+	}
+	cols.timestamps, ok = col.(*array.Float64)
+	if !ok {
+		return nil, fmt.Errorf("column %q is not a Float64 array", "timestamp")
+	}
+
+	if col, err = namedColumn(record, "arrow_payload", false); err != nil {
+		return nil, err
+	} else if col != nil {
+		if cols.payloads, ok = col.(*array.Binary); !ok {
+			return nil, fmt.Errorf("column %q is not a Binary array", "arrow_payload")
+		}
+	}
+
+	if col, err = namedColumn(record, "details", false); err != nil {
+		return nil, err
+	} else if col != nil {
+		if cols.details, ok = col.(*array.Map); !ok {
+			return nil, fmt.Errorf("column %q is not a Map array", "details")
+		}
+	}
+
+	return cols, nil
+}
+
+// stringColumn returns the named String column, or nil if it is absent and not required.
+func stringColumn(record arrow.Record, name string, required bool) (*array.String, error) {
+	col, err := namedColumn(record, name, required)
+	if err != nil || col == nil {
+		return nil, err
+	}
+
+	str, ok := col.(*array.String)
+	if !ok {
+		return nil, fmt.Errorf("column %q is not a String array", name)
+	}
+	return str, nil
+}
+
+// namedColumn returns the column with the given name, or nil if it is absent and not required.
+func namedColumn(record arrow.Record, name string, required bool) (arrow.Array, error) {
+	indices := record.Schema().FieldIndices(name)
+	if len(indices) == 0 {
+		if required {
+			return nil, fmt.Errorf("missing required column %q", name)
+		}
+		return nil, nil
+	}
+	return record.Column(indices[0]), nil
+}
+
+// floatToTime converts fractional Unix seconds to a time.Time.
+func floatToTime(ts float64) time.Time {
+	sec, frac := math.Modf(ts)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// TransactionsToArrowBatch converts transactions to a record in
+// TransactionSchema layout using the converter's allocator. Data is written
+// to arrow_payload; a nil Data or an empty Signature is stored as null, as
+// are the ZK proof columns, which TransactionJSON does not carry.
+func (c *Converter) TransactionsToArrowBatch(txs []TransactionJSON) (record arrow.Record, err error) {
+	if len(txs) == 0 {
+		return nil, errors.New("empty transactions slice")
+	}
+
+	defer recoverMemoryLimit(&err)
+
+	builder := array.NewRecordBuilder(c.allocator, TransactionSchema())
+	defer builder.Release()
+
+	txIDBuilder := builder.Field(0).(*array.StringBuilder)
+	entityIDBuilder := builder.Field(1).(*array.StringBuilder)
+	eventTypeBuilder := builder.Field(2).(*array.StringBuilder)
+	payloadBuilder := builder.Field(3).(*array.BinaryBuilder)
+	signatureBuilder := builder.Field(4).(*array.StringBuilder)
+	timestampBuilder := builder.Field(5).(*array.Float64Builder)
+	detailsBuilder := builder.Field(6).(*array.MapBuilder)
+	zkProofBuilder := builder.Field(7).(*array.BinaryBuilder)
+	zkInputsBuilder := builder.Field(8).(*array.BinaryBuilder)
+
+	keyBuilder := detailsBuilder.KeyBuilder().(*array.StringBuilder)
+	valueBuilder := detailsBuilder.ItemBuilder().(*array.StringBuilder)
+
+	for _, tx := range txs {
+		txIDBuilder.Append(tx.TxID)
+		entityIDBuilder.Append(tx.EntityID)
+		eventTypeBuilder.Append(tx.EventType)
+		timestampBuilder.Append(tx.Timestamp)
+
+		if tx.Data != nil {
+			payloadBuilder.Append(tx.Data)
+		} else {
+			payloadBuilder.AppendNull()
+		}
+
+		if tx.Signature != "" {
+			signatureBuilder.Append(tx.Signature)
+		} else {
+			signatureBuilder.AppendNull()
+		}
+
+		if len(tx.Details) > 0 {
+			detailsBuilder.Append(true)
+			for k, v := range tx.Details {
+				keyBuilder.Append(k)
+				valueBuilder.Append(v)
+			}
+		} else {
+			detailsBuilder.AppendNull()
+		}
+
+		zkProofBuilder.AppendNull()
+		zkInputsBuilder.AppendNull()
+	}
+
+	return builder.NewRecord(), nil
+}
+
+// ArrowBatchToTransactions converts a record in TransactionSchema layout back
+// to TransactionJSON values. Columns are matched by name as in
+// RecordToTransactions; null arrow_payload, signature and details leave the
+// corresponding fields at their zero value.
+func (c *Converter) ArrowBatchToTransactions(record arrow.Record) ([]TransactionJSON, error) {
+	if record == nil {
+		return nil, errors.New("record is nil")
+	}
+
+	cols, err := transactionColumnsOf(record)
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]TransactionJSON, record.NumRows())
+	for i := range txs {
+		tx := TransactionJSON{
+			TxID:      cols.txIDs.Value(i),
+			EntityID:  cols.entityIDs.Value(i),
+			EventType: cols.eventTypes.Value(i),
+			Timestamp: cols.timestamps.Value(i),
+		}
+
+		if cols.payloads != nil && !cols.payloads.IsNull(i) {
+			tx.Data = cols.payloads.Value(i)
+		}
+		if cols.signatures != nil && !cols.signatures.IsNull(i) {
+			tx.Signature = cols.signatures.Value(i)
+		}
+		if cols.details != nil && !cols.details.IsNull(i) {
+			tx.Details = extractMapValues(cols.details, i)
+		}
+
+		txs[i] = tx
+	}
+
+	return txs, nil
+}
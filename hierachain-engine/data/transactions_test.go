@@ -0,0 +1,199 @@
+package data
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/VanDung-dev/HieraChain-Engine/hierachain-engine/core"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// buildTransactionRecord builds a TransactionSchema record from parallel row values.
+func buildTransactionRecord(t *testing.T, ids, entities, events []string) arrow.Record {
+	t.Helper()
+
+	builder := array.NewRecordBuilder(memory.NewGoAllocator(), TransactionSchema())
+	defer builder.Release()
+
+	payloadBuilder := builder.Field(3).(*array.BinaryBuilder)
+	signatureBuilder := builder.Field(4).(*array.StringBuilder)
+	timestampBuilder := builder.Field(5).(*array.Float64Builder)
+	detailsBuilder := builder.Field(6).(*array.MapBuilder)
+	keyBuilder := detailsBuilder.KeyBuilder().(*array.StringBuilder)
+	valueBuilder := detailsBuilder.ItemBuilder().(*array.StringBuilder)
+
+	for i := range ids {
+		builder.Field(0).(*array.StringBuilder).Append(ids[i])
+		builder.Field(1).(*array.StringBuilder).Append(entities[i])
+		builder.Field(2).(*array.StringBuilder).Append(events[i])
+		timestampBuilder.Append(1700000000.5 + float64(i))
+
+		// Even rows carry payload, signature and details; odd rows are sparse
+		if i%2 == 0 {
+			payloadBuilder.Append([]byte("payload-" + ids[i]))
+			signatureBuilder.Append("sig-" + ids[i])
+			detailsBuilder.Append(true)
+			keyBuilder.Append("source")
+			valueBuilder.Append("arrow")
+		} else {
+			payloadBuilder.AppendNull()
+			signatureBuilder.AppendNull()
+			detailsBuilder.AppendNull()
+		}
+
+		builder.Field(7).(*array.BinaryBuilder).AppendNull()
+		builder.Field(8).(*array.BinaryBuilder).AppendNull()
+	}
+
+	return builder.NewRecord()
+}
+
+func TestRecordToTransactions(t *testing.T) {
+	record := buildTransactionRecord(t,
+		[]string{"tx-0", "tx-1", "tx-2"},
+		[]string{"entity-a", "entity-b", "entity-c"},
+		[]string{"created", "updated", "deleted"},
+	)
+	defer record.Release()
+
+	txs, err := RecordToTransactions(record)
+	if err != nil {
+		t.Fatalf("RecordToTransactions failed: %v", err)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(txs))
+	}
+
+	first := txs[0]
+	if first.ID != "tx-0" || first.EntityID != "entity-a" || first.EventType != "created" {
+		t.Errorf("Unexpected identity fields: %+v", first)
+	}
+	if string(first.Data) != "payload-tx-0" {
+		t.Errorf("Expected payload 'payload-tx-0', got %q", first.Data)
+	}
+	if first.Metadata["signature"] != "sig-tx-0" || first.Metadata["source"] != "arrow" {
+		t.Errorf("Unexpected metadata: %v", first.Metadata)
+	}
+	if first.Timestamp.Unix() != 1700000000 || first.Timestamp.Nanosecond() != 500000000 {
+		t.Errorf("Unexpected timestamp: %v", first.Timestamp)
+	}
+
+	second := txs[1]
+	if second.Data != nil || second.Metadata != nil {
+		t.Errorf("Expected null payload and metadata, got %q %v", second.Data, second.Metadata)
+	}
+	if second.EventType != "updated" {
+		t.Errorf("Expected event type 'updated', got %s", second.EventType)
+	}
+
+	// Converted transactions are admissible to the mempool
+	mempool := core.NewMempool(10)
+	for _, tx := range txs {
+		if err := mempool.Add(tx); err != nil {
+			t.Errorf("Mempool rejected %s: %v", tx.ID, err)
+		}
+	}
+}
+
+func TestRecordToTransactionsValidation(t *testing.T) {
+	record := buildTransactionRecord(t,
+		[]string{"tx-0", "tx-1"},
+		[]string{"entity-a", ""},
+		[]string{"created", "updated"},
+	)
+	defer record.Release()
+
+	if _, err := RecordToTransactions(record); !errors.Is(err, core.ErrInvalidTx) {
+		t.Errorf("Expected ErrInvalidTx for missing entity ID, got %v", err)
+	}
+
+	// Records missing required columns are rejected
+	events, err := NewConverter().EventsToArrowBatch([]EventJSON{{EntityID: "e1", Event: "created"}})
+	if err != nil {
+		t.Fatalf("EventsToArrowBatch failed: %v", err)
+	}
+	defer events.Release()
+
+	if _, err := RecordToTransactions(events); err == nil {
+		t.Error("Expected error for record without tx_id column")
+	}
+}
+
+func TestConverterTransactionsRoundTrip(t *testing.T) {
+	converter := NewConverter()
+
+	txs := []TransactionJSON{
+		{
+			TxID:      "tx-1",
+			EntityID:  "entity-1",
+			EventType: "created",
+			Timestamp: 1704067200.25,
+			Data:      []byte("payload"),
+			Signature: "sig-1",
+			Details:   map[string]string{"key1": "value1", "key2": "value2"},
+		},
+		{
+			TxID:      "tx-2",
+			EntityID:  "entity-2",
+			EventType: "updated",
+			Timestamp: 1704067300.0,
+		},
+	}
+
+	// Convert to Arrow
+	record, err := converter.TransactionsToArrowBatch(txs)
+	if err != nil {
+		t.Fatalf("Failed to convert to Arrow: %v", err)
+	}
+	defer record.Release()
+
+	if err := ValidateSchema(record, TransactionSchema()); err != nil {
+		t.Errorf("Record does not match TransactionSchema: %v", err)
+	}
+	if record.NumRows() != 2 {
+		t.Errorf("Expected 2 rows, got %d", record.NumRows())
+	}
+
+	// Absent signature and data are nulls, not empty values
+	if !record.Column(3).IsNull(1) {
+		t.Error("Expected arrow_payload to be null for tx-2")
+	}
+	if !record.Column(4).IsNull(1) {
+		t.Error("Expected signature to be null for tx-2")
+	}
+
+	// Convert back
+	result, err := converter.ArrowBatchToTransactions(record)
+	if err != nil {
+		t.Fatalf("Failed to convert from Arrow: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(result))
+	}
+
+	first := result[0]
+	if first.TxID != "tx-1" || first.EntityID != "entity-1" || first.EventType != "created" {
+		t.Errorf("Expected tx-1/entity-1/created, got %s/%s/%s", first.TxID, first.EntityID, first.EventType)
+	}
+	if first.Timestamp != 1704067200.25 {
+		t.Errorf("Expected timestamp 1704067200.25, got %f", first.Timestamp)
+	}
+	if string(first.Data) != "payload" {
+		t.Errorf("Expected data 'payload', got %q", first.Data)
+	}
+	if first.Signature != "sig-1" {
+		t.Errorf("Expected signature 'sig-1', got %q", first.Signature)
+	}
+	if len(first.Details) != 2 || first.Details["key1"] != "value1" || first.Details["key2"] != "value2" {
+		t.Errorf("Expected details to round-trip, got %v", first.Details)
+	}
+
+	second := result[1]
+	if second.Data != nil || second.Signature != "" || second.Details != nil {
+		t.Errorf("Expected empty data, signature and details for tx-2, got %q, %q, %v",
+			second.Data, second.Signature, second.Details)
+	}
+}
@@ -0,0 +1,39 @@
+package network
+
+import (
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultCompressionMinSize is the serialized size from which messages are
+// compressed when compression is enabled.
+const DefaultCompressionMinSize = 1024
+
+// zstdFrameFlag prefixes a zstd-compressed message on the wire. Plain
+// messages are JSON objects and always start with '{', so the two cannot
+// be confused and nodes without compression still read plain messages.
+const zstdFrameFlag byte = 0x01
+
+var (
+	// The encoder and decoder are safe for concurrent EncodeAll and
+	// DecodeAll calls. Decoding is capped at MaxNetworkMessageSize so a
+	// small compressed frame cannot expand without bound.
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxNetworkMessageSize))
+)
+
+// encodeFrame returns data as it is sent on the wire, compressed and
+// flagged if compression is enabled and data is at least minSize bytes.
+func encodeFrame(data []byte, compress bool, minSize int) []byte {
+	if !compress || len(data) < minSize {
+		return data
+	}
+	return zstdEncoder.EncodeAll(data, []byte{zstdFrameFlag})
+}
+
+// decodeFrame reverses encodeFrame, decompressing flagged frames.
+func decodeFrame(frame []byte) ([]byte, error) {
+	if len(frame) == 0 || frame[0] != zstdFrameFlag {
+		return frame, nil
+	}
+	return zstdDecoder.DecodeAll(frame[1:], nil)
+}
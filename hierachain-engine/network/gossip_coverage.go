@@ -0,0 +1,93 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// Payload keys used by coverage tracking. A tracked gossip message carries
+// its ID; an acknowledgement carries the ID and the node that saw it.
+const (
+	GossipIDKey    = "gossip_id"
+	GossipAckKey   = "gossip_ack"
+	GossipAckerKey = "gossip_acker"
+)
+
+// coverageEntry is what one node knows about the spread of a message.
+type coverageEntry struct {
+	createdAt time.Time
+	upstream  string          // peer the message arrived from; empty at the origin
+	seen      map[string]bool // node IDs known to have seen the message
+}
+
+// coverageTracker records which nodes have acknowledged tracked messages.
+// It is safe for concurrent use.
+type coverageTracker struct {
+	entries map[string]*coverageEntry
+	mu      sync.Mutex
+}
+
+// newCoverageTracker creates an empty tracker.
+func newCoverageTracker() *coverageTracker {
+	return &coverageTracker{entries: make(map[string]*coverageEntry)}
+}
+
+// track starts tracking msgID, seen by self and, unless this is the
+// origin, by upstream. It returns false if msgID is already tracked.
+func (t *coverageTracker) track(msgID, self, upstream string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.entries[msgID]; ok {
+		return false
+	}
+
+	entry := &coverageEntry{
+		createdAt: time.Now(),
+		upstream:  upstream,
+		seen:      map[string]bool{self: true},
+	}
+	if upstream != "" {
+		entry.seen[upstream] = true
+	}
+	t.entries[msgID] = entry
+	return true
+}
+
+// ack records that acker has seen msgID. It returns the peer to forward
+// the acknowledgement to, or "" if there is none: the message is not
+// tracked, this node is its origin, or acker was already recorded.
+func (t *coverageTracker) ack(msgID, acker string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[msgID]
+	if !ok || entry.seen[acker] {
+		return ""
+	}
+	entry.seen[acker] = true
+	return entry.upstream
+}
+
+// seenCount returns how many nodes are known to have seen msgID.
+func (t *coverageTracker) seenCount(msgID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.entries[msgID]; ok {
+		return len(entry.seen)
+	}
+	return 0
+}
+
+// removeOlderThan drops entries created before cutoff.
+func (t *coverageTracker) removeOlderThan(cutoff time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, entry := range t.entries {
+		if entry.createdAt.Before(cutoff) {
+			delete(t.entries, id)
+		}
+	}
+}
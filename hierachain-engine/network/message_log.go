@@ -0,0 +1,169 @@
+package network
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// MessageLog durably records received messages so they can be replayed
+// after a crash.
+type MessageLog interface {
+	// Append durably records msg.
+	Append(msg *Message) error
+	// Replay calls fn with every recorded message in append order,
+	// stopping at the first error fn returns.
+	Replay(fn func(*Message) error) error
+}
+
+// ErrLogClosed is returned when using a closed FileMessageLog.
+var ErrLogClosed = errors.New("message log is closed")
+
+// logRecordHeaderSize is the size of a record header:
+// [4 bytes length (BigEndian)] [4 bytes CRC32 of the payload (BigEndian)]
+const logRecordHeaderSize = 8
+
+// FileMessageLog is a MessageLog backed by an append-only file of
+// checksummed JSON records. Every Append is synced to disk before it
+// returns. A torn or corrupt record at the end of the file, as left by a
+// crash mid-write, is truncated when the log is opened.
+type FileMessageLog struct {
+	file *os.File
+	size int64 // offset just past the last valid record
+	mu   sync.Mutex
+}
+
+// OpenFileMessageLog opens or creates the log file at path.
+func OpenFileMessageLog(path string) (*FileMessageLog, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to stat message log: %w", err)
+	}
+
+	l := &FileMessageLog{file: file}
+	valid, err := l.scan(info.Size(), nil)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if valid < info.Size() {
+		log.Printf("Warning: truncating corrupt message log tail in %s (%d bytes)", path, info.Size()-valid)
+		if err := file.Truncate(valid); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to truncate message log: %w", err)
+		}
+	}
+	l.size = valid
+
+	return l, nil
+}
+
+// Append durably records msg.
+func (l *FileMessageLog) Append(msg *Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	record := make([]byte, logRecordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[logRecordHeaderSize:], payload)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrLogClosed
+	}
+
+	if _, err := l.file.WriteAt(record, l.size); err != nil {
+		return fmt.Errorf("failed to write message log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync message log: %w", err)
+	}
+	l.size += int64(len(record))
+
+	return nil
+}
+
+// Replay calls fn with every recorded message in append order.
+func (l *FileMessageLog) Replay(fn func(*Message) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrLogClosed
+	}
+
+	_, err := l.scan(l.size, fn)
+	return err
+}
+
+// Close closes the log file.
+func (l *FileMessageLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// scan reads records up to limit, passing each to fn if fn is non-nil.
+// It returns the offset just past the last valid record; reading stops
+// early at a truncated or corrupt record. The caller must hold l.mu or
+// have exclusive access to l.
+func (l *FileMessageLog) scan(limit int64, fn func(*Message) error) (int64, error) {
+	reader := io.NewSectionReader(l.file, 0, limit)
+	header := make([]byte, logRecordHeaderSize)
+
+	var offset int64
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return offset, nil // Clean end or torn header
+		}
+
+		length := binary.BigEndian.Uint32(header[0:4])
+		if length > MaxNetworkMessageSize {
+			return offset, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return offset, nil
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return offset, nil
+		}
+
+		var msg Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return offset, nil
+		}
+
+		if fn != nil {
+			if err := fn(&msg); err != nil {
+				return offset, err
+			}
+		}
+		offset += logRecordHeaderSize + int64(length)
+	}
+}
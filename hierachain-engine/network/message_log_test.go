@@ -0,0 +1,191 @@
+package network
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// replayAll returns every message in l.
+func replayAll(t *testing.T, l MessageLog) []*Message {
+	t.Helper()
+
+	var msgs []*Message
+	if err := l.Replay(func(msg *Message) error {
+		msgs = append(msgs, msg)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	return msgs
+}
+
+func TestFileMessageLogRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+
+	l, err := OpenFileMessageLog(path)
+	if err != nil {
+		t.Fatalf("OpenFileMessageLog failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		msg := &Message{
+			Type:      "block",
+			From:      "peer1",
+			Payload:   map[string]interface{}{"height": float64(i)},
+			Timestamp: time.Now().UTC(),
+			Seq:       uint64(i + 1),
+		}
+		if err := l.Append(msg); err != nil {
+			t.Fatalf("Append %d failed: %v", i, err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Messages survive reopening, in append order
+	l, err = OpenFileMessageLog(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer l.Close()
+
+	msgs := replayAll(t, l)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(msgs))
+	}
+	for i, msg := range msgs {
+		if msg.Seq != uint64(i+1) || msg.Payload["height"] != float64(i) || msg.From != "peer1" {
+			t.Errorf("Message %d: unexpected %+v", i, msg)
+		}
+	}
+
+	// Replay stops at the first handler error
+	count := 0
+	err = l.Replay(func(msg *Message) error {
+		count++
+		return errors.New("stop")
+	})
+	if err == nil || count != 1 {
+		t.Errorf("Expected replay to stop after 1 message with an error, got %d, %v", count, err)
+	}
+}
+
+func TestFileMessageLogCorruptTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+
+	l, err := OpenFileMessageLog(path)
+	if err != nil {
+		t.Fatalf("OpenFileMessageLog failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := l.Append(&Message{Type: "tx", From: "peer1", Seq: uint64(i + 1)}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	l.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	validSize := info.Size()
+
+	// Simulate a crash mid-write: a header promising more bytes than follow
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 50, 1, 2, 3, 4, '{', '"'}); err != nil {
+		t.Fatalf("Failed to write torn record: %v", err)
+	}
+	f.Close()
+
+	l, err = OpenFileMessageLog(path)
+	if err != nil {
+		t.Fatalf("Reopen with corrupt tail failed: %v", err)
+	}
+	defer l.Close()
+
+	if msgs := replayAll(t, l); len(msgs) != 2 {
+		t.Fatalf("Expected 2 intact messages, got %d", len(msgs))
+	}
+	if info, _ := os.Stat(path); info.Size() != validSize {
+		t.Errorf("Expected corrupt tail truncated to %d bytes, got %d", validSize, info.Size())
+	}
+
+	// Appends after recovery follow the intact records
+	if err := l.Append(&Message{Type: "tx", From: "peer1", Seq: 3}); err != nil {
+		t.Fatalf("Append after recovery failed: %v", err)
+	}
+	msgs := replayAll(t, l)
+	if len(msgs) != 3 || msgs[2].Seq != 3 {
+		t.Errorf("Expected 3 messages ending with seq 3, got %d", len(msgs))
+	}
+}
+
+func TestFileMessageLogChecksumMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.log")
+
+	l, err := OpenFileMessageLog(path)
+	if err != nil {
+		t.Fatalf("OpenFileMessageLog failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := l.Append(&Message{Type: "tx", From: "peer1", Seq: uint64(i + 1)}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	l.Close()
+
+	// Flip the last byte of the second record's payload
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	l, err = OpenFileMessageLog(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer l.Close()
+
+	msgs := replayAll(t, l)
+	if len(msgs) != 1 || msgs[0].Seq != 1 {
+		t.Errorf("Expected only the first message to survive, got %d", len(msgs))
+	}
+}
+
+func TestZmqNodeAppendsToMessageLog(t *testing.T) {
+	l, err := OpenFileMessageLog(filepath.Join(t.TempDir(), "messages.log"))
+	if err != nil {
+		t.Fatalf("OpenFileMessageLog failed: %v", err)
+	}
+	defer l.Close()
+
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	node.SetMessageLog(l)
+
+	raw := `{"type":"block","from":"peer1","payload":{"k":"v"},"timestamp":"` +
+		time.Now().UTC().Format(time.RFC3339Nano) + `"}`
+	if !node.handleFrames([][]byte{[]byte("peer1"), []byte(raw)}) {
+		t.Fatal("Message should be accepted")
+	}
+
+	msgs := replayAll(t, l)
+	if len(msgs) != 1 || msgs[0].Type != "block" || msgs[0].Payload["k"] != "v" {
+		t.Errorf("Expected the received message in the log, got %v", msgs)
+	}
+
+	// A closed log rejects the message instead of processing it unrecorded
+	l.Close()
+	if node.handleFrames([][]byte{[]byte("peer1"), []byte(raw)}) {
+		t.Error("Message should be dropped when the log cannot be written")
+	}
+}
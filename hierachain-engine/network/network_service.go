@@ -13,6 +13,12 @@ type NetworkConfig struct {
 	Host      string   `json:"host"`
 	Port      int      `json:"port"`
 	SeedNodes []string `json:"seed_nodes"`
+
+	// Observer nodes receive and relay gossip but never announce
+	// themselves or originate broadcasts, so peers do not advertise them.
+	Observer bool `json:"observer"`
+
+	Zmq ZmqOptions `json:"zmq"`
 }
 
 // DefaultNetworkConfig returns a configuration with sensible defaults.
@@ -22,6 +28,7 @@ func DefaultNetworkConfig() NetworkConfig {
 		Host:      "127.0.0.1",
 		Port:      5555,
 		SeedNodes: []string{},
+		Zmq:       DefaultZmqOptions(),
 	}
 }
 
@@ -49,6 +56,7 @@ type NetworkService struct {
 // NewNetworkService creates a new network service with the given configuration.
 func NewNetworkService(config NetworkConfig) *NetworkService {
 	node := NewZmqNode(config.NodeID, config.Host, config.Port)
+	node.SetOptions(config.Zmq)
 	p2p := NewP2PManager(node)
 	propagator := NewPropagator(node)
 
@@ -87,9 +95,11 @@ func (ns *NetworkService) Start() error {
 		}
 	}
 
-	// Announce ourselves to the network
-	if err := ns.p2p.AnnounceSelf(); err != nil {
-		log.Printf("Warning: self-announce failed: %v", err)
+	// Announce ourselves to the network; observers stay unlisted
+	if !ns.config.Observer {
+		if err := ns.p2p.AnnounceSelf(); err != nil {
+			log.Printf("Warning: self-announce failed: %v", err)
+		}
 	}
 
 	ns.running = true
@@ -134,6 +144,7 @@ func (ns *NetworkService) GetStatus() NetworkStatus {
 }
 
 // BroadcastBlock propagates a block to all peers in the network.
+// Observer nodes return ErrObserverMode.
 func (ns *NetworkService) BroadcastBlock(blockData []byte) error {
 	ns.mu.RLock()
 	defer ns.mu.RUnlock()
@@ -141,11 +152,15 @@ func (ns *NetworkService) BroadcastBlock(blockData []byte) error {
 	if !ns.running {
 		return ErrNodeNotRunning
 	}
+	if ns.config.Observer {
+		return ErrObserverMode
+	}
 
 	return ns.propagator.PropagateBlock(blockData)
 }
 
 // BroadcastTransaction propagates a transaction to all peers in the network.
+// Observer nodes return ErrObserverMode.
 func (ns *NetworkService) BroadcastTransaction(txData []byte) error {
 	ns.mu.RLock()
 	defer ns.mu.RUnlock()
@@ -153,6 +168,9 @@ func (ns *NetworkService) BroadcastTransaction(txData []byte) error {
 	if !ns.running {
 		return ErrNodeNotRunning
 	}
+	if ns.config.Observer {
+		return ErrObserverMode
+	}
 
 	return ns.propagator.PropagateTransaction(txData)
 }
@@ -194,11 +212,22 @@ func (ns *NetworkService) SetMessageHandler(handler MessageHandler) {
 	ns.node.SetHandler(handler)
 }
 
+// Subscribe registers handler for propagated messages on topic, such as
+// TopicBlock or TopicTransaction. See Propagator.Subscribe.
+func (ns *NetworkService) Subscribe(topic string, handler MessageHandler) {
+	ns.propagator.Subscribe(topic, handler)
+}
+
 // GetPropagatorStats returns propagation statistics.
 func (ns *NetworkService) GetPropagatorStats() PropagatorStats {
 	return ns.propagator.GetStats()
 }
 
+// IsObserver returns whether the service runs as a passive observer.
+func (ns *NetworkService) IsObserver() bool {
+	return ns.config.Observer
+}
+
 // IsRunning returns whether the service is currently running.
 func (ns *NetworkService) IsRunning() bool {
 	ns.mu.RLock()
@@ -2,6 +2,7 @@ package network
 
 import (
 	"testing"
+	"time"
 )
 
 func TestNewNetworkService(t *testing.T) {
@@ -125,3 +126,134 @@ func TestNetworkServiceSendDirectBeforeStart(t *testing.T) {
 		t.Errorf("Expected ErrNodeNotRunning, got %v", err)
 	}
 }
+
+func TestNetworkServiceObserver(t *testing.T) {
+	newService := func(id string, port int, observer bool, seeds ...string) *NetworkService {
+		config := DefaultNetworkConfig()
+		config.NodeID = id
+		config.Port = port
+		config.SeedNodes = seeds
+		config.Observer = observer
+		return NewNetworkService(config)
+	}
+
+	const seedAddr = "tcp://127.0.0.1:15781"
+	seed := newService("node-s", 15781, false)
+	observer := newService("node-o", 15783, true, seedAddr)
+	regular := newService("node-n", 15782, false, seedAddr)
+
+	for _, ns := range []*NetworkService{seed, observer, regular} {
+		if err := ns.Start(); err != nil {
+			t.Fatalf("Failed to start %s: %v", ns.config.NodeID, err)
+		}
+		defer ns.Stop()
+	}
+
+	// The regular node announces itself to the seed; the observer does not
+	known := func(id string) bool {
+		seed.p2p.mu.RLock()
+		defer seed.p2p.mu.RUnlock()
+		_, ok := seed.p2p.knownPeers[id]
+		return ok
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !known("node-n") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !known("node-n") {
+		t.Fatal("Seed never learned about the regular node")
+	}
+	if known("node-o") {
+		t.Error("Observer should not be advertised by the seed")
+	}
+
+	// Observers cannot originate broadcasts
+	if err := observer.BroadcastBlock([]byte("block")); err != ErrObserverMode {
+		t.Errorf("Expected ErrObserverMode for block, got %v", err)
+	}
+	if err := observer.BroadcastTransaction([]byte("tx")); err != ErrObserverMode {
+		t.Errorf("Expected ErrObserverMode for transaction, got %v", err)
+	}
+
+	// ...but still relay gossip they receive
+	received := make(chan *Message, 1)
+	seed.SetMessageHandler(func(msg *Message) error {
+		if msg.From == "node-o" {
+			received <- msg
+		}
+		return nil
+	})
+	if err := observer.node.ConnectPeer(seedAddr, 5*time.Second); err != nil {
+		t.Fatalf("ConnectPeer failed: %v", err)
+	}
+	gossip := &Message{
+		Type:      "block",
+		From:      "node-x",
+		Payload:   map[string]interface{}{"action": "new_block", "data": "relayed"},
+		Timestamp: time.Now(),
+	}
+	if !observer.propagator.HandleIncoming(gossip) {
+		t.Fatal("Observer should process incoming gossip")
+	}
+
+	select {
+	case msg := <-received:
+		if msg.Payload["data"] != "relayed" {
+			t.Errorf("Unexpected relayed payload: %v", msg.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Observer did not relay gossip")
+	}
+}
+
+func TestNetworkServicePropagatorCounters(t *testing.T) {
+	config := DefaultNetworkConfig()
+	config.Port = 15824
+	ns := NewNetworkService(config)
+	if err := ns.Start(); err != nil {
+		t.Fatalf("Failed to start network service: %v", err)
+	}
+	defer ns.Stop()
+
+	if err := ns.propagator.Propagate("block", map[string]interface{}{"height": 1}); err != nil {
+		t.Fatalf("Propagate failed: %v", err)
+	}
+
+	msg := &Message{
+		Type:      "block",
+		From:      "peer1",
+		Payload:   map[string]interface{}{"height": 2},
+		Timestamp: time.Now(),
+	}
+	if !ns.propagator.HandleIncoming(msg) {
+		t.Fatal("First delivery should be processed")
+	}
+	if ns.propagator.HandleIncoming(msg) {
+		t.Error("Duplicate delivery should be dropped")
+	}
+
+	atLimit := &Message{
+		Type:      "block",
+		From:      "peer1",
+		Payload:   map[string]interface{}{"height": 3},
+		Timestamp: time.Now(),
+		Hops:      ns.propagator.GetStats().MaxHops,
+	}
+	if !ns.propagator.HandleIncoming(atLimit) {
+		t.Error("Message at the hop limit should still be processed")
+	}
+
+	stats := ns.GetPropagatorStats()
+	if stats.MessagesOriginated != 1 {
+		t.Errorf("Expected 1 originated message, got %d", stats.MessagesOriginated)
+	}
+	if stats.MessagesForwarded != 1 {
+		t.Errorf("Expected 1 forwarded message, got %d", stats.MessagesForwarded)
+	}
+	if stats.DuplicatesDropped != 1 {
+		t.Errorf("Expected 1 duplicate dropped, got %d", stats.DuplicatesDropped)
+	}
+	if stats.HopLimitReached != 1 {
+		t.Errorf("Expected 1 message at the hop limit, got %d", stats.HopLimitReached)
+	}
+}
@@ -1,8 +1,19 @@
 package network
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/go-zeromq/zmq4"
+	"github.com/go-zeromq/zmq4/security/null"
 )
 
 func TestNewZmqNode(t *testing.T) {
@@ -155,3 +166,1564 @@ func TestP2PManagerGetHealthyPeers(t *testing.T) {
 		t.Errorf("Expected 1 healthy peer, got %d", len(healthy))
 	}
 }
+
+func TestPropagatorSuppressesDuplicateBroadcast(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 0)
+	if err := node.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer node.Stop()
+
+	prop := NewPropagator(node)
+
+	txData := []byte(`{"tx_id":"tx-1"}`)
+	if err := prop.PropagateTransaction(txData); err != nil {
+		t.Fatalf("First broadcast failed: %v", err)
+	}
+	if err := prop.PropagateTransaction(txData); err != nil {
+		t.Fatalf("Duplicate broadcast failed: %v", err)
+	}
+
+	stats := prop.GetStats()
+	if stats.SuppressedBroadcasts != 1 {
+		t.Errorf("Expected 1 suppressed broadcast, got %d", stats.SuppressedBroadcasts)
+	}
+
+	// Different content is not suppressed
+	if err := prop.PropagateTransaction([]byte(`{"tx_id":"tx-2"}`)); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+	if stats := prop.GetStats(); stats.SuppressedBroadcasts != 1 {
+		t.Errorf("Expected 1 suppressed broadcast, got %d", stats.SuppressedBroadcasts)
+	}
+}
+
+func TestP2PManagerRebootstrapFromSeeds(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	p2p := NewP2PManager(node)
+
+	seeds := []string{"tcp://127.0.0.1:6001", "tcp://127.0.0.1:6002"}
+	_ = p2p.DiscoverPeers(seeds)
+
+	// Age every peer past the stale timeout, in the manager and in the
+	// node it syncs from, and prune them all
+	stale := time.Now().Add(-2 * p2p.staleTimeout)
+	p2p.mu.Lock()
+	for _, peer := range p2p.knownPeers {
+		peer.LastSeen = stale
+	}
+	p2p.mu.Unlock()
+	node.mu.Lock()
+	for _, peer := range node.peers {
+		peer.LastSeen = stale
+	}
+	node.mu.Unlock()
+	p2p.prune()
+
+	if p2p.PeerCount() != 0 {
+		t.Fatalf("Expected 0 peers after prune, got %d", p2p.PeerCount())
+	}
+
+	if !p2p.rebootstrapIfNeeded() {
+		t.Fatal("Expected seeds to be re-contacted")
+	}
+
+	if p2p.PeerCount() != len(seeds) {
+		t.Errorf("Expected %d peers after re-bootstrap, got %d", len(seeds), p2p.PeerCount())
+	}
+	peers := node.GetPeers()
+	for _, seed := range seeds {
+		if peers[seed] == nil {
+			t.Errorf("Seed %s not re-registered with node", seed)
+		}
+	}
+
+	// Above the minimum, no further discovery happens
+	if p2p.rebootstrapIfNeeded() {
+		t.Error("Re-bootstrap should not trigger with healthy peer count")
+	}
+}
+
+func TestP2PManagerMaxPeers(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	p2p := NewP2PManager(node)
+	if p2p.maxPeers != DefaultMaxPeers {
+		t.Errorf("Expected default max peers %d, got %d", DefaultMaxPeers, p2p.maxPeers)
+	}
+	p2p.SetMaxPeers(100)
+
+	seed := "tcp://127.0.0.1:6001"
+	_ = p2p.DiscoverPeers([]string{seed})
+
+	// A flood of gossiped peers does not grow the table past the limit
+	peers := make([]interface{}, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		peers = append(peers, map[string]interface{}{
+			"id":      fmt.Sprintf("fake-%d", i),
+			"address": fmt.Sprintf("tcp://10.0.%d.%d:5555", i/256, i%256),
+		})
+	}
+	_ = p2p.handlePeerExchangeResponse(&Message{Payload: map[string]interface{}{"peers": peers}})
+
+	if count := p2p.PeerCount(); count != 100 {
+		t.Errorf("Expected 100 known peers, got %d", count)
+	}
+	if count := len(node.GetPeers()); count != 100 {
+		t.Errorf("Expected 100 peers registered with the node, got %d", count)
+	}
+
+	// Announced peers displace only stale non-seed peers
+	_ = p2p.handlePeerAnnounce(&Message{Payload: map[string]interface{}{"peer_id": "late", "address": "tcp://10.1.0.1:5555"}})
+	if p2p.PeerCount() != 100 || p2p.knownPeers["late"] != nil {
+		t.Error("Expected announced peer to be ignored while no peer is stale")
+	}
+
+	stale := time.Now().Add(-2 * p2p.staleTimeout)
+	p2p.mu.Lock()
+	p2p.knownPeers[seed].LastSeen = stale.Add(-time.Hour)
+	p2p.knownPeers["fake-0"].LastSeen = stale
+	p2p.mu.Unlock()
+
+	_ = p2p.handlePeerAnnounce(&Message{Payload: map[string]interface{}{"peer_id": "late", "address": "tcp://10.1.0.1:5555"}})
+	if p2p.knownPeers["late"] == nil {
+		t.Error("Expected announced peer to displace a stale peer")
+	}
+	if p2p.knownPeers["fake-0"] != nil {
+		t.Error("Expected stale gossiped peer to be displaced")
+	}
+	if p2p.knownPeers[seed] == nil {
+		t.Error("Expected seed to be exempt from displacement")
+	}
+	if count := p2p.PeerCount(); count != 100 {
+		t.Errorf("Expected 100 known peers, got %d", count)
+	}
+}
+
+func TestP2PManagerSaveLoadPeers(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	p2p := NewP2PManager(node)
+
+	key := []byte("peer1-key")
+	node.RegisterPeer("peer1", "tcp://127.0.0.1:6001", key)
+	p2p.knownPeers["peer1"] = &PeerInfo{ID: "peer1", Address: "tcp://127.0.0.1:6001", LastSeen: time.Now()}
+	p2p.knownPeers["peer2"] = &PeerInfo{ID: "peer2", Address: "tcp://127.0.0.1:6002", LastSeen: time.Now()}
+	p2p.knownPeers["old"] = &PeerInfo{
+		ID:       "old",
+		Address:  "tcp://127.0.0.1:6003",
+		LastSeen: time.Now().Add(-2 * p2p.staleTimeout),
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "peers.json")
+	if err := p2p.SavePeers(path); err != nil {
+		t.Fatalf("SavePeers failed: %v", err)
+	}
+	// Saving again replaces the file without leaving temporary files
+	if err := p2p.SavePeers(path); err != nil {
+		t.Fatalf("Second SavePeers failed: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the peer store in %s, got %d entries", dir, len(entries))
+	}
+
+	restarted := NewZmqNode("test-node", "127.0.0.1", 5555)
+	reloaded := NewP2PManager(restarted)
+	if err := reloaded.LoadPeers(path); err != nil {
+		t.Fatalf("LoadPeers failed: %v", err)
+	}
+
+	if reloaded.PeerCount() != 2 {
+		t.Errorf("Expected 2 peers after load, got %d", reloaded.PeerCount())
+	}
+	if reloaded.knownPeers["old"] != nil {
+		t.Error("Expected stale peer to be skipped on load")
+	}
+	nodePeers := restarted.GetPeers()
+	if peer := nodePeers["peer1"]; peer == nil || string(peer.PublicKey) != string(key) {
+		t.Errorf("Expected peer1 registered with its public key, got %+v", peer)
+	}
+	if nodePeers["peer2"] == nil {
+		t.Error("Expected peer2 registered with the node")
+	}
+
+	if err := reloaded.LoadPeers(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist for a missing store, got %v", err)
+	}
+}
+
+func TestP2PManagerVersionNegotiation(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	p2p := NewP2PManager(node)
+	p2p.SetCapabilities([]string{CapabilityCompression})
+
+	// Our own announcements carry the version and capabilities
+	ad := p2p.advertise(map[string]interface{}{"action": "peer_announce"})
+	if ad["version"] != ProtocolVersion {
+		t.Errorf("Expected advertised version %s, got %v", ProtocolVersion, ad["version"])
+	}
+	if caps := parseCapabilities(ad["capabilities"]); len(caps) != 1 || caps[0] != CapabilityCompression {
+		t.Errorf("Expected advertised capabilities [compression], got %v", caps)
+	}
+
+	_ = p2p.handlePeerAnnounce(&Message{From: "fast", Payload: map[string]interface{}{
+		"peer_id":      "fast",
+		"address":      "tcp://127.0.0.1:6001",
+		"version":      "1.4",
+		"capabilities": []interface{}{CapabilityCompression},
+	}})
+	_ = p2p.handlePeerExchangeResponse(&Message{From: "seed", Payload: map[string]interface{}{
+		"peers": []interface{}{
+			map[string]interface{}{"id": "legacy", "address": "tcp://127.0.0.1:6002"},
+			map[string]interface{}{"id": "future", "address": "tcp://127.0.0.1:6003", "version": "2.0"},
+		},
+	}})
+
+	if p2p.PeerCount() != 3 {
+		t.Errorf("Expected incompatible peers to be logged but kept by default, got %d peers", p2p.PeerCount())
+	}
+	if peer := p2p.knownPeers["fast"]; peer == nil || peer.Version != "1.4" {
+		t.Errorf("Expected fast peer with version 1.4, got %+v", peer)
+	}
+
+	compressed := p2p.GetPeersByCapability(CapabilityCompression)
+	if len(compressed) != 1 || compressed[0].ID != "fast" {
+		t.Errorf("Expected only fast peer to advertise compression, got %+v", compressed)
+	}
+
+	// A legacy peer's version is learned from its exchange request
+	_ = p2p.handlePeerExchangeRequest(&Message{From: "legacy", Payload: map[string]interface{}{
+		"action":       "peer_exchange_request",
+		"version":      "1.0",
+		"capabilities": []interface{}{CapabilityCompression},
+	}})
+	if got := len(p2p.GetPeersByCapability(CapabilityCompression)); got != 2 {
+		t.Errorf("Expected 2 peers with compression after handshake, got %d", got)
+	}
+
+	// With refusal on, incompatible peers are dropped and not added
+	p2p.SetRefuseIncompatible(true)
+	_ = p2p.handlePeerAnnounce(&Message{From: "future", Payload: map[string]interface{}{
+		"peer_id": "future",
+		"address": "tcp://127.0.0.1:6003",
+		"version": "2.0",
+	}})
+	_ = p2p.handlePeerAnnounce(&Message{From: "other", Payload: map[string]interface{}{
+		"peer_id": "other",
+		"address": "tcp://127.0.0.1:6004",
+		"version": "3.1",
+	}})
+	if p2p.knownPeers["future"] != nil || p2p.knownPeers["other"] != nil {
+		t.Error("Expected incompatible peers to be refused")
+	}
+	if node.GetPeers()["future"] != nil {
+		t.Error("Expected refused peer to be unregistered from the node")
+	}
+}
+
+func TestZmqNodeConnectPeer(t *testing.T) {
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15771)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15772)
+
+	received := make(chan *Message, 1)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	if err := nodeA.ConnectPeer("node-b", time.Second); err != ErrPeerNotFound {
+		t.Errorf("Expected ErrPeerNotFound for unknown peer, got %v", err)
+	}
+
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15772", nil)
+	if err := nodeA.ConnectPeer("node-b", 5*time.Second); err != nil {
+		t.Fatalf("ConnectPeer failed: %v", err)
+	}
+
+	// Connecting again is a no-op
+	if err := nodeA.ConnectPeer("node-b", 5*time.Second); err != nil {
+		t.Fatalf("Repeated ConnectPeer failed: %v", err)
+	}
+
+	if err := nodeA.SendDirect("node-b", map[string]interface{}{"hello": "world"}); err != nil {
+		t.Fatalf("First send after ConnectPeer failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.From != "node-a" || msg.Payload["hello"] != "world" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+}
+
+func TestZmqNodeFlush(t *testing.T) {
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15773)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15774)
+
+	const numSends = 20
+	received := make(chan *Message, numSends)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15774", nil)
+	if err := nodeA.ConnectPeer("node-b", 5*time.Second); err != nil {
+		t.Fatalf("ConnectPeer failed: %v", err)
+	}
+
+	// Send right before shutting down, as after broadcasting a block
+	for i := 0; i < numSends; i++ {
+		if err := nodeA.Broadcast(map[string]interface{}{"i": i}, nil); err != nil {
+			t.Fatalf("Broadcast %d failed: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nodeA.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	nodeA.Stop()
+
+	for i := 0; i < numSends; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Received %d of %d messages", i, numSends)
+		}
+	}
+}
+
+func TestZmqNodePruneWhileSending(t *testing.T) {
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15775)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15776)
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	const peerAddr = "tcp://127.0.0.1:15776"
+	nodeA.RegisterPeer("node-b", peerAddr, nil)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 100)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				err := nodeA.SendDirect("node-b", map[string]interface{}{"k": "v"})
+				if err != nil && !errors.Is(err, ErrPeerNotFound) && !errors.Is(err, ErrSendFailed) {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}()
+	}
+
+	// Prune and re-add the peer while sends are in flight, as the P2P
+	// manager does for a peer that goes stale and is rediscovered
+	for i := 0; i < 20; i++ {
+		nodeA.UnregisterPeer("node-b")
+		time.Sleep(time.Millisecond)
+		nodeA.RegisterPeer("node-b", peerAddr, nil)
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Unexpected send error: %v", err)
+	}
+
+	// Once pruned, the peer gets no new dealer
+	nodeA.UnregisterPeer("node-b")
+	if err := nodeA.SendDirect("node-b", map[string]interface{}{"k": "v"}); !errors.Is(err, ErrPeerNotFound) {
+		t.Errorf("Expected ErrPeerNotFound after prune, got %v", err)
+	}
+	if err := nodeA.ConnectPeer("node-b", time.Second); !errors.Is(err, ErrPeerNotFound) {
+		t.Errorf("Expected ErrPeerNotFound connecting a pruned peer, got %v", err)
+	}
+
+	nodeA.mu.RLock()
+	dealers := len(nodeA.dealers)
+	nodeA.mu.RUnlock()
+	if dealers != 0 {
+		t.Errorf("Expected no dealers after prune, got %d", dealers)
+	}
+}
+
+func TestZmqNodeFlushTimeout(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+
+	// Nothing in flight
+	if err := node.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush with no sends failed: %v", err)
+	}
+
+	// A stuck send holds Flush until the context ends
+	node.beginSend()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := node.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- node.Flush(context.Background())
+	}()
+	node.endSend()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Flush failed after send finished: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Flush did not return after send finished")
+	}
+}
+
+func TestZmqNodeSequenceOrdering(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+
+	msg := func(seq uint64) *Message {
+		return &Message{Type: "direct", From: "peer1", Seq: seq}
+	}
+
+	if !node.acceptSequence(msg(1)) || !node.acceptSequence(msg(2)) {
+		t.Fatal("In-order messages should be accepted")
+	}
+
+	// Duplicate after reconnect
+	if node.acceptSequence(msg(2)) {
+		t.Error("Duplicate sequence should be rejected")
+	}
+
+	// Gap: 3 is missing
+	if !node.acceptSequence(msg(4)) {
+		t.Error("Message after a gap should be accepted")
+	}
+
+	// Late arrival of the missing message is now stale
+	if node.acceptSequence(msg(3)) {
+		t.Error("Out-of-order older sequence should be rejected")
+	}
+
+	// Other senders are tracked independently
+	if !node.acceptSequence(&Message{From: "peer2", Seq: 1}) {
+		t.Error("Sequence from a different sender should be accepted")
+	}
+
+	// Unsequenced messages are not checked
+	if !node.acceptSequence(&Message{From: "peer1"}) {
+		t.Error("Message without a sequence should be accepted")
+	}
+
+	stats := node.GetStats()
+	if stats.SequenceGaps != 1 {
+		t.Errorf("Expected 1 sequence gap, got %d", stats.SequenceGaps)
+	}
+	if stats.SequenceRejected != 2 {
+		t.Errorf("Expected 2 rejected messages, got %d", stats.SequenceRejected)
+	}
+
+	// Outgoing sequences increase per peer
+	if a, b, c := node.nextSeq("peer1"), node.nextSeq("peer1"), node.nextSeq("peer2"); a != 1 || b != 2 || c != 1 {
+		t.Errorf("Expected per-peer sequences 1, 2, 1; got %d, %d, %d", a, b, c)
+	}
+}
+
+func TestZmqNodeSocketOptions(t *testing.T) {
+	config := DefaultNetworkConfig()
+	if config.Zmq != DefaultZmqOptions() {
+		t.Errorf("Expected default ZMQ options in config, got %+v", config.Zmq)
+	}
+
+	opts := ZmqOptions{HWM: 10, Timeout: time.Second, Linger: 100 * time.Millisecond}
+	node := NewZmqNode("opts-node", "127.0.0.1", 15781)
+	node.SetOptions(opts)
+
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+
+	// Dealers are created with the configured options
+	node.RegisterPeer("peer1", "tcp://127.0.0.1:15782", nil)
+	_ = node.ConnectPeer("peer1", 2*time.Second)
+
+	node.mu.RLock()
+	if node.options != opts {
+		t.Errorf("Expected options %+v, got %+v", opts, node.options)
+	}
+	if hwm, err := node.router.GetOption(zmq4.OptionHWM); err != nil || hwm != opts.HWM {
+		t.Errorf("Expected router HWM %d, got %v (err %v)", opts.HWM, hwm, err)
+	}
+	node.mu.RUnlock()
+
+	// Linger bounds how long Stop waits on socket close
+	start := time.Now()
+	node.Stop()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Stop took %v, expected it bounded by linger", elapsed)
+	}
+}
+
+// recordingSecurity hands out NULL security and records the peer keys
+// its client sockets were created for.
+type recordingSecurity struct {
+	mu         sync.Mutex
+	servers    int
+	clientKeys [][]byte
+}
+
+func (r *recordingSecurity) Server() zmq4.Security {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.servers++
+	return null.Security()
+}
+
+func (r *recordingSecurity) Client(peerPublicKey []byte) zmq4.Security {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clientKeys = append(r.clientKeys, peerPublicKey)
+	return null.Security()
+}
+
+func TestZmqNodeWithSecurity(t *testing.T) {
+	secA := &recordingSecurity{}
+	secB := &recordingSecurity{}
+	nodeA := NewZmqNodeWithSecurity("node-a", "127.0.0.1", 15801, secA)
+	nodeB := NewZmqNodeWithSecurity("node-b", "127.0.0.1", 15802, secB)
+
+	received := make(chan *Message, 1)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	peerKey := []byte("node-b-public-key")
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15802", peerKey)
+	if err := nodeA.SendDirect("node-b", map[string]interface{}{"hello": "world"}); err != nil {
+		t.Fatalf("SendDirect failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.Payload["hello"] != "world" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	secA.mu.Lock()
+	defer secA.mu.Unlock()
+	if secA.servers != 1 {
+		t.Errorf("Expected 1 server socket, got %d", secA.servers)
+	}
+	if len(secA.clientKeys) != 1 || string(secA.clientKeys[0]) != string(peerKey) {
+		t.Errorf("Expected client socket for key %q, got %q", peerKey, secA.clientKeys)
+	}
+}
+
+func TestZmqNodeMessageSigning(t *testing.T) {
+	pubA, privA, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	_, privOther, _ := ed25519.GenerateKey(nil)
+
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15803)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15804)
+	nodeA.SetSigningKey(privA)
+
+	received := make(chan *Message, 1)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15804", nil)
+	nodeB.RegisterPeer("node-a", "tcp://127.0.0.1:15803", nil)
+	if err := nodeB.SetPeerSigningKey("node-a", pubA); err != nil {
+		t.Fatalf("SetPeerSigningKey failed: %v", err)
+	}
+	if err := nodeB.SetPeerSigningKey("unknown", pubA); !errors.Is(err, ErrPeerNotFound) {
+		t.Errorf("Expected ErrPeerNotFound for an unknown peer, got %v", err)
+	}
+
+	// Payload values that change form when decoded still verify
+	payload := map[string]interface{}{
+		"big":    int64(1) << 60,
+		"nested": struct{ B, A int }{1, 2},
+	}
+	if err := nodeA.SendDirect("node-b", payload); err != nil {
+		t.Fatalf("SendDirect failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if len(msg.Signature) != ed25519.SignatureSize {
+			t.Errorf("Expected signed message, got signature %x", msg.Signature)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for signed message")
+	}
+
+	// Messages claiming to be from node-a without its signature are dropped
+	forged := &Message{
+		Type:      "direct",
+		From:      "node-a",
+		Payload:   map[string]interface{}{"k": "v"},
+		Timestamp: time.Now(),
+	}
+	unsigned, _ := json.Marshal(forged)
+	if nodeB.handleFrames([][]byte{[]byte("node-a"), unsigned}) {
+		t.Error("Unsigned message from a keyed peer should be dropped")
+	}
+
+	data, _ := signingBytes(forged)
+	forged.Signature = ed25519.Sign(privOther, data)
+	wrongKey, _ := json.Marshal(forged)
+	if nodeB.handleFrames([][]byte{[]byte("node-a"), wrongKey}) {
+		t.Error("Message signed with another key should be dropped")
+	}
+
+	if failures := nodeB.GetStats().SignatureFailures; failures != 2 {
+		t.Errorf("Expected 2 signature failures, got %d", failures)
+	}
+
+	// Peers without a registered key are not checked
+	forged.From = "node-c"
+	forged.Signature = nil
+	unkeyed, _ := json.Marshal(forged)
+	if !nodeB.handleFrames([][]byte{[]byte("node-c"), unkeyed}) {
+		t.Error("Unsigned message from an unkeyed sender should be accepted")
+	}
+}
+
+func TestZmqNodeSigningWithSecurity(t *testing.T) {
+	pubA, privA, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	secA := &recordingSecurity{}
+	nodeA := NewZmqNodeWithSecurity("node-a", "127.0.0.1", 15830, secA)
+	nodeB := NewZmqNodeWithSecurity("node-b", "127.0.0.1", 15831, &recordingSecurity{})
+	nodeA.SetSigningKey(privA)
+
+	received := make(chan *Message, 1)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	// Transport keys and signing keys are separate
+	transportKey := []byte("node-b-transport-key")
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15831", transportKey)
+	nodeB.RegisterPeer("node-a", "tcp://127.0.0.1:15830", []byte("node-a-transport-key"))
+	if err := nodeB.SetPeerSigningKey("node-a", pubA); err != nil {
+		t.Fatalf("SetPeerSigningKey failed: %v", err)
+	}
+
+	if err := nodeA.SendDirect("node-b", map[string]interface{}{"hello": "world"}); err != nil {
+		t.Fatalf("SendDirect failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if len(msg.Signature) != ed25519.SignatureSize {
+			t.Errorf("Expected signed message, got signature %x", msg.Signature)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for signed message")
+	}
+	if failures := nodeB.GetStats().SignatureFailures; failures != 0 {
+		t.Errorf("Expected no signature failures, got %d", failures)
+	}
+
+	secA.mu.Lock()
+	defer secA.mu.Unlock()
+	if len(secA.clientKeys) != 1 || string(secA.clientKeys[0]) != string(transportKey) {
+		t.Errorf("Expected client socket for key %q, got %q", transportKey, secA.clientKeys)
+	}
+
+	// Registering again keeps the signing key
+	nodeB.RegisterPeer("node-a", "tcp://127.0.0.1:15830", nil)
+	if key := nodeB.GetPeers()["node-a"].SigningKey; string(key) != string(pubA) {
+		t.Errorf("Expected the signing key kept on re-registration, got %x", key)
+	}
+}
+
+func TestZmqNodeSendReliable(t *testing.T) {
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15805)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15806)
+
+	received := make(chan *Message, 10)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15806", nil)
+
+	// node-b cannot reach node-a to acknowledge, so every attempt times out
+	err := nodeA.SendReliable("node-b", map[string]interface{}{"n": 1}, 200*time.Millisecond)
+	if !errors.Is(err, ErrAckTimeout) {
+		t.Fatalf("Expected ErrAckTimeout, got %v", err)
+	}
+	if retransmits := nodeA.GetStats().Retransmits; retransmits != MaxReliableAttempts-1 {
+		t.Errorf("Expected %d retransmits, got %d", MaxReliableAttempts-1, retransmits)
+	}
+
+	// Retransmissions are delivered to the handler only once
+	select {
+	case msg := <-received:
+		if !msg.Reliable || msg.ID == "" {
+			t.Errorf("Expected reliable message with an ID, got %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for reliable message")
+	}
+	select {
+	case msg := <-received:
+		t.Errorf("Expected retransmissions to be deduplicated, got %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Once node-b can reply, the send is acknowledged and the ack itself
+	// is neither delivered nor acknowledged
+	nodeB.RegisterPeer("node-a", "tcp://127.0.0.1:15805", nil)
+	if err := nodeA.SendReliable("node-b", map[string]interface{}{"n": 2}, 5*time.Second); err != nil {
+		t.Fatalf("SendReliable failed: %v", err)
+	}
+	select {
+	case msg := <-received:
+		if msg.Payload["n"] != float64(2) {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for acknowledged message")
+	}
+
+	nodeA.ackMu.Lock()
+	pending := len(nodeA.pendingAcks)
+	nodeA.ackMu.Unlock()
+	if pending != 0 {
+		t.Errorf("Expected no pending acks, got %d", pending)
+	}
+
+	if err := nodeA.SendReliable("node-c", nil, time.Second); err != ErrPeerNotFound {
+		t.Errorf("Expected ErrPeerNotFound, got %v", err)
+	}
+}
+
+func TestZmqNodeHeartbeat(t *testing.T) {
+	opts := DefaultZmqOptions()
+	if opts.HeartbeatInterval != 20*time.Second {
+		t.Errorf("Expected default heartbeat interval 20s, got %v", opts.HeartbeatInterval)
+	}
+	opts.HeartbeatInterval = 50 * time.Millisecond
+
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15807)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15808)
+	nodeA.SetOptions(opts)
+
+	handled := make(chan *Message, 10)
+	for _, node := range []*ZmqNode{nodeA, nodeB} {
+		node.SetHandler(func(msg *Message) error {
+			handled <- msg
+			return nil
+		})
+	}
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	stale := time.Now().Add(-time.Hour)
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15808", nil)
+	nodeB.RegisterPeer("node-a", "tcp://127.0.0.1:15807", nil)
+	for _, node := range []*ZmqNode{nodeA, nodeB} {
+		node.mu.Lock()
+		for _, peer := range node.peers {
+			peer.LastSeen = stale
+		}
+		node.mu.Unlock()
+	}
+
+	// node-a's pings refresh node-b's view of it, and node-b's pongs
+	// refresh node-a's view of node-b
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		seenByA := nodeA.GetPeers()["node-b"].LastSeen
+		seenByB := nodeB.GetPeers()["node-a"].LastSeen
+		if seenByA.After(stale) && seenByB.After(stale) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("LastSeen not refreshed: node-a sees %v, node-b sees %v", seenByA, seenByB)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// node-b was never dialled, so its pings went through temporary dealers
+	nodeA.mu.RLock()
+	pooled := len(nodeA.dealers)
+	nodeA.mu.RUnlock()
+	if pooled != 0 {
+		t.Errorf("Expected pings to leave the dealer pool empty, got %d dealers", pooled)
+	}
+
+	// P2PManager picks up the node's LastSeen instead of pruning the peer
+	p2p := NewP2PManager(nodeA)
+	p2p.knownPeers["node-b"] = &PeerInfo{ID: "node-b", Address: "tcp://127.0.0.1:15808", LastSeen: stale}
+	p2p.prune()
+	if p2p.PeerCount() != 1 {
+		t.Error("Expected heartbeating peer to survive pruning")
+	}
+
+	select {
+	case msg := <-handled:
+		t.Errorf("Expected heartbeats to bypass handlers, got %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestZmqNodeDealerPoolBounded(t *testing.T) {
+	if max := DefaultZmqOptions().MaxDealers; max != 256 {
+		t.Errorf("Expected default max dealers 256, got %d", max)
+	}
+
+	opts := DefaultZmqOptions()
+	opts.MaxDealers = 2
+	sender := NewZmqNode("sender", "127.0.0.1", 15809)
+	sender.SetOptions(opts)
+	if err := sender.Start(); err != nil {
+		t.Fatalf("Failed to start sender: %v", err)
+	}
+	defer sender.Stop()
+
+	received := make(chan string, 1000)
+	peerIDs := []string{"peer-1", "peer-2", "peer-3"}
+	for i, id := range peerIDs {
+		port := 15810 + i
+		peer := NewZmqNode(id, "127.0.0.1", port)
+		peerID := id
+		peer.SetHandler(func(msg *Message) error {
+			received <- peerID
+			return nil
+		})
+		if err := peer.Start(); err != nil {
+			t.Fatalf("Failed to start %s: %v", id, err)
+		}
+		defer peer.Stop()
+		sender.RegisterPeer(id, fmt.Sprintf("tcp://127.0.0.1:%d", port), nil)
+	}
+
+	for _, id := range peerIDs {
+		if err := sender.SendDirect(id, map[string]interface{}{"k": "v"}); err != nil {
+			t.Fatalf("Send to %s failed: %v", id, err)
+		}
+	}
+	if size := sender.GetStats().DialerPoolSize; size != 2 {
+		t.Errorf("Expected dealer pool capped at 2, got %d", size)
+	}
+	sender.mu.RLock()
+	_, kept := sender.dealers["peer-1"]
+	sender.mu.RUnlock()
+	if kept {
+		t.Error("Expected least recently used dealer to be evicted")
+	}
+
+	// Concurrent sends keep evicting dealers out from under each other
+	var wg sync.WaitGroup
+	errs := make(chan error, 300)
+	for i := 0; i < 100; i++ {
+		for _, id := range peerIDs {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				if err := sender.SendDirect(id, map[string]interface{}{"k": "v"}); err != nil {
+					errs <- err
+				}
+			}(id)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Concurrent send failed: %v", err)
+	}
+
+	// Every peer, including evicted ones, still receives messages
+	seen := make(map[string]bool)
+	deadline := time.After(5 * time.Second)
+	for len(seen) < len(peerIDs) {
+		select {
+		case id := <-received:
+			seen[id] = true
+		case <-deadline:
+			t.Fatalf("Only received messages from %v", seen)
+		}
+	}
+	if size := sender.GetStats().DialerPoolSize; size > 2 {
+		t.Errorf("Expected dealer pool capped at 2, got %d", size)
+	}
+}
+
+func TestZmqNodeHeartbeatKeepsDealerPool(t *testing.T) {
+	opts := DefaultZmqOptions()
+	opts.MaxDealers = 2
+	opts.HeartbeatInterval = 20 * time.Millisecond
+	sender := NewZmqNode("sender", "127.0.0.1", 15825)
+	sender.SetOptions(opts)
+	if err := sender.Start(); err != nil {
+		t.Fatalf("Failed to start sender: %v", err)
+	}
+	defer sender.Stop()
+
+	// peer-0 and peer-1 have no listener, so their pings fail
+	for i := 0; i < 4; i++ {
+		id, port := fmt.Sprintf("peer-%d", i), 15826+i
+		if i >= 2 {
+			peer := NewZmqNode(id, "127.0.0.1", port)
+			if err := peer.Start(); err != nil {
+				t.Fatalf("Failed to start %s: %v", id, err)
+			}
+			defer peer.Stop()
+		}
+		sender.RegisterPeer(id, fmt.Sprintf("tcp://127.0.0.1:%d", port), nil)
+	}
+	for _, id := range []string{"peer-2", "peer-3"} {
+		if err := sender.ConnectPeer(id, time.Second); err != nil {
+			t.Fatalf("ConnectPeer %s failed: %v", id, err)
+		}
+	}
+
+	snapshot := func() map[string]*peerDealer {
+		sender.mu.RLock()
+		defer sender.mu.RUnlock()
+		dealers := make(map[string]*peerDealer, len(sender.dealers))
+		for id, dealer := range sender.dealers {
+			dealers[id] = dealer
+		}
+		return dealers
+	}
+	before := snapshot()
+	sender.mu.RLock()
+	lastUsed := before["peer-2"].lastUsed
+	sender.mu.RUnlock()
+
+	// Several ticks with more peers than MaxDealers
+	time.Sleep(10 * opts.HeartbeatInterval)
+
+	after := snapshot()
+	if len(after) != 2 {
+		t.Fatalf("Expected 2 dealers, got %d", len(after))
+	}
+	for id, dealer := range before {
+		if after[id] != dealer {
+			t.Errorf("Expected dealer for %s to be kept across heartbeats", id)
+		}
+	}
+	sender.mu.RLock()
+	touched := after["peer-2"].lastUsed != lastUsed
+	sender.mu.RUnlock()
+	if touched {
+		t.Error("Expected pings not to mark dealers as used")
+	}
+}
+
+// blockPayload returns a block-sized payload as PropagateBlock builds it.
+func blockPayload(size int) map[string]interface{} {
+	var block []byte
+	for i := 0; len(block) < size; i++ {
+		block = append(block, fmt.Sprintf(`{"tx_id":"tx-%08d","from":"acct-%d","to":"acct-%d","amount":%d},`, i, i%97, i%89, i*7)...)
+	}
+	return map[string]interface{}{"action": "new_block", "data": string(block[:size])}
+}
+
+func TestZmqNodeCompression(t *testing.T) {
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15813)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15814)
+	nodeA.SetCompression(true, 0)
+
+	received := make(chan *Message, 2)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15814", nil)
+
+	// Small messages are sent as plain JSON, large ones compressed
+	large := blockPayload(64 * 1024)
+	for _, payload := range []map[string]interface{}{{"k": "v"}, large} {
+		if err := nodeA.SendDirect("node-b", payload); err != nil {
+			t.Fatalf("SendDirect failed: %v", err)
+		}
+		select {
+		case msg := <-received:
+			if msg.Payload["data"] != payload["data"] {
+				t.Error("Payload changed in transit")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for message")
+		}
+	}
+
+	if frame := encodeFrame([]byte(`{"k":"v"}`), true, DefaultCompressionMinSize); frame[0] != '{' {
+		t.Errorf("Expected small frame to stay plain, got flag %x", frame[0])
+	}
+
+	// A corrupt compressed frame is dropped and counted
+	if nodeB.handleFrames([][]byte{[]byte("node-a"), {zstdFrameFlag, 0xde, 0xad, 0xbe, 0xef}}) {
+		t.Error("Corrupt compressed message should be dropped")
+	}
+	if failures := nodeB.GetStats().DecompressFailures; failures != 1 {
+		t.Errorf("Expected 1 decompress failure, got %d", failures)
+	}
+}
+
+func BenchmarkMessageCompression(b *testing.B) {
+	msg := &Message{
+		Type:      MessageTypeDirect,
+		From:      "node-a",
+		To:        "node-b",
+		Payload:   blockPayload(1024 * 1024),
+		Timestamp: time.Now(),
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		b.Fatalf("Failed to marshal message: %v", err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	var frame []byte
+	for i := 0; i < b.N; i++ {
+		frame = encodeFrame(data, true, DefaultCompressionMinSize)
+		if _, err := decodeFrame(frame); err != nil {
+			b.Fatalf("Failed to decode frame: %v", err)
+		}
+	}
+
+	b.ReportMetric(float64(len(data)), "plain-bytes")
+	b.ReportMetric(float64(len(frame)), "wire-bytes")
+	b.ReportMetric(float64(len(frame))/float64(len(data)), "ratio")
+}
+
+func TestZmqNodePeerRateLimit(t *testing.T) {
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15815)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15816)
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15816", nil)
+	nodeA.RegisterPeer("node-b-fast", "tcp://127.0.0.1:15816", nil)
+	nodeA.SetPeerRateLimit(0.1, 3)
+	nodeA.SetPeerRateLimitOverride("node-b-fast", 0.1, 10)
+
+	// The burst goes through and the rest are rejected without blocking
+	sent := 0
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		err := nodeA.SendDirect("node-b", map[string]interface{}{"i": i})
+		if err == nil {
+			sent++
+		} else if !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got %v", err)
+		}
+	}
+	if sent != 3 {
+		t.Errorf("Expected burst of 3 sends, got %d", sent)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Rate limited sends blocked for %v", elapsed)
+	}
+
+	// The override applies in place of the default
+	for i := 0; i < 10; i++ {
+		if err := nodeA.SendDirect("node-b-fast", map[string]interface{}{"i": i}); err != nil {
+			t.Fatalf("Send %d within override burst failed: %v", i, err)
+		}
+	}
+
+	// Unregistering a peer discards its bucket
+	nodeA.UnregisterPeer("node-b")
+	nodeA.mu.RLock()
+	_, kept := nodeA.limiters["node-b"]
+	nodeA.mu.RUnlock()
+	if kept {
+		t.Error("Expected limiter state to be removed with the peer")
+	}
+	if err := nodeA.SendDirect("node-b", nil); err != ErrPeerNotFound {
+		t.Errorf("Expected ErrPeerNotFound for unregistered peer, got %v", err)
+	}
+
+	// Buckets refill at the configured rate up to the burst
+	now := time.Now()
+	bucket := newTokenBucket(PeerRateLimit{MsgsPerSec: 2, Burst: 2}, now)
+	if !bucket.allow(now) || !bucket.allow(now) || bucket.allow(now) {
+		t.Error("Expected a full bucket to allow exactly its burst")
+	}
+	if !bucket.allow(now.Add(500 * time.Millisecond)) {
+		t.Error("Expected one token after half a second at 2 msgs/sec")
+	}
+	if bucket.allow(now.Add(500 * time.Millisecond)) {
+		t.Error("Expected bucket to be empty again")
+	}
+}
+
+func TestZmqNodeTrafficStats(t *testing.T) {
+	nodeA := NewZmqNode("node-a", "127.0.0.1", 15817)
+	nodeB := NewZmqNode("node-b", "127.0.0.1", 15818)
+
+	received := make(chan *Message, 3)
+	nodeB.SetHandler(func(msg *Message) error {
+		received <- msg
+		return nil
+	})
+
+	if err := nodeA.Start(); err != nil {
+		t.Fatalf("Failed to start node-a: %v", err)
+	}
+	defer nodeA.Stop()
+	if err := nodeB.Start(); err != nil {
+		t.Fatalf("Failed to start node-b: %v", err)
+	}
+	defer nodeB.Stop()
+
+	nodeA.RegisterPeer("node-b", "tcp://127.0.0.1:15818", nil)
+	for i := 0; i < 3; i++ {
+		if err := nodeA.SendDirect("node-b", map[string]interface{}{"i": i}); err != nil {
+			t.Fatalf("SendDirect failed: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Received %d of 3 messages", i)
+		}
+	}
+
+	sent := nodeA.GetStats()
+	if sent.MessagesSent != 3 || sent.BytesSent == 0 {
+		t.Errorf("Expected 3 messages sent with bytes, got %d messages, %d bytes", sent.MessagesSent, sent.BytesSent)
+	}
+	recv := nodeB.GetStats()
+	if recv.MessagesReceived != 3 || recv.BytesReceived < sent.BytesSent {
+		t.Errorf("Expected 3 messages and at least %d bytes received, got %d messages, %d bytes",
+			sent.BytesSent, recv.MessagesReceived, recv.BytesReceived)
+	}
+
+	// Messages arriving while msgChan is full are counted as dropped
+	idle := NewZmqNode("idle-node", "127.0.0.1", 5555)
+	frame := []byte(`{"type":"direct","from":"peer1","payload":{}}`)
+	for i := 0; i < cap(idle.msgChan)+2; i++ {
+		idle.handleFrames([][]byte{[]byte("peer1"), frame})
+	}
+	if dropped := idle.GetStats().MessagesDropped; dropped != 2 {
+		t.Errorf("Expected 2 dropped messages, got %d", dropped)
+	}
+}
+
+func TestZmqNodePubSub(t *testing.T) {
+	publisher := NewZmqNode("publisher", "127.0.0.1", 15819)
+	publisher.SetPubSubPort(15822)
+	if err := publisher.Start(); err != nil {
+		t.Fatalf("Failed to start publisher: %v", err)
+	}
+	defer publisher.Stop()
+
+	// Each subscriber opts into a single topic
+	topics := map[string]string{"block-sub": "block", "tx-sub": "transaction"}
+	received := make(map[string]chan *Message)
+	port := 15820
+	for id, topic := range topics {
+		node := NewZmqNode(id, "127.0.0.1", port)
+		port++
+		ch := make(chan *Message, 10)
+		received[id] = ch
+		node.SetHandler(func(msg *Message) error {
+			ch <- msg
+			return nil
+		})
+		if err := node.Subscribe(topic); err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+		if err := node.Start(); err != nil {
+			t.Fatalf("Failed to start %s: %v", id, err)
+		}
+		defer node.Stop()
+		if err := node.ConnectPublisher(publisher.PubAddress()); err != nil {
+			t.Fatalf("ConnectPublisher failed: %v", err)
+		}
+	}
+
+	// Subscriptions reach the publisher asynchronously after
+	// ConnectPublisher, so keep publishing until every subscriber has
+	// seen its topic
+	pending := map[string]bool{"block-sub": true, "tx-sub": true}
+	broadcasts := int64(0)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pending) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for pub/sub delivery to %v", pending)
+		}
+		for _, topic := range []string{"block", "transaction"} {
+			if err := publisher.BroadcastPubSub(topic, map[string]interface{}{"topic": topic}); err != nil {
+				t.Fatalf("BroadcastPubSub failed: %v", err)
+			}
+			broadcasts++
+		}
+		time.Sleep(50 * time.Millisecond)
+		for id, topic := range topics {
+			for drained := false; !drained; {
+				select {
+				case msg := <-received[id]:
+					if msg.Topic != topic || msg.Payload["topic"] != topic || msg.From != "publisher" {
+						t.Errorf("%s: unexpected message %+v", id, msg)
+					}
+					delete(pending, id)
+				default:
+					drained = true
+				}
+			}
+		}
+	}
+
+	if sent := publisher.GetStats().MessagesSent; sent != broadcasts {
+		t.Errorf("Expected each broadcast to be sent once (%d), got %d sends", broadcasts, sent)
+	}
+
+	plain := NewZmqNode("plain", "127.0.0.1", 15823)
+	if err := plain.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	defer plain.Stop()
+	if plain.PubAddress() != "" {
+		t.Errorf("Expected no pub address, got %q", plain.PubAddress())
+	}
+	if err := plain.BroadcastPubSub("block", nil); err != ErrPubSubDisabled {
+		t.Errorf("Expected ErrPubSubDisabled, got %v", err)
+	}
+}
+
+func TestZmqNodeHandleFrames(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+
+	full := `{"type":"direct","from":"peer1","payload":{"k":"v"},"timestamp":"` +
+		time.Now().UTC().Format(time.RFC3339Nano) + `"}`
+	identity := []byte("peer1")
+
+	// Payload split across frames after the identity frame is reassembled
+	if !node.handleFrames([][]byte{identity, []byte(full[:20]), []byte(full[20:])}) {
+		t.Fatal("Multipart message should be decoded")
+	}
+	select {
+	case msg := <-node.Messages():
+		if msg.From != "peer1" || msg.Payload["k"] != "v" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+	default:
+		t.Fatal("Decoded message was not queued")
+	}
+
+	// A truncated frame is counted rather than silently swallowed
+	if node.handleFrames([][]byte{identity, []byte(full[:20])}) {
+		t.Error("Truncated message should be dropped")
+	}
+	if failures := node.GetStats().ParseFailures; failures != 1 {
+		t.Errorf("Expected 1 parse failure, got %d", failures)
+	}
+}
+
+func TestPropagatorFanout(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	for i := 0; i < 10; i++ {
+		node.RegisterPeer(fmt.Sprintf("peer%d", i), fmt.Sprintf("tcp://127.0.0.1:%d", 6000+i), nil)
+	}
+	prop := NewPropagator(node)
+
+	if prop.GetStats().Fanout != 0 {
+		t.Errorf("Expected default fanout 0 (all peers), got %d", prop.GetStats().Fanout)
+	}
+	prop.SetFanout(3)
+	if prop.GetStats().Fanout != 3 {
+		t.Errorf("Expected fanout 3, got %d", prop.GetStats().Fanout)
+	}
+
+	// Samples are distinct, exclude the sender, and cover every peer over
+	// many draws
+	picked := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		targets := prop.sampleTargets(3, "peer0")
+		if len(targets) != 3 {
+			t.Fatalf("Expected 3 targets, got %v", targets)
+		}
+		unique := make(map[string]bool)
+		for _, id := range targets {
+			if id == "peer0" {
+				t.Fatal("Sender was selected as a relay target")
+			}
+			unique[id] = true
+			picked[id]++
+		}
+		if len(unique) != 3 {
+			t.Fatalf("Expected distinct targets, got %v", targets)
+		}
+	}
+	if len(picked) != 9 {
+		t.Errorf("Expected all 9 other peers to be picked, got %d", len(picked))
+	}
+	for id, n := range picked {
+		// Each peer is expected 1000*3/9 = 333 times
+		if n < 200 || n > 470 {
+			t.Errorf("Peer %s picked %d times, expected about 333", id, n)
+		}
+	}
+
+	// A fanout larger than the peer set selects everyone but the sender
+	if targets := prop.sampleTargets(50, "peer0"); len(targets) != 9 {
+		t.Errorf("Expected 9 targets, got %d", len(targets))
+	}
+}
+
+func TestPropagatorSubscribe(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	prop := NewPropagator(node)
+
+	var mu sync.Mutex
+	got := make(map[string][]string)
+	record := func(name string) MessageHandler {
+		return func(msg *Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = append(got[name], msg.Payload["action"].(string))
+			return nil
+		}
+	}
+	prop.Subscribe(TopicBlock, record("blocks"))
+	prop.Subscribe(TopicBlock, record("blocks-2"))
+	prop.Subscribe(TopicTransaction, record("txs"))
+	prop.Subscribe("vote", record("votes"))
+
+	now := time.Now()
+	messages := []*Message{
+		{From: "peer1", Payload: map[string]interface{}{"action": "new_block"}, Timestamp: now},
+		{From: "peer1", Payload: map[string]interface{}{"action": "new_transaction"}, Timestamp: now},
+		{From: "peer1", Payload: map[string]interface{}{"action": "vote"}, Timestamp: now},
+		{From: "peer1", Payload: map[string]interface{}{"k": "v"}, Timestamp: now},
+	}
+	for _, msg := range messages {
+		prop.HandleIncoming(msg)
+	}
+	// Duplicates are not dispatched again
+	prop.HandleIncoming(messages[0])
+
+	mu.Lock()
+	defer mu.Unlock()
+	for name, want := range map[string]string{"blocks": "new_block", "blocks-2": "new_block", "txs": "new_transaction", "votes": "vote"} {
+		if len(got[name]) != 1 || got[name][0] != want {
+			t.Errorf("Expected %s handler to see one %s, got %v", name, want, got[name])
+		}
+	}
+}
+
+func TestPropagatorSeenCacheBounded(t *testing.T) {
+	node := NewZmqNode("test-node", "127.0.0.1", 5555)
+	prop := NewPropagator(node)
+	prop.SetMaxSeenEntries(100)
+
+	var last *Message
+	for i := 0; i < 1000; i++ {
+		last = &Message{
+			Type:      "transaction",
+			From:      "peer1",
+			Payload:   map[string]interface{}{"n": i},
+			Timestamp: time.Now(),
+		}
+		if !prop.HandleIncoming(last) {
+			t.Fatalf("Unique message %d reported as duplicate", i)
+		}
+	}
+
+	stats := prop.GetStats()
+	if stats.CacheSize != 100 {
+		t.Errorf("Expected cache bounded at 100, got %d", stats.CacheSize)
+	}
+	if stats.CacheEvictions != 900 {
+		t.Errorf("Expected 900 evictions, got %d", stats.CacheEvictions)
+	}
+
+	// The most recent message is still recognized
+	last.Hops = 0
+	if prop.HandleIncoming(last) {
+		t.Error("Recent message should still be a duplicate")
+	}
+}
+
+func TestPropagatorCoverageGrows(t *testing.T) {
+	// A line topology a - b - c - d, so the message needs three hops
+	ids := []string{"node-a", "node-b", "node-c", "node-d"}
+	nodes := make([]*ZmqNode, len(ids))
+	props := make([]*Propagator, len(ids))
+	for i, id := range ids {
+		nodes[i] = NewZmqNode(id, "127.0.0.1", 15791+i)
+		props[i] = NewPropagator(nodes[i])
+		prop := props[i]
+		nodes[i].SetHandler(func(msg *Message) error {
+			prop.HandleIncoming(msg)
+			return nil
+		})
+		if err := nodes[i].Start(); err != nil {
+			t.Fatalf("Failed to start %s: %v", id, err)
+		}
+		defer nodes[i].Stop()
+	}
+	for i := 0; i+1 < len(ids); i++ {
+		nodes[i].RegisterPeer(ids[i+1], fmt.Sprintf("tcp://127.0.0.1:%d", 15792+i), nil)
+		nodes[i+1].RegisterPeer(ids[i], fmt.Sprintf("tcp://127.0.0.1:%d", 15791+i), nil)
+	}
+	for i := 0; i+1 < len(ids); i++ {
+		if err := nodes[i].ConnectPeer(ids[i+1], 5*time.Second); err != nil {
+			t.Fatalf("ConnectPeer failed: %v", err)
+		}
+		if err := nodes[i+1].ConnectPeer(ids[i], 5*time.Second); err != nil {
+			t.Fatalf("ConnectPeer failed: %v", err)
+		}
+	}
+
+	origin := props[0]
+	origin.SetNetworkSize(len(ids))
+
+	msgID, err := origin.PropagateTracked("block", map[string]interface{}{"height": 1})
+	if err != nil || msgID == "" {
+		t.Fatalf("PropagateTracked failed: %q, %v", msgID, err)
+	}
+
+	// Coverage only grows, from the origin alone to every node
+	last := 0
+	deadline := time.Now().Add(5 * time.Second)
+	for last < len(ids) && time.Now().Before(deadline) {
+		seen, total := origin.Coverage(msgID)
+		if seen < last {
+			t.Fatalf("Coverage went down from %d to %d", last, seen)
+		}
+		if total != len(ids) {
+			t.Fatalf("Expected total %d, got %d", len(ids), total)
+		}
+		last = seen
+		time.Sleep(5 * time.Millisecond)
+	}
+	if last != len(ids) {
+		t.Fatalf("Expected coverage of %d nodes, got %d", len(ids), last)
+	}
+
+	// A relay knows about itself, its upstream and everything downstream
+	if seen, _ := props[2].Coverage(msgID); seen != 3 {
+		t.Errorf("Expected node-c to count 3 nodes, got %d", seen)
+	}
+	if seen, total := origin.Coverage("unknown"); seen != 0 || total != len(ids) {
+		t.Errorf("Expected 0 of %d for an unknown ID, got %d of %d", len(ids), seen, total)
+	}
+}
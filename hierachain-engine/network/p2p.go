@@ -1,10 +1,28 @@
 package network
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// ProtocolVersion is the peer protocol version this node advertises.
+// Peers with a different major version are incompatible.
+const ProtocolVersion = "1.0"
+
+// CapabilityCompression is advertised by peers that accept zstd-compressed
+// messages.
+const CapabilityCompression = "compression"
+
+// DefaultMaxPeers bounds the peers a P2PManager learns through gossip.
+const DefaultMaxPeers = 1000
+
 // P2PManager handles peer discovery and connection management.
 type P2PManager struct {
 	node       *ZmqNode
@@ -15,6 +33,12 @@ type P2PManager struct {
 	// Configuration
 	pruneInterval time.Duration
 	staleTimeout  time.Duration
+	minPeers      int // re-bootstrap from seeds below this many known peers
+	maxPeers      int // gossiped peers are ignored at this many known peers
+
+	// Advertised in announcements and peer exchange
+	capabilities       []string
+	refuseIncompatible bool // drop peers with another major protocol version
 
 	// Control
 	stopChan chan struct{}
@@ -29,6 +53,8 @@ func NewP2PManager(node *ZmqNode) *P2PManager {
 		knownPeers:    make(map[string]*PeerInfo),
 		pruneInterval: 30 * time.Second,
 		staleTimeout:  5 * time.Minute,
+		minPeers:      1,
+		maxPeers:      DefaultMaxPeers,
 		stopChan:      make(chan struct{}),
 	}
 }
@@ -75,17 +101,20 @@ func (p *P2PManager) DiscoverPeers(seeds []string) error {
 	for i, addr := range seeds {
 		peerID := addr // Use address as ID for seeds
 		p.node.RegisterPeer(peerID, addr, nil)
+		p.mu.Lock()
 		p.knownPeers[peerID] = &PeerInfo{
 			ID:       peerID,
 			Address:  addr,
 			LastSeen: time.Now(),
 		}
+		p.mu.Unlock()
 
-		// Request peer list from seeds
-		_ = p.node.SendDirect(peerID, map[string]interface{}{
+		// Request peer list from seeds, advertising our version so they
+		// learn it in the same exchange
+		_ = p.node.SendDirect(peerID, p.advertise(map[string]interface{}{
 			"action": "peer_exchange_request",
 			"index":  i,
-		})
+		}))
 	}
 
 	return nil
@@ -111,23 +140,30 @@ func (p *P2PManager) handleMessage(msg *Message) error {
 	return nil
 }
 
-// handlePeerExchangeRequest responds with known peers.
+// handlePeerExchangeRequest records the requester's advertised version
+// and responds with known peers and our own version.
 func (p *P2PManager) handlePeerExchangeRequest(msg *Message) error {
-	p.mu.RLock()
+	p.mu.Lock()
+	p.updateAdvertisedLocked(msg.From, msg.Payload)
 	peers := make([]map[string]interface{}, 0, len(p.knownPeers))
 	for _, peer := range p.knownPeers {
-		peers = append(peers, map[string]interface{}{
+		entry := map[string]interface{}{
 			"id":        peer.ID,
 			"address":   peer.Address,
 			"last_seen": peer.LastSeen.Unix(),
-		})
+		}
+		if peer.Version != "" {
+			entry["version"] = peer.Version
+			entry["capabilities"] = peer.Capabilities
+		}
+		peers = append(peers, entry)
 	}
-	p.mu.RUnlock()
+	p.mu.Unlock()
 
-	return p.node.SendDirect(msg.From, map[string]interface{}{
+	return p.node.SendDirect(msg.From, p.advertise(map[string]interface{}{
 		"action": "peer_exchange_response",
 		"peers":  peers,
-	})
+	}))
 }
 
 // handlePeerExchangeResponse processes received peer list.
@@ -140,6 +176,8 @@ func (p *P2PManager) handlePeerExchangeResponse(msg *Message) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	p.updateAdvertisedLocked(msg.From, msg.Payload)
+
 	for _, pData := range peersData {
 		peerMap, ok := pData.(map[string]interface{})
 		if !ok {
@@ -160,12 +198,8 @@ func (p *P2PManager) handlePeerExchangeResponse(msg *Message) error {
 
 		// Add or update peer
 		if _, exists := p.knownPeers[peerID]; !exists {
-			p.knownPeers[peerID] = &PeerInfo{
-				ID:       peerID,
-				Address:  address,
-				LastSeen: time.Now(),
-			}
-			p.node.RegisterPeer(peerID, address, nil)
+			version, _ := peerMap["version"].(string)
+			p.addGossipedPeerLocked(peerID, address, version, parseCapabilities(peerMap["capabilities"]))
 		}
 	}
 
@@ -185,27 +219,203 @@ func (p *P2PManager) handlePeerAnnounce(msg *Message) error {
 	defer p.mu.Unlock()
 
 	if _, exists := p.knownPeers[peerID]; !exists {
-		p.knownPeers[peerID] = &PeerInfo{
-			ID:       peerID,
-			Address:  address,
-			LastSeen: time.Now(),
-		}
-		p.node.RegisterPeer(peerID, address, nil)
-	} else {
+		version, _ := msg.Payload["version"].(string)
+		p.addGossipedPeerLocked(peerID, address, version, parseCapabilities(msg.Payload["capabilities"]))
+	} else if p.updateAdvertisedLocked(peerID, msg.Payload) {
 		p.knownPeers[peerID].LastSeen = time.Now()
 	}
 
 	return nil
 }
 
+// addGossipedPeerLocked adds a peer learned from another node with the
+// version and capabilities advertised for it. Peers with an incompatible
+// version are refused if SetRefuseIncompatible is on. Once maxPeers are
+// known, the peer is only added if it can displace the least recently
+// seen peer that has gone stale; seeds are never displaced.
+// Returns true if the peer was added. The caller must hold p.mu.
+func (p *P2PManager) addGossipedPeerLocked(peerID, address, version string, capabilities []string) bool {
+	if !p.acceptVersion(peerID, version) {
+		return false
+	}
+
+	if p.maxPeers > 0 && len(p.knownPeers) >= p.maxPeers {
+		cutoff := time.Now().Add(-p.staleTimeout)
+		var stalest *PeerInfo
+		for _, peer := range p.knownPeers {
+			if p.isSeedLocked(peer.ID) || !peer.LastSeen.Before(cutoff) {
+				continue
+			}
+			if stalest == nil || peer.LastSeen.Before(stalest.LastSeen) {
+				stalest = peer
+			}
+		}
+		if stalest == nil {
+			return false
+		}
+
+		delete(p.knownPeers, stalest.ID)
+		p.node.UnregisterPeer(stalest.ID)
+	}
+
+	p.knownPeers[peerID] = &PeerInfo{
+		ID:           peerID,
+		Address:      address,
+		LastSeen:     time.Now(),
+		Version:      version,
+		Capabilities: capabilities,
+	}
+	p.node.RegisterPeer(peerID, address, nil)
+	return true
+}
+
+// updateAdvertisedLocked records the version and capabilities a known
+// peer advertised in payload. A peer advertising an incompatible version
+// is removed if SetRefuseIncompatible is on. Returns false if the peer
+// was removed. The caller must hold p.mu.
+func (p *P2PManager) updateAdvertisedLocked(peerID string, payload map[string]interface{}) bool {
+	peer, ok := p.knownPeers[peerID]
+	if !ok {
+		return true
+	}
+
+	version, _ := payload["version"].(string)
+	if version == "" {
+		return true // predates negotiation
+	}
+
+	if !p.acceptVersion(peerID, version) {
+		delete(p.knownPeers, peerID)
+		p.node.UnregisterPeer(peerID)
+		return false
+	}
+
+	peer.Version = version
+	peer.Capabilities = parseCapabilities(payload["capabilities"])
+	return true
+}
+
+// acceptVersion logs peers advertising an incompatible protocol version
+// and reports whether they may be kept. The caller must hold p.mu.
+func (p *P2PManager) acceptVersion(peerID, version string) bool {
+	if compatibleVersion(version) {
+		return true
+	}
+
+	log.Printf("Warning: peer %s advertises protocol version %s, incompatible with %s", peerID, version, ProtocolVersion)
+	return !p.refuseIncompatible
+}
+
+// compatibleVersion reports whether version has the same major version
+// as ProtocolVersion. An empty version, from a peer that predates
+// negotiation, is treated as compatible.
+func compatibleVersion(version string) bool {
+	if version == "" {
+		return true
+	}
+	return majorVersion(version) == majorVersion(ProtocolVersion)
+}
+
+// majorVersion returns the part of version before the first dot.
+func majorVersion(version string) string {
+	if i := strings.IndexByte(version, '.'); i >= 0 {
+		return version[:i]
+	}
+	return version
+}
+
+// parseCapabilities reads a capability list from a decoded payload.
+func parseCapabilities(v interface{}) []string {
+	switch caps := v.(type) {
+	case []string:
+		return append([]string(nil), caps...)
+	case []interface{}:
+		parsed := make([]string, 0, len(caps))
+		for _, c := range caps {
+			if s, ok := c.(string); ok {
+				parsed = append(parsed, s)
+			}
+		}
+		return parsed
+	}
+	return nil
+}
+
+// advertise adds this node's protocol version and capabilities to payload.
+func (p *P2PManager) advertise(payload map[string]interface{}) map[string]interface{} {
+	p.mu.RLock()
+	capabilities := append([]string{}, p.capabilities...)
+	p.mu.RUnlock()
+
+	payload["version"] = ProtocolVersion
+	payload["capabilities"] = capabilities
+	return payload
+}
+
+// SetCapabilities sets the feature flags this node advertises to peers.
+func (p *P2PManager) SetCapabilities(capabilities []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.capabilities = append([]string(nil), capabilities...)
+}
+
+// SetRefuseIncompatible sets whether peers advertising a different major
+// protocol version are dropped rather than only logged.
+func (p *P2PManager) SetRefuseIncompatible(refuse bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refuseIncompatible = refuse
+}
+
+// GetPeersByCapability returns copies of the known peers that advertised
+// capability.
+func (p *P2PManager) GetPeersByCapability(capability string) []*PeerInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	matched := make([]*PeerInfo, 0)
+	for _, peer := range p.knownPeers {
+		for _, c := range peer.Capabilities {
+			if c == capability {
+				matched = append(matched, copyPeerInfo(peer))
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// copyPeerInfo returns a copy of peer that shares no slices with it.
+func copyPeerInfo(peer *PeerInfo) *PeerInfo {
+	return &PeerInfo{
+		ID:           peer.ID,
+		Address:      peer.Address,
+		PublicKey:    append([]byte(nil), peer.PublicKey...),
+		LastSeen:     peer.LastSeen,
+		Version:      peer.Version,
+		Capabilities: append([]string(nil), peer.Capabilities...),
+	}
+}
+
+// isSeedLocked reports whether peerID is one of the seed nodes.
+// The caller must hold p.mu.
+func (p *P2PManager) isSeedLocked(peerID string) bool {
+	for _, seed := range p.seedNodes {
+		if seed == peerID {
+			return true
+		}
+	}
+	return false
+}
+
 // AnnounceSelf broadcasts this node's presence to the network.
 func (p *P2PManager) AnnounceSelf() error {
 	stats := p.node.GetStats()
-	return p.node.Broadcast(map[string]interface{}{
+	return p.node.Broadcast(p.advertise(map[string]interface{}{
 		"action":  "peer_announce",
 		"peer_id": stats.NodeID,
 		"address": stats.Address,
-	}, nil)
+	}), nil)
 }
 
 // pruneStalePeers periodically removes stale peers.
@@ -221,15 +431,65 @@ func (p *P2PManager) pruneStalePeers() {
 			return
 		case <-ticker.C:
 			p.prune()
+			p.rebootstrapIfNeeded()
 		}
 	}
 }
 
+// rebootstrapIfNeeded re-runs discovery against the seed nodes when the
+// number of known peers has fallen below the configured minimum.
+// Returns true if discovery was triggered.
+func (p *P2PManager) rebootstrapIfNeeded() bool {
+	p.mu.RLock()
+	below := len(p.knownPeers) < p.minPeers
+	seeds := make([]string, len(p.seedNodes))
+	copy(seeds, p.seedNodes)
+	p.mu.RUnlock()
+
+	if !below || len(seeds) == 0 {
+		return false
+	}
+
+	_ = p.DiscoverPeers(seeds)
+	return true
+}
+
+// SetMinPeers sets the known-peer count below which seeds are re-contacted.
+// Zero disables automatic re-bootstrapping.
+func (p *P2PManager) SetMinPeers(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.minPeers = n
+}
+
+// syncLastSeen refreshes known peers' LastSeen from the node, which also
+// sees heartbeats and traffic the manager does not handle.
+// The caller must hold p.mu.
+func (p *P2PManager) syncLastSeen(nodePeers map[string]*PeerInfo) {
+	for peerID, peer := range p.knownPeers {
+		if nodePeer, ok := nodePeers[peerID]; ok && nodePeer.LastSeen.After(peer.LastSeen) {
+			peer.LastSeen = nodePeer.LastSeen
+		}
+	}
+}
+
+// SetMaxPeers sets how many known peers gossip can grow the table to.
+// Seeds from DiscoverPeers are always added. Zero removes the limit.
+func (p *P2PManager) SetMaxPeers(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxPeers = n
+}
+
 // prune removes peers that haven't been seen recently.
 func (p *P2PManager) prune() {
+	nodePeers := p.node.GetPeers()
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	p.syncLastSeen(nodePeers)
+
 	cutoff := time.Now().Add(-p.staleTimeout)
 	for peerID, peer := range p.knownPeers {
 		if peer.LastSeen.Before(cutoff) {
@@ -241,25 +501,120 @@ func (p *P2PManager) prune() {
 
 // GetHealthyPeers returns peers that are considered healthy.
 func (p *P2PManager) GetHealthyPeers() []*PeerInfo {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	nodePeers := p.node.GetPeers()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.syncLastSeen(nodePeers)
 
 	cutoff := time.Now().Add(-p.staleTimeout)
 	healthy := make([]*PeerInfo, 0)
 
 	for _, peer := range p.knownPeers {
 		if peer.LastSeen.After(cutoff) {
-			healthy = append(healthy, &PeerInfo{
-				ID:       peer.ID,
-				Address:  peer.Address,
-				LastSeen: peer.LastSeen,
-			})
+			healthy = append(healthy, copyPeerInfo(peer))
 		}
 	}
 
 	return healthy
 }
 
+// SavePeers writes the known peers, with the public keys the node has
+// for them, to path as JSON. The file is written to a temporary file and
+// renamed into place, so a crash mid-write leaves the previous file intact.
+func (p *P2PManager) SavePeers(path string) error {
+	nodePeers := p.node.GetPeers()
+
+	p.mu.RLock()
+	peers := make([]*PeerInfo, 0, len(p.knownPeers))
+	for _, peer := range p.knownPeers {
+		saved := copyPeerInfo(peer)
+		if nodePeer, ok := nodePeers[peer.ID]; ok {
+			if saved.PublicKey == nil {
+				saved.PublicKey = nodePeer.PublicKey
+			}
+			if nodePeer.LastSeen.After(saved.LastSeen) {
+				saved.LastSeen = nodePeer.LastSeen
+			}
+		}
+		peers = append(peers, saved)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].ID < peers[j].ID })
+
+	data, err := json.MarshalIndent(peers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal peers: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create peer store: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write peer store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync peer store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close peer store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace peer store: %w", err)
+	}
+	return nil
+}
+
+// LoadPeers adds the peers saved by SavePeers at path and registers them
+// with the node. Peers not seen within the stale timeout, this node
+// itself and peers already known are skipped; if more peers remain than
+// the max peers limit, the most recently seen are kept. A missing file
+// returns an error wrapping os.ErrNotExist.
+func (p *P2PManager) LoadPeers(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read peer store: %w", err)
+	}
+
+	var peers []*PeerInfo
+	if err := json.Unmarshal(data, &peers); err != nil {
+		return fmt.Errorf("failed to parse peer store: %w", err)
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].LastSeen.After(peers[j].LastSeen) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cutoff := time.Now().Add(-p.staleTimeout)
+	for _, peer := range peers {
+		if peer == nil || peer.ID == "" || peer.Address == "" || peer.ID == p.node.nodeID {
+			continue
+		}
+		if peer.LastSeen.Before(cutoff) {
+			continue
+		}
+		if _, exists := p.knownPeers[peer.ID]; exists {
+			continue
+		}
+		if p.maxPeers > 0 && len(p.knownPeers) >= p.maxPeers {
+			break
+		}
+
+		p.knownPeers[peer.ID] = copyPeerInfo(peer)
+		p.node.RegisterPeer(peer.ID, peer.Address, peer.PublicKey)
+	}
+
+	return nil
+}
+
 // PeerCount returns the number of known peers.
 func (p *P2PManager) PeerCount() int {
 	p.mu.RLock()
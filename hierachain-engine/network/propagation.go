@@ -1,24 +1,64 @@
 package network
 
 import (
+	crand "crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"log"
+	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Topics that PropagateBlock and PropagateTransaction publish under.
+const (
+	TopicBlock       = "block"
+	TopicTransaction = "transaction"
+)
+
+// actionTopics maps the payload actions of built-in broadcasts to their
+// topics. Other actions are their own topic.
+var actionTopics = map[string]string{
+	"new_block":       TopicBlock,
+	"new_transaction": TopicTransaction,
+}
+
 // Propagator handles message propagation across the network using gossip protocol.
 type Propagator struct {
 	node *ZmqNode
 
-	// Seen messages cache (hash -> timestamp)
-	seenMessages sync.Map
+	// Seen messages cache (hash -> timestamp), bounded with LRU eviction
+	seenMessages *seenCache
+
+	// Recently broadcast content (content hash -> timestamp)
+	sentContent sync.Map
+
+	// Handlers for incoming messages by topic, guarded by mu.
+	// Each list is replaced rather than appended to in place.
+	handlers map[string][]MessageHandler
+
+	// Acknowledgements of tracked messages, for Coverage
+	coverage    *coverageTracker
+	networkSize int // expected number of nodes; 0 means peers plus self
 
 	// Configuration
-	maxHops       int
-	cacheExpiry   time.Duration
-	cleanInterval time.Duration
+	maxHops         int
+	cacheExpiry     time.Duration
+	cleanInterval   time.Duration
+	broadcastWindow time.Duration
+	fanout          int        // peers each relay is forwarded to; 0 means all
+	rng             *rand.Rand // picks relay targets, guarded by mu
+
+	// Stats
+	suppressedBroadcasts int64
+	messagesOriginated   int64
+	messagesForwarded    int64
+	duplicatesDropped    int64
+	hopLimitReached      int64
 
 	// Control
 	stopChan chan struct{}
@@ -30,14 +70,29 @@ type Propagator struct {
 // NewPropagator creates a new message propagator.
 func NewPropagator(node *ZmqNode) *Propagator {
 	return &Propagator{
-		node:          node,
-		maxHops:       5,
-		cacheExpiry:   5 * time.Minute,
-		cleanInterval: time.Minute,
-		stopChan:      make(chan struct{}),
+		node:            node,
+		seenMessages:    newSeenCache(DefaultMaxSeenEntries),
+		coverage:        newCoverageTracker(),
+		handlers:        make(map[string][]MessageHandler),
+		maxHops:         5,
+		cacheExpiry:     5 * time.Minute,
+		cleanInterval:   time.Minute,
+		broadcastWindow: 30 * time.Second,
+		rng:             rand.New(rand.NewSource(newSeed())), // #nosec G404 - gossip target sampling
+		stopChan:        make(chan struct{}),
 	}
 }
 
+// newSeed returns a random seed, so propagators on different nodes do not
+// pick correlated relay targets.
+func newSeed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
 // Start begins propagation operations.
 func (p *Propagator) Start() {
 	p.mu.Lock()
@@ -68,7 +123,30 @@ func (p *Propagator) Stop() {
 }
 
 // Propagate sends a message to all peers using gossip protocol.
+// Content already broadcast within the broadcast window is suppressed.
 func (p *Propagator) Propagate(msgType string, payload map[string]interface{}) error {
+	_, err := p.propagate(msgType, payload, false)
+	return err
+}
+
+// PropagateTracked is Propagate for a message whose spread should be
+// measured. The payload is tagged with a gossip ID, which is returned for
+// use with Coverage, and every node that receives the message acknowledges
+// it back along the path it arrived by. The ID is empty if the content was
+// suppressed as a recent duplicate.
+func (p *Propagator) PropagateTracked(msgType string, payload map[string]interface{}) (string, error) {
+	return p.propagate(msgType, payload, true)
+}
+
+// propagate implements Propagate and PropagateTracked.
+func (p *Propagator) propagate(msgType string, payload map[string]interface{}, tracked bool) (string, error) {
+	// Collapse repeated application-level broadcasts of the same content
+	contentHash := p.hashContent(msgType, payload)
+	if !p.markSent(contentHash) {
+		atomic.AddInt64(&p.suppressedBroadcasts, 1)
+		return "", nil
+	}
+
 	msg := &Message{
 		Type:      msgType,
 		From:      p.node.nodeID,
@@ -81,57 +159,250 @@ func (p *Propagator) Propagate(msgType string, payload map[string]interface{}) e
 	hash := p.hashMessage(msg)
 	p.seenMessages.Store(hash, time.Now())
 
+	if tracked {
+		tagged := make(map[string]interface{}, len(payload)+1)
+		for k, v := range payload {
+			tagged[k] = v
+		}
+		tagged[GossipIDKey] = hash
+		payload = tagged
+		p.coverage.track(hash, p.node.nodeID, "")
+	}
+
 	// Broadcast to all peers
-	return p.node.Broadcast(payload, nil)
+	if err := p.node.Broadcast(payload, nil); err != nil {
+		// Allow a retry to go out if the broadcast failed
+		p.sentContent.Delete(contentHash)
+		return "", err
+	}
+	atomic.AddInt64(&p.messagesOriginated, 1)
+	return hash, nil
 }
 
-// PropagateBlock broadcasts a block to all peers.
+// markSent records content as broadcast. Returns false if the same content
+// was already broadcast within the broadcast window.
+func (p *Propagator) markSent(contentHash string) bool {
+	p.mu.Lock()
+	window := p.broadcastWindow
+	p.mu.Unlock()
+
+	now := time.Now()
+	if prev, loaded := p.sentContent.LoadOrStore(contentHash, now); loaded {
+		if ts, ok := prev.(time.Time); ok && now.Sub(ts) < window {
+			return false
+		}
+		p.sentContent.Store(contentHash, now)
+	}
+	return true
+}
+
+// PropagateBlock broadcasts a block to all peers under TopicBlock.
 func (p *Propagator) PropagateBlock(blockData []byte) error {
-	return p.Propagate("block", map[string]interface{}{
+	return p.Propagate(TopicBlock, map[string]interface{}{
 		"action": "new_block",
 		"data":   string(blockData),
 	})
 }
 
-// PropagateTransaction broadcasts a transaction to all peers.
+// PropagateTransaction broadcasts a transaction to all peers under
+// TopicTransaction.
 func (p *Propagator) PropagateTransaction(txData []byte) error {
-	return p.Propagate("transaction", map[string]interface{}{
+	return p.Propagate(TopicTransaction, map[string]interface{}{
 		"action": "new_transaction",
 		"data":   string(txData),
 	})
 }
 
-// HandleIncoming processes an incoming message for propagation.
+// HandleIncoming processes an incoming message for propagation, passing
+// it to the handlers subscribed to its topic unless it is a duplicate.
 // Returns true if the message should be processed, false if it's a duplicate.
 func (p *Propagator) HandleIncoming(msg *Message) bool {
-	hash := p.hashMessage(msg)
+	// Acknowledgements only update coverage
+	if msgID, ok := msg.Payload[GossipAckKey].(string); ok {
+		p.handleAck(msgID, msg.Payload)
+		return false
+	}
+
+	// Tracked messages keep their gossip ID across relays, so it
+	// identifies them better than the relay's envelope
+	hash, tracked := msg.Payload[GossipIDKey].(string)
+	if !tracked {
+		hash = p.hashMessage(msg)
+	}
 
 	// Check if already seen
 	if p.IsDuplicate(hash) {
+		atomic.AddInt64(&p.duplicatesDropped, 1)
 		return false
 	}
 
 	// Mark as seen
 	p.seenMessages.Store(hash, time.Now())
 
+	if tracked && p.coverage.track(hash, p.node.nodeID, msg.From) {
+		p.sendAck(msg.From, hash, p.node.nodeID)
+	}
+
+	p.dispatch(msg)
+
 	// Check hop count
 	if msg.Hops >= p.maxHops {
+		atomic.AddInt64(&p.hopLimitReached, 1)
 		return true // Process but don't propagate further
 	}
 
 	// Increment hops and propagate
 	msg.Hops++
 
-	// Propagate to all peers except sender
-	_ = p.node.Broadcast(msg.Payload, []string{msg.From})
+	// Propagate to all peers, or a sample of them, except sender
+	_ = p.forward(msg.Payload, msg.From)
+	atomic.AddInt64(&p.messagesForwarded, 1)
 
 	return true
 }
 
+// forward relays payload to every peer except from, or to a uniform random
+// sample of fanout of them if a fanout is set.
+func (p *Propagator) forward(payload map[string]interface{}, from string) error {
+	p.mu.Lock()
+	fanout := p.fanout
+	p.mu.Unlock()
+
+	if fanout <= 0 {
+		return p.node.Broadcast(payload, []string{from})
+	}
+
+	var lastErr error
+	for _, peerID := range p.sampleTargets(fanout, from) {
+		if err := p.node.SendDirect(peerID, payload); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// sampleTargets picks up to k of the node's current peers, other than
+// exclude, uniformly at random.
+func (p *Propagator) sampleTargets(k int, exclude string) []string {
+	peers := p.node.GetPeers()
+	ids := make([]string, 0, len(peers))
+	for id := range peers {
+		if id != exclude {
+			ids = append(ids, id)
+		}
+	}
+	// Sort first so the sample depends only on the RNG, not map order
+	sort.Strings(ids)
+
+	if k > len(ids) {
+		k = len(ids)
+	}
+
+	// Partial Fisher-Yates shuffle
+	p.mu.Lock()
+	for i := 0; i < k; i++ {
+		j := i + p.rng.Intn(len(ids)-i)
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+	p.mu.Unlock()
+
+	return ids[:k]
+}
+
+// Subscribe registers handler for incoming messages on topic. A message's
+// topic comes from its payload action: TopicBlock for blocks, TopicTransaction
+// for transactions, and the action itself otherwise. Handlers run in the
+// order subscribed, on the goroutine calling HandleIncoming.
+func (p *Propagator) Subscribe(topic string, handler MessageHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	handlers := make([]MessageHandler, 0, len(p.handlers[topic])+1)
+	handlers = append(handlers, p.handlers[topic]...)
+	p.handlers[topic] = append(handlers, handler)
+}
+
+// messageTopic returns the topic an incoming message is dispatched under,
+// or an empty string if its payload has no action.
+func messageTopic(msg *Message) string {
+	action, _ := msg.Payload["action"].(string)
+	if topic, ok := actionTopics[action]; ok {
+		return topic
+	}
+	return action
+}
+
+// dispatch passes msg to the handlers subscribed to its topic.
+func (p *Propagator) dispatch(msg *Message) {
+	topic := messageTopic(msg)
+	if topic == "" {
+		return
+	}
+
+	p.mu.Lock()
+	handlers := p.handlers[topic]
+	p.mu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(msg); err != nil {
+			log.Printf("Warning: %s handler failed for message from %s: %v", topic, msg.From, err)
+		}
+	}
+}
+
+// handleAck records an acknowledgement of msgID and passes it on toward
+// the message's origin.
+func (p *Propagator) handleAck(msgID string, payload map[string]interface{}) {
+	acker, ok := payload[GossipAckerKey].(string)
+	if !ok || acker == "" {
+		return
+	}
+	if upstream := p.coverage.ack(msgID, acker); upstream != "" {
+		p.sendAck(upstream, msgID, acker)
+	}
+}
+
+// sendAck tells peerID that acker has seen msgID (best effort).
+func (p *Propagator) sendAck(peerID, msgID, acker string) {
+	_ = p.node.SendDirect(peerID, map[string]interface{}{
+		GossipAckKey:   msgID,
+		GossipAckerKey: acker,
+	})
+}
+
+// Coverage returns a best-effort estimate of how far a message sent with
+// PropagateTracked has spread: seen is the number of distinct nodes known
+// to have received it, including this one, and total the number expected
+// to. Lost or in-flight acknowledgements make seen an underestimate.
+// total is the size set with SetNetworkSize, or else this node's peers
+// plus itself, and is never below seen. Unknown or expired IDs report 0.
+func (p *Propagator) Coverage(msgID string) (seen int, total int) {
+	seen = p.coverage.seenCount(msgID)
+
+	p.mu.Lock()
+	total = p.networkSize
+	p.mu.Unlock()
+	if total <= 0 {
+		total = len(p.node.GetPeers()) + 1
+	}
+	if total < seen {
+		total = seen
+	}
+	return seen, total
+}
+
+// SetNetworkSize sets the expected number of nodes Coverage reports as
+// total, for networks where a node is not peered with everyone.
+// Zero or less reverts to counting this node's peers.
+func (p *Propagator) SetNetworkSize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.networkSize = n
+}
+
 // IsDuplicate checks if a message hash has been seen before.
 func (p *Propagator) IsDuplicate(hash string) bool {
-	_, seen := p.seenMessages.Load(hash)
-	return seen
+	return p.seenMessages.Contains(hash)
 }
 
 // hashMessage creates a hash of the message for deduplication.
@@ -154,6 +425,22 @@ func (p *Propagator) hashMessage(msg *Message) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// hashContent creates a hash of the message content, independent of
+// sender and timestamp, for outbound broadcast deduplication.
+func (p *Propagator) hashContent(msgType string, payload map[string]interface{}) string {
+	data := struct {
+		Type    string
+		Payload map[string]interface{}
+	}{
+		Type:    msgType,
+		Payload: payload,
+	}
+
+	jsonData, _ := json.Marshal(data)
+	hash := sha256.Sum256(jsonData)
+	return hex.EncodeToString(hash[:])
+}
+
 // cacheCleaner periodically cleans old entries from the seen messages cache.
 func (p *Propagator) cacheCleaner() {
 	defer p.wg.Done()
@@ -171,14 +458,21 @@ func (p *Propagator) cacheCleaner() {
 	}
 }
 
-// cleanCache removes expired entries from the seen messages cache.
+// cleanCache removes expired entries from the seen messages and sent content caches.
 func (p *Propagator) cleanCache() {
 	cutoff := time.Now().Add(-p.cacheExpiry)
 
-	p.seenMessages.Range(func(key, value interface{}) bool {
+	p.seenMessages.RemoveOlderThan(cutoff)
+	p.coverage.removeOlderThan(cutoff)
+
+	p.mu.Lock()
+	sentCutoff := time.Now().Add(-p.broadcastWindow)
+	p.mu.Unlock()
+
+	p.sentContent.Range(func(key, value interface{}) bool {
 		if ts, ok := value.(time.Time); ok {
-			if ts.Before(cutoff) {
-				p.seenMessages.Delete(key)
+			if ts.Before(sentCutoff) {
+				p.sentContent.Delete(key)
 			}
 		}
 		return true
@@ -192,11 +486,42 @@ func (p *Propagator) SetMaxHops(hops int) {
 	p.maxHops = hops
 }
 
+// SetFanout sets how many randomly chosen peers each relayed message is
+// forwarded to, trading propagation latency for bandwidth in large
+// networks. Zero or less forwards to all peers. Messages this node
+// originates are still sent to all peers.
+func (p *Propagator) SetFanout(k int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fanout = k
+}
+
+// SetMaxSeenEntries bounds the seen-message cache, evicting the least
+// recently used hashes beyond it. Zero or less leaves it unbounded.
+func (p *Propagator) SetMaxSeenEntries(maxEntries int) {
+	p.seenMessages.SetMaxEntries(maxEntries)
+}
+
+// SetBroadcastWindow sets how long identical outbound content is suppressed.
+// A zero window disables outbound deduplication.
+func (p *Propagator) SetBroadcastWindow(window time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.broadcastWindow = window
+}
+
 // PropagatorStats contains propagator statistics.
 type PropagatorStats struct {
-	MaxHops   int  `json:"max_hops"`
-	CacheSize int  `json:"cache_size"`
-	IsRunning bool `json:"is_running"`
+	MaxHops              int   `json:"max_hops"`
+	Fanout               int   `json:"fanout"`
+	CacheSize            int   `json:"cache_size"`
+	CacheEvictions       int64 `json:"cache_evictions"`
+	IsRunning            bool  `json:"is_running"`
+	SuppressedBroadcasts int64 `json:"suppressed_broadcasts"`
+	MessagesOriginated   int64 `json:"messages_originated"`
+	MessagesForwarded    int64 `json:"messages_forwarded"`
+	DuplicatesDropped    int64 `json:"duplicates_dropped"`
+	HopLimitReached      int64 `json:"hop_limit_reached"`
 }
 
 // GetStats returns propagator statistics.
@@ -204,15 +529,16 @@ func (p *Propagator) GetStats() PropagatorStats {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	cacheSize := 0
-	p.seenMessages.Range(func(key, value interface{}) bool {
-		cacheSize++
-		return true
-	})
-
 	return PropagatorStats{
-		MaxHops:   p.maxHops,
-		CacheSize: cacheSize,
-		IsRunning: p.running,
+		MaxHops:              p.maxHops,
+		Fanout:               p.fanout,
+		CacheSize:            p.seenMessages.Len(),
+		CacheEvictions:       p.seenMessages.Evictions(),
+		IsRunning:            p.running,
+		SuppressedBroadcasts: atomic.LoadInt64(&p.suppressedBroadcasts),
+		MessagesOriginated:   atomic.LoadInt64(&p.messagesOriginated),
+		MessagesForwarded:    atomic.LoadInt64(&p.messagesForwarded),
+		DuplicatesDropped:    atomic.LoadInt64(&p.duplicatesDropped),
+		HopLimitReached:      atomic.LoadInt64(&p.hopLimitReached),
 	}
 }
@@ -0,0 +1,165 @@
+package network
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-zeromq/zmq4"
+)
+
+// SetPubSubPort sets the port the node binds a PUB socket on for
+// BroadcastPubSub. Zero, the default, disables it. Call it before Start.
+func (n *ZmqNode) SetPubSubPort(port int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.pubPort = port
+}
+
+// PubAddress returns the endpoint peers connect to with ConnectPublisher,
+// or an empty string if pub/sub broadcasting is disabled.
+func (n *ZmqNode) PubAddress() string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.pubPort <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("tcp://%s:%d", n.host, n.pubPort)
+}
+
+// bindPubLocked creates and binds the PUB socket. The caller must hold n.mu.
+func (n *ZmqNode) bindPubLocked() error {
+	opts := n.socketOptions()
+	if n.security != nil {
+		if sec := n.security.Server(); sec != nil {
+			opts = append(opts, zmq4.WithSecurity(sec))
+		}
+	}
+	sock := zmq4.NewPub(n.ctx, opts...)
+	if err := n.applySocketOptions(sock); err != nil {
+		closeSocket(sock, n.options.Linger)
+		return err
+	}
+
+	address := fmt.Sprintf("tcp://%s:%d", n.host, n.pubPort)
+	if err := sock.Listen(address); err != nil {
+		closeSocket(sock, n.options.Linger)
+		return fmt.Errorf("failed to bind publisher: %w", err)
+	}
+
+	n.pub = &peerDealer{sock: sock}
+	return nil
+}
+
+// BroadcastPubSub publishes a message once on the PUB socket, reaching
+// every peer connected with ConnectPublisher and subscribed to a prefix
+// of topic. Unlike Broadcast, delivery is not per peer: there are no
+// sequence numbers, rate limits or acknowledgements.
+func (n *ZmqNode) BroadcastPubSub(topic string, payload map[string]interface{}) error {
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return ErrNodeNotRunning
+	}
+	pub := n.pub
+	if pub == nil {
+		n.mu.Unlock()
+		return ErrPubSubDisabled
+	}
+	pub.sends.Add(1)
+	signingKey := n.signingKey
+	compress, compressMin := n.compress, n.compressMin
+	n.beginSend()
+	n.mu.Unlock()
+	defer n.endSend()
+	defer pub.sends.Done()
+
+	msg := n.newMessage(MessageTypePublish, "", payload)
+	msg.Topic = topic
+
+	frame, err := sealMessage(msg, signingKey, compress, compressMin)
+	if err != nil {
+		return err
+	}
+
+	// The topic goes in its own frame, which SUB sockets filter on
+	if err := pub.sock.Send(zmq4.NewMsgFrom([]byte(topic), frame)); err != nil {
+		atomic.AddInt64(&n.messagesDropped, 1)
+		return fmt.Errorf("%w: %v", ErrSendFailed, err)
+	}
+
+	atomic.AddInt64(&n.messagesSent, 1)
+	atomic.AddInt64(&n.bytesSent, int64(len(frame)))
+	return nil
+}
+
+// ConnectPublisher connects the node's SUB socket to a peer's PUB
+// endpoint, as returned by the peer's PubAddress. Messages published
+// there on subscribed topics are delivered like direct messages. It is a
+// no-op if the endpoint is already connected.
+func (n *ZmqNode) ConnectPublisher(address string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.running {
+		return ErrNodeNotRunning
+	}
+	if n.publishers[address] {
+		return nil
+	}
+
+	if n.sub == nil {
+		// SUB sockets only accept the subscription options, so
+		// applySocketOptions (HWM) is not used here
+		sub := zmq4.NewSub(n.ctx, n.socketOptions()...)
+		for topic := range n.topics {
+			if err := sub.SetOption(zmq4.OptionSubscribe, topic); err != nil {
+				closeSocket(sub, n.options.Linger)
+				return fmt.Errorf("failed to subscribe to %q: %w", topic, err)
+			}
+		}
+
+		n.sub = sub
+		n.wg.Add(1)
+		go n.receiverLoop(sub)
+	}
+
+	if err := n.sub.Dial(address); err != nil {
+		return fmt.Errorf("failed to connect to publisher %s: %w", address, err)
+	}
+	n.publishers[address] = true
+	return nil
+}
+
+// Subscribe opts into messages published on topics starting with topic.
+// An empty topic subscribes to everything. Subscriptions made before
+// ConnectPublisher apply once the SUB socket is created.
+func (n *ZmqNode) Subscribe(topic string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.sub != nil {
+		if err := n.sub.SetOption(zmq4.OptionSubscribe, topic); err != nil {
+			return fmt.Errorf("failed to subscribe to %q: %w", topic, err)
+		}
+	}
+	n.topics[topic] = true
+	return nil
+}
+
+// Unsubscribe removes a subscription made with Subscribe.
+func (n *ZmqNode) Unsubscribe(topic string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.topics[topic] {
+		return nil
+	}
+	if n.sub != nil {
+		if err := n.sub.SetOption(zmq4.OptionUnsubscribe, topic); err != nil {
+			return fmt.Errorf("failed to unsubscribe from %q: %w", topic, err)
+		}
+	}
+	delete(n.topics, topic)
+	return nil
+}
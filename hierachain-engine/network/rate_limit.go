@@ -0,0 +1,59 @@
+package network
+
+import (
+	"time"
+)
+
+// PeerRateLimit is a token-bucket limit on messages sent to a peer.
+type PeerRateLimit struct {
+	// MsgsPerSec is the sustained send rate; 0 or less disables the limit
+	MsgsPerSec float64 `json:"msgs_per_sec"`
+	// Burst is how many messages may be sent at once; at least 1 is allowed
+	Burst int `json:"burst"`
+}
+
+// enabled reports whether the limit restricts sends at all.
+func (l PeerRateLimit) enabled() bool {
+	return l.MsgsPerSec > 0
+}
+
+// tokenBucket holds the send allowance for one peer. It is not safe for
+// concurrent use.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket for limit.
+func newTokenBucket(limit PeerRateLimit, now time.Time) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.MsgsPerSec,
+		burst:  burst,
+		tokens: burst,
+		last:   now,
+	}
+}
+
+// allow refills the bucket for the time since the last call and takes a
+// token if one is available.
+func (b *tokenBucket) allow(now time.Time) bool {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
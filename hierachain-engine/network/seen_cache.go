@@ -0,0 +1,116 @@
+package network
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSeenEntries bounds the propagator's seen-message cache.
+const DefaultMaxSeenEntries = 100000
+
+// seenEntry is a cached message hash and when it was last seen.
+type seenEntry struct {
+	key    string
+	seenAt time.Time
+}
+
+// seenCache is a bounded set of message hashes with least-recently-used
+// eviction. It is safe for concurrent use.
+type seenCache struct {
+	maxEntries int
+	ll         *list.List // most recently used at the front
+	items      map[string]*list.Element
+	evictions  int64
+	mu         sync.Mutex
+}
+
+// newSeenCache creates a cache holding at most maxEntries hashes.
+// A maxEntries of zero or less leaves the cache unbounded.
+func newSeenCache(maxEntries int) *seenCache {
+	return &seenCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Store records key as seen at seenAt, evicting the least recently used
+// entry if the cache is full.
+func (c *seenCache) Store(key string, seenAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*seenEntry).seenAt = seenAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&seenEntry{key: key, seenAt: seenAt})
+	c.evictOverflow()
+}
+
+// Contains reports whether key has been seen, marking it recently used.
+func (c *seenCache) Contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if ok {
+		c.ll.MoveToFront(elem)
+	}
+	return ok
+}
+
+// RemoveOlderThan drops entries last seen before cutoff.
+func (c *seenCache) RemoveOlderThan(cutoff time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.ll.Front(); elem != nil; {
+		next := elem.Next()
+		if entry := elem.Value.(*seenEntry); entry.seenAt.Before(cutoff) {
+			c.ll.Remove(elem)
+			delete(c.items, entry.key)
+		}
+		elem = next
+	}
+}
+
+// SetMaxEntries changes the bound, evicting entries if the cache is now over it.
+func (c *seenCache) SetMaxEntries(maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxEntries = maxEntries
+	c.evictOverflow()
+}
+
+// Len returns the number of cached entries.
+func (c *seenCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Evictions returns how many entries were evicted to stay within the bound.
+func (c *seenCache) Evictions() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
+
+// evictOverflow removes least recently used entries above the bound.
+// The caller must hold c.mu.
+func (c *seenCache) evictOverflow() {
+	if c.maxEntries <= 0 {
+		return
+	}
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*seenEntry).key)
+		c.evictions++
+	}
+}
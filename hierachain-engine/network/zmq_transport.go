@@ -1,17 +1,21 @@
 // Package network provides ZeroMQ-based P2P networking for HieraChain.
 //
 // This package implements:
-//   - ZmqNode: ZeroMQ transport with ROUTER/DEALER pattern
+//   - ZmqNode: ZeroMQ transport with ROUTER/DEALER pattern and optional PUB/SUB fan-out
 //   - P2PManager: Peer discovery and management
 //   - Propagator: Message propagation with gossip protocol
 package network
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-zeromq/zmq4"
@@ -22,18 +26,86 @@ var (
 	ErrNodeNotRunning = errors.New("node is not running")
 	ErrPeerNotFound   = errors.New("peer not found")
 	ErrSendFailed     = errors.New("failed to send message")
+	ErrConnectTimeout = errors.New("timed out connecting to peer")
+	ErrObserverMode   = errors.New("observer nodes do not originate broadcasts")
+	ErrAckTimeout     = errors.New("peer did not acknowledge message")
+	ErrRateLimited    = errors.New("send rate limit exceeded for peer")
+	ErrPubSubDisabled = errors.New("pub/sub broadcasting is not enabled")
 )
 
+// errNoDealer is returned by acquireOpenDealer for a peer without an open DEALER socket.
+var errNoDealer = errors.New("no open dealer for peer")
+
 // MaxNetworkMessageSize is the maximum allowed size for network messages (10MB).
 // This prevents DoS attacks via oversized messages.
 const MaxNetworkMessageSize = 10 * 1024 * 1024 // 10MB
 
+// ZmqOptions tunes the ZeroMQ sockets created by a ZmqNode.
+// The pure-Go zmq4 transport has a single high-water mark and timeout per
+// socket, so they apply to both sending and receiving.
+type ZmqOptions struct {
+	// HWM caps the number of messages queued per socket; 0 keeps the library default
+	HWM int `json:"hwm"`
+	// Timeout bounds socket send, receive and handshake operations; 0 disables it
+	Timeout time.Duration `json:"timeout"`
+	// Linger bounds how long Stop waits for each socket to close; 0 waits indefinitely
+	Linger time.Duration `json:"linger"`
+	// HeartbeatInterval is how often every registered peer is pinged; 0 disables pings
+	HeartbeatInterval time.Duration `json:"heartbeat_interval"`
+	// MaxDealers caps the open DEALER sockets, closing the least recently
+	// used one when exceeded; 0 leaves them unbounded
+	MaxDealers int `json:"max_dealers"`
+}
+
+// DefaultZmqOptions returns socket options that bound queued memory and
+// keep Stop prompt under load.
+func DefaultZmqOptions() ZmqOptions {
+	return ZmqOptions{
+		HWM:               1000,
+		Timeout:           5 * time.Second,
+		Linger:            time.Second,
+		HeartbeatInterval: 20 * time.Second,
+		MaxDealers:        256,
+	}
+}
+
+// SecurityProvider supplies the zmq4 security mechanism for a node's sockets.
+// A nil Security from either method leaves that socket in plaintext.
+type SecurityProvider interface {
+	// Server returns the mechanism for the node's ROUTER socket
+	Server() zmq4.Security
+	// Client returns the mechanism for a DEALER socket dialing a peer
+	// registered with peerPublicKey, which may be empty
+	Client(peerPublicKey []byte) zmq4.Security
+}
+
+// Message types sent by ZmqNode itself.
+const (
+	MessageTypeDirect  = "direct"
+	MessageTypeAck     = "ack"
+	MessageTypePing    = "ping"
+	MessageTypePong    = "pong"
+	MessageTypePublish = "publish"
+)
+
+// MaxReliableAttempts is how many times SendReliable sends a message
+// before giving up on an acknowledgement.
+const MaxReliableAttempts = 3
+
 // PeerInfo contains information about a network peer.
 type PeerInfo struct {
 	ID        string    `json:"id"`
 	Address   string    `json:"address"`
-	PublicKey []byte    `json:"public_key,omitempty"`
+	PublicKey []byte    `json:"public_key,omitempty"` // transport security key
 	LastSeen  time.Time `json:"last_seen"`
+
+	// Ed25519 key the peer's messages are verified against; set with
+	// SetPeerSigningKey
+	SigningKey []byte `json:"signing_key,omitempty"`
+
+	// Advertised by the peer during peer exchange
+	Version      string   `json:"version,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
 }
 
 // Message represents a network message.
@@ -45,6 +117,11 @@ type Message struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Nonce     string                 `json:"nonce,omitempty"`
 	Hops      int                    `json:"hops,omitempty"`
+	Seq       uint64                 `json:"seq,omitempty"`       // per-peer sequence for direct messages
+	Signature []byte                 `json:"signature,omitempty"` // Ed25519 signature over the other fields
+	ID        string                 `json:"id,omitempty"`        // reliable message ID, or the ID an ack is for
+	Reliable  bool                   `json:"reliable,omitempty"`  // the sender waits for an ack
+	Topic     string                 `json:"topic,omitempty"`     // pub/sub topic the message was published on
 }
 
 // MessageHandler is a callback for processing received messages.
@@ -61,7 +138,30 @@ type ZmqNode struct {
 	cancel context.CancelFunc
 
 	router  zmq4.Socket            // ROUTER socket for receiving
-	dealers map[string]zmq4.Socket // DEALER sockets for sending (per peer)
+	dealers map[string]*peerDealer // DEALER sockets for sending (per peer)
+	options ZmqOptions
+
+	security   SecurityProvider   // nil keeps sockets in plaintext
+	signingKey ed25519.PrivateKey // nil sends messages unsigned
+
+	// Optional PUB/SUB fan-out alongside ROUTER/DEALER, guarded by mu.
+	// The PUB socket is bound at Start if pubPort is set; the SUB socket
+	// is created when the first publisher is connected.
+	pubPort    int
+	pub        *peerDealer // PUB socket with its in-flight sends
+	sub        zmq4.Socket
+	topics     map[string]bool
+	publishers map[string]bool // PUB endpoints the SUB socket is connected to
+
+	// Per-peer send rate limits, guarded by mu. Overrides replace
+	// rateLimit for their peer; buckets are created on first send.
+	rateLimit     PeerRateLimit
+	rateOverrides map[string]PeerRateLimit
+	limiters      map[string]*tokenBucket
+
+	// Outgoing compression of messages of at least compressMin bytes
+	compress    bool
+	compressMin int
 
 	peers map[string]*PeerInfo
 	mu    sync.RWMutex
@@ -69,16 +169,73 @@ type ZmqNode struct {
 	// Message handling
 	handler MessageHandler
 	msgChan chan *Message
+	msgLog  MessageLog // optional durable log of received messages
 
 	// Replay protection
 	replayCache     map[string]time.Time
 	replayCacheMu   sync.RWMutex
 	replayTolerance time.Duration
 
+	// Per-peer direct message sequencing
+	sendSeq     map[string]uint64 // peer -> last sequence sent
+	recvSeq     map[string]uint64 // sender -> last sequence accepted
+	seqMu       sync.Mutex
+	seqGaps     int64
+	seqRejected int64
+
+	// Atomic count of received messages that failed to decode
+	parseFailures int64
+
+	// In-flight SendReliable calls by message ID
+	pendingAcks map[string]*pendingAck
+	ackMu       sync.Mutex
+	reliableSeq uint64 // atomic counter for reliable message IDs
+	retransmits int64  // atomic count of reliable messages sent again
+
+	// Atomic traffic counters. Sent counts cover messages handed to a
+	// socket; dropped counts failed sends and messages discarded because
+	// msgChan was full.
+	bytesSent        int64
+	bytesReceived    int64
+	messagesSent     int64
+	messagesReceived int64
+	messagesDropped  int64
+
+	// Atomic count of received messages that failed to decompress
+	decompressFailures int64
+
+	// Atomic count of received messages dropped for a missing or invalid signature
+	signatureFailures int64
+
+	// Atomic count of sends discarded because their peer was unregistered
+	// while the send was starting
+	prunedSends int64
+
+	// In-flight sends, so Flush can wait for them; sendIdle is closed and
+	// replaced each time the count drops to zero
+	sending  int64
+	sendIdle chan struct{}
+	sendMu   sync.Mutex
+
 	running bool
 	wg      sync.WaitGroup
 }
 
+// peerDealer is a peer's DEALER socket with the sends currently using it,
+// so unregistering the peer or evicting the socket closes it only once
+// they finish.
+type peerDealer struct {
+	sock     zmq4.Socket
+	sends    sync.WaitGroup
+	lastUsed time.Time // guarded by ZmqNode.mu
+}
+
+// pendingAck is a reliable message waiting for its peer's acknowledgement.
+type pendingAck struct {
+	peerID string
+	acked  chan struct{} // closed when the ack arrives
+}
+
 // NewZmqNode creates a new ZeroMQ node.
 func NewZmqNode(nodeID string, host string, port int) *ZmqNode {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -90,11 +247,149 @@ func NewZmqNode(nodeID string, host string, port int) *ZmqNode {
 		address:         fmt.Sprintf("tcp://%s:%d", host, port),
 		ctx:             ctx,
 		cancel:          cancel,
-		dealers:         make(map[string]zmq4.Socket),
+		dealers:         make(map[string]*peerDealer),
+		options:         DefaultZmqOptions(),
 		peers:           make(map[string]*PeerInfo),
 		msgChan:         make(chan *Message, 1000),
 		replayCache:     make(map[string]time.Time),
 		replayTolerance: 60 * time.Second,
+		sendSeq:         make(map[string]uint64),
+		recvSeq:         make(map[string]uint64),
+		pendingAcks:     make(map[string]*pendingAck),
+		rateOverrides:   make(map[string]PeerRateLimit),
+		topics:          make(map[string]bool),
+		publishers:      make(map[string]bool),
+		limiters:        make(map[string]*tokenBucket),
+		sendIdle:        make(chan struct{}),
+	}
+}
+
+// NewZmqNodeWithSecurity creates a ZeroMQ node whose sockets use the
+// mechanisms returned by provider. Peers registered with a public key
+// have it passed to provider.Client when their DEALER socket is created.
+func NewZmqNodeWithSecurity(nodeID string, host string, port int, provider SecurityProvider) *ZmqNode {
+	n := NewZmqNode(nodeID, host, port)
+	n.security = provider
+	return n
+}
+
+// SetOptions sets the socket options. Options apply to sockets created
+// afterwards, so call it before Start.
+func (n *ZmqNode) SetOptions(opts ZmqOptions) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.options = opts
+}
+
+// SetMessageLog sets a log that every accepted message is appended to
+// before it is queued for processing. Passing nil disables logging.
+// Replaying the log on restart is up to the caller.
+func (n *ZmqNode) SetMessageLog(msgLog MessageLog) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.msgLog = msgLog
+}
+
+// SetSigningKey sets the Ed25519 key that outgoing messages are signed
+// with. Passing nil sends messages unsigned.
+func (n *ZmqNode) SetSigningKey(priv ed25519.PrivateKey) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.signingKey = priv
+}
+
+// SetCompression sets whether outgoing messages of at least minSize
+// serialized bytes are compressed with zstd. Incoming compressed messages
+// are always accepted. A minSize of zero or less uses
+// DefaultCompressionMinSize.
+func (n *ZmqNode) SetCompression(enabled bool, minSize int) {
+	if minSize <= 0 {
+		minSize = DefaultCompressionMinSize
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.compress = enabled
+	n.compressMin = minSize
+}
+
+// SetPeerRateLimit limits SendDirect to each peer without an override to
+// msgsPerSec messages per second, allowing bursts of up to burst
+// messages. A msgsPerSec of 0 or less removes the limit. Sends over the
+// limit fail with ErrRateLimited instead of blocking.
+func (n *ZmqNode) SetPeerRateLimit(msgsPerSec float64, burst int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.rateLimit = PeerRateLimit{MsgsPerSec: msgsPerSec, Burst: burst}
+	for peerID := range n.limiters {
+		if _, ok := n.rateOverrides[peerID]; !ok {
+			delete(n.limiters, peerID)
+		}
+	}
+}
+
+// SetPeerRateLimitOverride sets a limit for one peer in place of the one
+// from SetPeerRateLimit. A msgsPerSec of 0 or less leaves the peer
+// unlimited.
+func (n *ZmqNode) SetPeerRateLimitOverride(peerID string, msgsPerSec float64, burst int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.rateOverrides[peerID] = PeerRateLimit{MsgsPerSec: msgsPerSec, Burst: burst}
+	delete(n.limiters, peerID)
+}
+
+// RemovePeerRateLimitOverride returns a peer to the limit from
+// SetPeerRateLimit.
+func (n *ZmqNode) RemovePeerRateLimitOverride(peerID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.rateOverrides, peerID)
+	delete(n.limiters, peerID)
+}
+
+// socketOptions returns the zmq4 options for a new socket.
+// The caller must hold n.mu.
+func (n *ZmqNode) socketOptions() []zmq4.Option {
+	opts := []zmq4.Option{zmq4.WithID(zmq4.SocketIdentity(n.nodeID))}
+	if n.options.Timeout > 0 {
+		opts = append(opts, zmq4.WithTimeout(n.options.Timeout), zmq4.WithDialerTimeout(n.options.Timeout))
+	}
+	return opts
+}
+
+// applySocketOptions sets the per-socket options that zmq4 exposes after creation.
+// The caller must hold n.mu.
+func (n *ZmqNode) applySocketOptions(sock zmq4.Socket) error {
+	if n.options.HWM > 0 {
+		if err := sock.SetOption(zmq4.OptionHWM, n.options.HWM); err != nil {
+			return fmt.Errorf("failed to set HWM: %w", err)
+		}
+	}
+	return nil
+}
+
+// closeSocket closes sock, giving up after the linger period.
+func closeSocket(sock zmq4.Socket, linger time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		_ = sock.Close() // Best effort: errors are expected during shutdown
+		close(done)
+	}()
+
+	if linger <= 0 {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(linger)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
 	}
 }
 
@@ -107,7 +402,17 @@ func (n *ZmqNode) Start() error {
 	}
 
 	// Create ROUTER socket for receiving messages
-	n.router = zmq4.NewRouter(n.ctx, zmq4.WithID(zmq4.SocketIdentity(n.nodeID)))
+	opts := n.socketOptions()
+	if n.security != nil {
+		if sec := n.security.Server(); sec != nil {
+			opts = append(opts, zmq4.WithSecurity(sec))
+		}
+	}
+	n.router = zmq4.NewRouter(n.ctx, opts...)
+	if err := n.applySocketOptions(n.router); err != nil {
+		n.mu.Unlock()
+		return err
+	}
 
 	// Bind to address
 	if err := n.router.Listen(n.address); err != nil {
@@ -115,12 +420,22 @@ func (n *ZmqNode) Start() error {
 		return fmt.Errorf("failed to bind router: %w", err)
 	}
 
+	// Bind the PUB socket for BroadcastPubSub
+	if n.pubPort > 0 {
+		if err := n.bindPubLocked(); err != nil {
+			closeSocket(n.router, n.options.Linger)
+			n.mu.Unlock()
+			return err
+		}
+	}
+
 	n.running = true
+	heartbeat := n.options.HeartbeatInterval
 	n.mu.Unlock()
 
 	// Start receiver goroutine
 	n.wg.Add(1)
-	go n.receiverLoop()
+	go n.receiverLoop(n.router)
 
 	// Start message processor
 	n.wg.Add(1)
@@ -130,6 +445,12 @@ func (n *ZmqNode) Start() error {
 	n.wg.Add(1)
 	go n.replayCacheCleaner()
 
+	// Start peer heartbeats
+	if heartbeat > 0 {
+		n.wg.Add(1)
+		go n.heartbeatLoop(heartbeat)
+	}
+
 	return nil
 }
 
@@ -141,24 +462,28 @@ func (n *ZmqNode) Stop() {
 		return
 	}
 	n.running = false
+	linger := n.options.Linger
+	dealers := n.dealers
+	n.dealers = make(map[string]*peerDealer)
+	pub, sub := n.pub, n.sub
 	n.mu.Unlock()
 
 	// Cancel context to stop goroutines
 	n.cancel()
 
-	// Close router socket (best effort - ignore errors during shutdown)
+	// Close router, pub/sub and dealer sockets (best effort, bounded by linger)
 	if n.router != nil {
-		if err := n.router.Close(); err != nil {
-			// Log in production; during shutdown, errors are expected
-			_ = err // G104: explicitly acknowledge
-		}
+		closeSocket(n.router, linger)
 	}
-
-	// Close all dealer sockets (best effort)
-	for _, dealer := range n.dealers {
-		if err := dealer.Close(); err != nil {
-			_ = err // G104: explicitly acknowledge during cleanup
-		}
+	if pub != nil {
+		pub.sends.Wait()
+		closeSocket(pub.sock, linger)
+	}
+	if sub != nil {
+		closeSocket(sub, linger)
+	}
+	for _, dealer := range dealers {
+		closeSocket(dealer.sock, linger)
 	}
 
 	// Wait for goroutines to finish
@@ -167,32 +492,97 @@ func (n *ZmqNode) Stop() {
 	close(n.msgChan)
 }
 
-// RegisterPeer adds a peer to the known peers list.
+// RegisterPeer adds a peer to the known peers list. publicKey is the
+// peer's transport security key, passed to the SecurityProvider.
+// Registering a known peer again keeps its signing key.
 func (n *ZmqNode) RegisterPeer(peerID, address string, publicKey []byte) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	var signingKey []byte
+	if peer, ok := n.peers[peerID]; ok {
+		signingKey = peer.SigningKey
+	}
 	n.peers[peerID] = &PeerInfo{
-		ID:        peerID,
-		Address:   address,
-		PublicKey: publicKey,
-		LastSeen:  time.Now(),
+		ID:         peerID,
+		Address:    address,
+		PublicKey:  publicKey,
+		LastSeen:   time.Now(),
+		SigningKey: signingKey,
 	}
 }
 
-// UnregisterPeer removes a peer from the known peers list.
+// SetPeerSigningKey sets the Ed25519 public key that messages from a
+// registered peer must be signed with. Passing nil accepts its messages
+// unchecked. It returns ErrPeerNotFound if the peer is not registered.
+func (n *ZmqNode) SetPeerSigningKey(peerID string, publicKey ed25519.PublicKey) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	peer, ok := n.peers[peerID]
+	if !ok {
+		return ErrPeerNotFound
+	}
+	peer.SigningKey = publicKey
+	return nil
+}
+
+// UnregisterPeer removes a peer from the known peers list. Its dealer
+// socket is closed in the background once in-flight sends to the peer
+// finish; sends that have not yet reached the socket are discarded and
+// counted in NodeStats.PrunedSends. Its rate limit bucket is discarded.
 func (n *ZmqNode) UnregisterPeer(peerID string) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
 	delete(n.peers, peerID)
-
-	// Close dealer socket if exists (best effort)
+	delete(n.limiters, peerID)
 	if dealer, ok := n.dealers[peerID]; ok {
-		if err := dealer.Close(); err != nil {
-			_ = err // G104: explicitly acknowledge during cleanup
-		}
 		delete(n.dealers, peerID)
+		n.retireDealerLocked(dealer)
+	}
+}
+
+// retireDealerLocked closes a dealer already removed from n.dealers in
+// the background, once its in-flight sends finish. Sends started after
+// removal create a fresh dealer. The caller must hold n.mu.
+func (n *ZmqNode) retireDealerLocked(dealer *peerDealer) {
+	linger := n.options.Linger
+	n.wg.Add(1)
+
+	// In-flight sends are bounded by the socket timeout, and Stop cancels
+	// them, so this does not outlive the node
+	go func() {
+		defer n.wg.Done()
+		dealer.sends.Wait()
+		closeSocket(dealer.sock, linger)
+	}()
+}
+
+// evictDealersLocked retires least recently used dealers until at most
+// MaxDealers remain, never evicting keep. The caller must hold n.mu.
+func (n *ZmqNode) evictDealersLocked(keep string) {
+	if n.options.MaxDealers <= 0 {
+		return
+	}
+
+	for len(n.dealers) > n.options.MaxDealers {
+		var oldestID string
+		var oldest *peerDealer
+		for id, dealer := range n.dealers {
+			if id == keep {
+				continue
+			}
+			if oldest == nil || dealer.lastUsed.Before(oldest.lastUsed) {
+				oldestID, oldest = id, dealer
+			}
+		}
+		if oldest == nil {
+			return
+		}
+
+		delete(n.dealers, oldestID)
+		n.retireDealerLocked(oldest)
 	}
 }
 
@@ -203,51 +593,344 @@ func (n *ZmqNode) SetHandler(handler MessageHandler) {
 	n.handler = handler
 }
 
-// SendDirect sends a message directly to a specific peer.
+// SendDirect sends a message directly to a specific peer. It returns
+// ErrRateLimited if the peer's send rate limit is exhausted.
 func (n *ZmqNode) SendDirect(peerID string, payload map[string]interface{}) error {
+	if !n.allowSend(peerID) {
+		return fmt.Errorf("%w: %s", ErrRateLimited, peerID)
+	}
+	return n.sendMessage(peerID, n.newMessage(MessageTypeDirect, peerID, payload), true)
+}
+
+// allowSend takes a token from the peer's rate limit bucket, creating the
+// bucket on first use. Sends to unregistered peers are let through to
+// fail on their own, so they do not leave buckets behind.
+func (n *ZmqNode) allowSend(peerID string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	limit, ok := n.rateOverrides[peerID]
+	if !ok {
+		limit = n.rateLimit
+	}
+	if !limit.enabled() {
+		return true
+	}
+	if _, registered := n.peers[peerID]; !registered {
+		return true
+	}
+
+	now := time.Now()
+	bucket, ok := n.limiters[peerID]
+	if !ok {
+		bucket = newTokenBucket(limit, now)
+		n.limiters[peerID] = bucket
+	}
+	return bucket.allow(now)
+}
+
+// SendReliable sends a message to a specific peer and waits for the peer
+// to acknowledge it, retransmitting up to MaxReliableAttempts times.
+// timeout bounds the wait for each acknowledgement. The peer must have
+// this node registered to send the acknowledgement back. Reliable
+// messages carry no sequence number, so retransmissions are not rejected
+// as stale; duplicates are dropped by the replay check instead.
+func (n *ZmqNode) SendReliable(peerID string, payload map[string]interface{}, timeout time.Duration) error {
+	msg := n.newMessage(MessageTypeDirect, peerID, payload)
+	msg.ID = fmt.Sprintf("%s-%d", n.nodeID, atomic.AddUint64(&n.reliableSeq, 1))
+	msg.Reliable = true
+
+	acked := make(chan struct{})
+	n.ackMu.Lock()
+	n.pendingAcks[msg.ID] = &pendingAck{peerID: peerID, acked: acked}
+	n.ackMu.Unlock()
+
+	defer func() {
+		n.ackMu.Lock()
+		delete(n.pendingAcks, msg.ID)
+		n.ackMu.Unlock()
+	}()
+
+	for attempt := 1; attempt <= MaxReliableAttempts; attempt++ {
+		if attempt > 1 {
+			atomic.AddInt64(&n.retransmits, 1)
+		}
+
+		if err := n.sendMessage(peerID, msg, false); err != nil {
+			if errors.Is(err, ErrNodeNotRunning) || errors.Is(err, ErrPeerNotFound) {
+				return err
+			}
+			// A failed send is retried like a lost message
+		}
+
+		timer := time.NewTimer(timeout)
+		select {
+		case <-acked:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		case <-n.ctx.Done():
+			timer.Stop()
+			return ErrNodeNotRunning
+		}
+	}
+
+	return fmt.Errorf("%w: %s after %d attempts", ErrAckTimeout, peerID, MaxReliableAttempts)
+}
+
+// newMessage creates an outgoing message from this node.
+func (n *ZmqNode) newMessage(msgType, peerID string, payload map[string]interface{}) *Message {
+	return &Message{
+		Type:      msgType,
+		From:      n.nodeID,
+		To:        peerID,
+		Payload:   payload,
+		Timestamp: time.Now(),
+		Nonce:     fmt.Sprintf("%d-%s", time.Now().UnixNano(), n.nodeID),
+	}
+}
+
+// sendMessage signs msg if a signing key is set and sends it to a
+// registered peer. If sequenced, msg is given the peer's next sequence
+// number once its socket is ready.
+func (n *ZmqNode) sendMessage(peerID string, msg *Message, sequenced bool) error {
+	return n.send(peerID, msg, sequenced, n.acquireDealer)
+}
+
+// send is sendMessage with the dealer obtained through acquire.
+func (n *ZmqNode) send(peerID string, msg *Message, sequenced bool, acquire func(string) (*peerDealer, error)) error {
 	n.mu.RLock()
 	if !n.running {
 		n.mu.RUnlock()
 		return ErrNodeNotRunning
 	}
 
-	peer, ok := n.peers[peerID]
-	if !ok {
+	if _, ok := n.peers[peerID]; !ok {
 		n.mu.RUnlock()
 		return ErrPeerNotFound
 	}
+	signingKey := n.signingKey
+	compress, compressMin := n.compress, n.compressMin
+	n.beginSend()
 	n.mu.RUnlock()
+	defer n.endSend()
 
-	// Get or create dealer socket
-	dealer, err := n.getOrCreateDealer(peerID, peer.Address)
+	// Get or create dealer socket, holding it open until the send is done
+	dealer, err := acquire(peerID)
 	if err != nil {
+		if errors.Is(err, ErrPeerNotFound) {
+			atomic.AddInt64(&n.prunedSends, 1)
+		}
 		return err
 	}
+	defer dealer.sends.Done()
 
-	// Create message
-	msg := &Message{
-		Type:      "direct",
-		From:      n.nodeID,
-		To:        peerID,
-		Payload:   payload,
-		Timestamp: time.Now(),
-		Nonce:     fmt.Sprintf("%d-%s", time.Now().UnixNano(), n.nodeID),
+	if sequenced {
+		msg.Seq = n.nextSeq(peerID)
 	}
 
 	// Serialize and send
-	data, err := json.Marshal(msg)
+	frame, err := sealMessage(msg, signingKey, compress, compressMin)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return err
 	}
 
-	msgFrame := zmq4.NewMsg(data)
-	if err := dealer.Send(msgFrame); err != nil {
+	if err := dealer.sock.Send(zmq4.NewMsg(frame)); err != nil {
+		atomic.AddInt64(&n.messagesDropped, 1)
 		return fmt.Errorf("%w: %v", ErrSendFailed, err)
 	}
 
+	atomic.AddInt64(&n.messagesSent, 1)
+	atomic.AddInt64(&n.bytesSent, int64(len(frame)))
 	return nil
 }
 
+// sealMessage signs msg with signingKey, if set, and returns its wire
+// encoding, compressed if enabled and the message is large enough.
+func sealMessage(msg *Message, signingKey ed25519.PrivateKey, compress bool, compressMin int) ([]byte, error) {
+	msg.Signature = nil
+	if signingKey != nil {
+		data, err := signingBytes(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal message: %w", err)
+		}
+		msg.Signature = ed25519.Sign(signingKey, data)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return encodeFrame(data, compress, compressMin), nil
+}
+
+// sendReply sends an ack or pong back to the sender of a received message.
+// id is the message being acknowledged, if any.
+func (n *ZmqNode) sendReply(msgType, to, id string) {
+	defer n.wg.Done()
+
+	msg := n.newMessage(msgType, to, nil)
+	msg.ID = id
+	if err := n.sendMessage(to, msg, false); err != nil && !errors.Is(err, ErrNodeNotRunning) {
+		log.Printf("Warning: failed to send %s to %s: %v", msgType, to, err)
+	}
+}
+
+// heartbeatLoop pings every registered peer each interval, so idle but
+// healthy peers keep refreshing their LastSeen through the pongs.
+func (n *ZmqNode) heartbeatLoop(interval time.Duration) {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+			n.pingPeers()
+		}
+	}
+}
+
+// pingPeers sends a ping to every registered peer. Pings do not count as
+// use, so the MaxDealers LRU is left as it was: a peer without an open
+// DEALER socket is pinged through a temporary one. Unreachable peers are
+// skipped; their LastSeen going stale is what reports them.
+func (n *ZmqNode) pingPeers() {
+	n.mu.RLock()
+	peerIDs := make([]string, 0, len(n.peers))
+	for id := range n.peers {
+		peerIDs = append(peerIDs, id)
+	}
+	n.mu.RUnlock()
+
+	for _, peerID := range peerIDs {
+		if n.ctx.Err() != nil {
+			return
+		}
+		ping := n.newMessage(MessageTypePing, peerID, nil)
+		if err := n.send(peerID, ping, false, n.acquireOpenDealer); errors.Is(err, errNoDealer) {
+			n.pingUnconnected(peerID, ping)
+		}
+	}
+}
+
+// pingUnconnected sends ping through a temporary DEALER socket that is
+// closed afterwards and never joins the dealer pool.
+func (n *ZmqNode) pingUnconnected(peerID string, ping *Message) {
+	var temp *peerDealer
+	_ = n.send(peerID, ping, false, func(peerID string) (*peerDealer, error) {
+		dealer, err := n.dialTemporaryDealer(peerID)
+		if err != nil {
+			return nil, err
+		}
+		dealer.sends.Add(1)
+		temp = dealer
+		return dealer, nil
+	})
+	if temp == nil {
+		return
+	}
+
+	n.mu.RLock()
+	linger := n.options.Linger
+	n.mu.RUnlock()
+	closeSocket(temp.sock, linger)
+}
+
+// resolveAck wakes the SendReliable call waiting on an acknowledgement.
+// Acknowledgements from a peer other than the one sent to are ignored.
+func (n *ZmqNode) resolveAck(from, id string) {
+	n.ackMu.Lock()
+	defer n.ackMu.Unlock()
+
+	pending, ok := n.pendingAcks[id]
+	if !ok || pending.peerID != from {
+		return
+	}
+	delete(n.pendingAcks, id)
+	close(pending.acked)
+}
+
+// ConnectPeer establishes the DEALER connection to a registered peer ahead of
+// the first send, returning once it is ready or the timeout elapses.
+// It is a no-op if the peer is already connected.
+func (n *ZmqNode) ConnectPeer(peerID string, timeout time.Duration) error {
+	n.mu.RLock()
+	if !n.running {
+		n.mu.RUnlock()
+		return ErrNodeNotRunning
+	}
+
+	if _, ok := n.peers[peerID]; !ok {
+		n.mu.RUnlock()
+		return ErrPeerNotFound
+	}
+	n.mu.RUnlock()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := n.getOrCreateDealer(peerID)
+		done <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w: %s", ErrConnectTimeout, peerID)
+	case <-n.ctx.Done():
+		return ErrNodeNotRunning
+	}
+}
+
+// beginSend marks a send as in flight.
+func (n *ZmqNode) beginSend() {
+	atomic.AddInt64(&n.sending, 1)
+}
+
+// endSend marks a send as finished, waking Flush callers when none remain.
+func (n *ZmqNode) endSend() {
+	if atomic.AddInt64(&n.sending, -1) != 0 {
+		return
+	}
+
+	n.sendMu.Lock()
+	close(n.sendIdle)
+	n.sendIdle = make(chan struct{})
+	n.sendMu.Unlock()
+}
+
+// Flush blocks until every in-flight SendDirect and Broadcast call has
+// handed its message to the socket, or ctx ends. Call it before Stop so
+// messages sent just before shutdown, such as a block broadcast, are not
+// cut off when the sockets close. Sends started after Flush is called may
+// or may not be waited for.
+func (n *ZmqNode) Flush(ctx context.Context) error {
+	for {
+		// Take the channel before checking, so a drop to zero after the
+		// check closes the channel being waited on
+		n.sendMu.Lock()
+		idle := n.sendIdle
+		n.sendMu.Unlock()
+
+		if atomic.LoadInt64(&n.sending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // Broadcast sends a message to all registered peers.
 func (n *ZmqNode) Broadcast(payload map[string]interface{}, exclude []string) error {
 	n.mu.RLock()
@@ -289,10 +972,11 @@ func (n *ZmqNode) GetPeers() map[string]*PeerInfo {
 	peers := make(map[string]*PeerInfo)
 	for id, peer := range n.peers {
 		peers[id] = &PeerInfo{
-			ID:        peer.ID,
-			Address:   peer.Address,
-			PublicKey: peer.PublicKey,
-			LastSeen:  peer.LastSeen,
+			ID:         peer.ID,
+			Address:    peer.Address,
+			PublicKey:  peer.PublicKey,
+			LastSeen:   peer.LastSeen,
+			SigningKey: peer.SigningKey,
 		}
 	}
 	return peers
@@ -304,27 +988,124 @@ func (n *ZmqNode) Messages() <-chan *Message {
 }
 
 // getOrCreateDealer gets or creates a DEALER socket for a peer.
-func (n *ZmqNode) getOrCreateDealer(peerID, address string) (zmq4.Socket, error) {
+func (n *ZmqNode) getOrCreateDealer(peerID string) (*peerDealer, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.dealerLocked(peerID)
+}
+
+// acquireDealer is getOrCreateDealer for a send: it registers the send on
+// the dealer, and the caller must call dealer.sends.Done when finished.
+func (n *ZmqNode) acquireDealer(peerID string) (*peerDealer, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	dealer, err := n.dealerLocked(peerID)
+	if err != nil {
+		return nil, err
+	}
+	dealer.sends.Add(1)
+	dealer.lastUsed = time.Now()
+	return dealer, nil
+}
+
+// acquireOpenDealer is acquireDealer for heartbeats: it neither creates a
+// dealer nor marks it used. It returns errNoDealer if peerID has none open.
+func (n *ZmqNode) acquireOpenDealer(peerID string) (*peerDealer, error) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	if !n.running {
+		return nil, ErrNodeNotRunning
+	}
+	dealer, ok := n.dealers[peerID]
+	if !ok {
+		return nil, errNoDealer
+	}
+	dealer.sends.Add(1)
+	return dealer, nil
+}
+
+// dealerLocked gets or creates the DEALER socket for a registered peer.
+// The peer is looked up again, so a peer unregistered since the caller
+// last checked does not get a new socket. The caller must hold n.mu.
+func (n *ZmqNode) dealerLocked(peerID string) (*peerDealer, error) {
+	if !n.running {
+		return nil, ErrNodeNotRunning
+	}
+
+	peer, ok := n.peers[peerID]
+	if !ok {
+		return nil, ErrPeerNotFound
+	}
+
 	if dealer, ok := n.dealers[peerID]; ok {
 		return dealer, nil
 	}
 
 	// Create new DEALER socket
-	dealer := zmq4.NewDealer(n.ctx, zmq4.WithID(zmq4.SocketIdentity(n.nodeID)))
+	sock, err := n.newDealerLocked(peer)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := dealer.Dial(address); err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	if err := sock.Dial(peer.Address); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", peer.Address, err)
 	}
 
+	dealer := &peerDealer{sock: sock, lastUsed: time.Now()}
 	n.dealers[peerID] = dealer
+	n.evictDealersLocked(peerID)
 	return dealer, nil
 }
 
-// receiverLoop continuously receives messages from the ROUTER socket.
-func (n *ZmqNode) receiverLoop() {
+// newDealerLocked creates an undialled DEALER socket for peer, secured
+// with its public key. The caller must hold n.mu.
+func (n *ZmqNode) newDealerLocked(peer *PeerInfo) (zmq4.Socket, error) {
+	opts := n.socketOptions()
+	if n.security != nil {
+		if sec := n.security.Client(peer.PublicKey); sec != nil {
+			opts = append(opts, zmq4.WithSecurity(sec))
+		}
+	}
+	sock := zmq4.NewDealer(n.ctx, opts...)
+	if err := n.applySocketOptions(sock); err != nil {
+		return nil, err
+	}
+	return sock, nil
+}
+
+// dialTemporaryDealer connects a DEALER socket to a registered peer
+// without adding it to n.dealers; the caller closes it. The dial happens
+// without holding n.mu.
+func (n *ZmqNode) dialTemporaryDealer(peerID string) (*peerDealer, error) {
+	n.mu.Lock()
+	if !n.running {
+		n.mu.Unlock()
+		return nil, ErrNodeNotRunning
+	}
+	peer, ok := n.peers[peerID]
+	if !ok {
+		n.mu.Unlock()
+		return nil, ErrPeerNotFound
+	}
+	address := peer.Address
+	sock, err := n.newDealerLocked(peer)
+	n.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sock.Dial(address); err != nil {
+		_ = sock.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	return &peerDealer{sock: sock}, nil
+}
+
+// receiverLoop continuously receives messages from the ROUTER or SUB socket.
+func (n *ZmqNode) receiverLoop(sock zmq4.Socket) {
 	defer n.wg.Done()
 
 	for {
@@ -332,7 +1113,7 @@ func (n *ZmqNode) receiverLoop() {
 		case <-n.ctx.Done():
 			return
 		default:
-			msg, err := n.router.Recv()
+			msg, err := sock.Recv()
 			if err != nil {
 				// Check if context cancelled
 				select {
@@ -343,37 +1124,124 @@ func (n *ZmqNode) receiverLoop() {
 				}
 			}
 
-			// Check message size to prevent DoS
-			msgBytes := msg.Bytes()
-			if len(msgBytes) > MaxNetworkMessageSize {
-				continue // Drop oversized messages
+			size := 0
+			for _, frame := range msg.Frames {
+				size += len(frame)
 			}
+			atomic.AddInt64(&n.messagesReceived, 1)
+			atomic.AddInt64(&n.bytesReceived, int64(size))
 
-			// Parse message
-			var netMsg Message
-			if err := json.Unmarshal(msgBytes, &netMsg); err != nil {
-				continue
-			}
+			n.handleFrames(msg.Frames)
+		}
+	}
+}
 
-			// Check replay
-			if !n.isValidReplay(&netMsg) {
-				continue
-			}
+// handleFrames decodes one multipart message received on the ROUTER or SUB
+// socket and queues it for processing. The first frame is the sender's
+// identity, prepended by the ROUTER, or the topic of a published message;
+// the remaining frames are joined so a payload split across frames
+// is decoded as one message. It returns false if the message was dropped.
+func (n *ZmqNode) handleFrames(frames [][]byte) bool {
+	if len(frames) > 1 {
+		frames = frames[1:] // Strip the identity or topic frame
+	}
 
-			// Update peer last seen
-			n.mu.Lock()
-			if peer, ok := n.peers[netMsg.From]; ok {
-				peer.LastSeen = time.Now()
-			}
-			n.mu.Unlock()
+	size := 0
+	for _, frame := range frames {
+		size += len(frame)
+	}
 
-			// Send to channel (non-blocking)
-			select {
-			case n.msgChan <- &netMsg:
-			default:
-				// Channel full, drop message
-			}
+	// Check message size to prevent DoS
+	if size > MaxNetworkMessageSize {
+		return false // Drop oversized messages
+	}
+
+	payload := make([]byte, 0, size)
+	for _, frame := range frames {
+		payload = append(payload, frame...)
+	}
+
+	payload, err := decodeFrame(payload)
+	if err != nil {
+		if failures := atomic.AddInt64(&n.decompressFailures, 1); failures == 1 || failures%100 == 0 {
+			log.Printf("Warning: failed to decompress network message (%d failures so far): %v", failures, err)
+		}
+		return false
+	}
+
+	// Parse message
+	var netMsg Message
+	if err := json.Unmarshal(payload, &netMsg); err != nil {
+		if failures := atomic.AddInt64(&n.parseFailures, 1); failures == 1 || failures%100 == 0 {
+			log.Printf("Warning: failed to parse network message (%d failures so far): %v", failures, err)
+		}
+		return false
+	}
+
+	// Drop forged messages before they reach the replay cache
+	if !n.verifySignature(payload, &netMsg) {
+		if failures := atomic.AddInt64(&n.signatureFailures, 1); failures == 1 || failures%100 == 0 {
+			log.Printf("Warning: dropped message from %s with missing or invalid signature (%d so far)", netMsg.From, failures)
 		}
+		return false
+	}
+
+	// Acknowledge reliable messages even if they turn out to be
+	// retransmissions, since the earlier ack may have been lost.
+	// Acks are never acknowledged themselves.
+	if netMsg.Reliable && netMsg.ID != "" && netMsg.Type != MessageTypeAck {
+		n.wg.Add(1)
+		go n.sendReply(MessageTypeAck, netMsg.From, netMsg.ID)
+	}
+
+	// Check replay
+	if !n.isValidReplay(&netMsg) {
+		return false
+	}
+
+	// Drop duplicate or stale direct messages
+	if !n.acceptSequence(&netMsg) {
+		return false
+	}
+
+	// Update peer last seen
+	n.mu.Lock()
+	if peer, ok := n.peers[netMsg.From]; ok {
+		peer.LastSeen = time.Now()
+	}
+	msgLog := n.msgLog
+	n.mu.Unlock()
+
+	// Acks and heartbeats are consumed here rather than delivered to the
+	// handler; receiving them has already refreshed the peer's LastSeen
+	switch netMsg.Type {
+	case MessageTypeAck:
+		n.resolveAck(netMsg.From, netMsg.ID)
+		return true
+	case MessageTypePing:
+		n.wg.Add(1)
+		go n.sendReply(MessageTypePong, netMsg.From, "")
+		return true
+	case MessageTypePong:
+		return true
+	}
+
+	// Record the message durably before it is processed
+	if msgLog != nil {
+		if err := msgLog.Append(&netMsg); err != nil {
+			log.Printf("Warning: failed to append message from %s to log: %v", netMsg.From, err)
+			return false
+		}
+	}
+
+	// Send to channel (non-blocking)
+	select {
+	case n.msgChan <- &netMsg:
+		return true
+	default:
+		// Channel full, drop message
+		atomic.AddInt64(&n.messagesDropped, 1)
+		return false
 	}
 }
 
@@ -401,6 +1269,61 @@ func (n *ZmqNode) messageProcessor() {
 	}
 }
 
+// signingBytes returns the canonical encoding of msg that its signature
+// covers: the message without its signature, decoded with numbers kept
+// verbatim and re-encoded, so both ends produce the same bytes however
+// the payload was built.
+func signingBytes(msg *Message) ([]byte, error) {
+	unsigned := *msg
+	unsigned.Signature = nil
+
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	var canonical Message
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&canonical); err != nil {
+		return nil, err
+	}
+	return json.Marshal(&canonical)
+}
+
+// verifySignature checks the signature of a received message against its
+// sender's signing key. Messages from peers without a signing key are
+// accepted unchecked.
+func (n *ZmqNode) verifySignature(data []byte, msg *Message) bool {
+	n.mu.RLock()
+	var publicKey []byte
+	if peer, ok := n.peers[msg.From]; ok {
+		publicKey = peer.SigningKey
+	}
+	n.mu.RUnlock()
+
+	if len(publicKey) == 0 {
+		return true
+	}
+	if len(publicKey) != ed25519.PublicKeySize || len(msg.Signature) == 0 {
+		return false
+	}
+
+	// Decode the raw message again keeping numbers verbatim, as the
+	// sender signed them
+	var signed Message
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&signed); err != nil {
+		return false
+	}
+	signedData, err := signingBytes(&signed)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(publicKey, signedData, msg.Signature)
+}
+
 // isValidReplay checks if a message is not a replay attack.
 func (n *ZmqNode) isValidReplay(msg *Message) bool {
 	if msg.Nonce == "" {
@@ -425,6 +1348,42 @@ func (n *ZmqNode) isValidReplay(msg *Message) bool {
 	return true
 }
 
+// nextSeq returns the next outgoing sequence number for a peer.
+func (n *ZmqNode) nextSeq(peerID string) uint64 {
+	n.seqMu.Lock()
+	defer n.seqMu.Unlock()
+
+	n.sendSeq[peerID]++
+	return n.sendSeq[peerID]
+}
+
+// acceptSequence tracks the last sequence seen from each sender. It rejects
+// duplicates and messages older than the last accepted one, and logs gaps.
+// Sequence 1 is always accepted so a restarted sender resets its stream.
+// Messages without a sequence are not checked.
+func (n *ZmqNode) acceptSequence(msg *Message) bool {
+	if msg.Seq == 0 {
+		return true
+	}
+
+	n.seqMu.Lock()
+	defer n.seqMu.Unlock()
+
+	last, seen := n.recvSeq[msg.From]
+	if seen && msg.Seq <= last && msg.Seq != 1 {
+		n.seqRejected++
+		return false
+	}
+
+	if seen && msg.Seq > last+1 {
+		n.seqGaps++
+		log.Printf("Warning: sequence gap from %s: expected %d, got %d", msg.From, last+1, msg.Seq)
+	}
+
+	n.recvSeq[msg.From] = msg.Seq
+	return true
+}
+
 // replayCacheCleaner periodically cleans old entries from replay cache.
 func (n *ZmqNode) replayCacheCleaner() {
 	defer n.wg.Done()
@@ -462,18 +1421,50 @@ type NodeStats struct {
 	PeerCount int    `json:"peer_count"`
 	IsRunning bool   `json:"is_running"`
 	QueueSize int    `json:"queue_size"`
+
+	BytesSent        int64 `json:"bytes_sent"`
+	BytesReceived    int64 `json:"bytes_received"`
+	MessagesSent     int64 `json:"messages_sent"`
+	MessagesReceived int64 `json:"messages_received"`
+	MessagesDropped  int64 `json:"messages_dropped"`
+
+	SequenceGaps       int64 `json:"sequence_gaps"`
+	SequenceRejected   int64 `json:"sequence_rejected"`
+	ParseFailures      int64 `json:"parse_failures"`
+	DecompressFailures int64 `json:"decompress_failures"`
+	SignatureFailures  int64 `json:"signature_failures"`
+	PrunedSends        int64 `json:"pruned_sends"`
+	Retransmits        int64 `json:"retransmits"`
+	DialerPoolSize     int   `json:"dialer_pool_size"`
 }
 
 // GetStats returns current node statistics.
 func (n *ZmqNode) GetStats() NodeStats {
+	n.seqMu.Lock()
+	gaps, rejected := n.seqGaps, n.seqRejected
+	n.seqMu.Unlock()
+
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
 	return NodeStats{
-		NodeID:    n.nodeID,
-		Address:   n.address,
-		PeerCount: len(n.peers),
-		IsRunning: n.running,
-		QueueSize: len(n.msgChan),
+		NodeID:             n.nodeID,
+		Address:            n.address,
+		PeerCount:          len(n.peers),
+		IsRunning:          n.running,
+		QueueSize:          len(n.msgChan),
+		BytesSent:          atomic.LoadInt64(&n.bytesSent),
+		BytesReceived:      atomic.LoadInt64(&n.bytesReceived),
+		MessagesSent:       atomic.LoadInt64(&n.messagesSent),
+		MessagesReceived:   atomic.LoadInt64(&n.messagesReceived),
+		MessagesDropped:    atomic.LoadInt64(&n.messagesDropped),
+		SequenceGaps:       gaps,
+		SequenceRejected:   rejected,
+		ParseFailures:      atomic.LoadInt64(&n.parseFailures),
+		DecompressFailures: atomic.LoadInt64(&n.decompressFailures),
+		SignatureFailures:  atomic.LoadInt64(&n.signatureFailures),
+		PrunedSends:        atomic.LoadInt64(&n.prunedSends),
+		Retransmits:        atomic.LoadInt64(&n.retransmits),
+		DialerPoolSize:     len(n.dealers),
 	}
 }